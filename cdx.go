@@ -0,0 +1,452 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- CDX (FoxPro/VFP) ---------------------------
+
+// Um .CDX começa com um header de 1024 bytes cuja B-tree (o diretório)
+// tem como chaves os nomes das tags e, no lugar do número de registro, a
+// posição do header de cada tag. Cada tag tem sua própria B-tree de nós
+// de 512 bytes; as folhas usam o formato compacto (chaves com prefixo e
+// sufixo comprimidos, empacotadas do fim do nó para o início).
+const (
+	cdxHeaderSize = 1024
+	cdxNodeSize   = 512
+
+	cdxUnique   = 0x01
+	cdxHasFor   = 0x08
+	cdxCompact  = 0x20
+	cdxCompound = 0x40
+
+	cdxNodeRoot = 0x01
+	cdxNodeLeaf = 0x02
+
+	cdxLeafHeader = 24
+)
+
+// readCDX lê o diretório de tags de um .CDX.
+func (d *DBF) readCDX(r io.ReaderAt) ([]*IndexTag, error) {
+	dir, err := readCDXTag(r, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	var tags []*IndexTag
+	c := dir.tree.scan(false)
+	for c.Next() {
+		off := int64(c.Recno())
+		t, err := readCDXTag(r, off, d.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", trimKey(c.Key()), err)
+		}
+		t.Name = trimKey(c.Key())
+		enc := d.keyCodec(t.Expr)
+		t.seekKey = func(v any) ([]byte, error) { return cdxSeekKey(t, v, enc) }
+		tags = append(tags, t)
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// readCDXTag lê o header da tag em off. Com fields nil (diretório), as
+// chaves são tratadas como texto.
+func readCDXTag(r io.ReaderAt, off int64, fields []Field) (*IndexTag, error) {
+	hdr := make([]byte, cdxHeaderSize)
+	if _, err := r.ReadAt(hdr, off); err != nil {
+		return nil, fmt.Errorf("lendo header de tag em %d: %w", off, err)
+	}
+	opts := hdr[14]
+	if opts&cdxCompact == 0 {
+		return nil, errors.New("índice não compacto (IDX antigo) não suportado")
+	}
+	keyLen := int(binary.LittleEndian.Uint16(hdr[12:14]))
+	if keyLen <= 0 || keyLen > 240 {
+		return nil, fmt.Errorf("tamanho de chave inválido: %d", keyLen)
+	}
+
+	// pool de expressões: chave e FOR, terminadas por NUL
+	pool := hdr[512:]
+	expr, rest, _ := bytes.Cut(pool, []byte{0})
+	forExpr, _, _ := bytes.Cut(rest, []byte{0})
+
+	t := &IndexTag{
+		Expr:       string(bytes.TrimSpace(expr)),
+		Unique:     opts&cdxUnique != 0,
+		Descending: binary.LittleEndian.Uint16(hdr[502:504]) == 1,
+		KeyLen:     keyLen,
+		keyType:    'C',
+	}
+	if opts&cdxHasFor != 0 {
+		t.For = string(bytes.TrimSpace(forExpr))
+	}
+	if fields != nil {
+		t.keyType = keyTypeFor(fields, t.Expr)
+	}
+
+	fill := byte(' ')
+	if t.keyType != 'C' {
+		fill = 0
+	}
+	t.tree = &btree{
+		root:    int64(binary.LittleEndian.Uint32(hdr[0:4])),
+		compare: bytes.Compare,
+		load: func(off int64) (*indexNode, error) {
+			return readCDXNode(r, off, keyLen, fill)
+		},
+	}
+	return t, nil
+}
+
+func readCDXNode(r io.ReaderAt, off int64, keyLen int, fill byte) (*indexNode, error) {
+	buf := make([]byte, cdxNodeSize)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("lendo nó em %d: %w", off, err)
+	}
+	attr := binary.LittleEndian.Uint16(buf[0:2])
+	count := int(binary.LittleEndian.Uint16(buf[2:4]))
+	if attr&cdxNodeLeaf != 0 {
+		n, err := decodeCDXLeaf(buf, count, keyLen, fill)
+		if err != nil {
+			return nil, fmt.Errorf("nó em %d: %w", off, err)
+		}
+		return n, nil
+	}
+
+	// nó interno: chave, registro (BE) e filho (BE)
+	entry := keyLen + 8
+	if 12+count*entry > cdxNodeSize {
+		return nil, fmt.Errorf("nó em %d: %d chaves não cabem no nó", off, count)
+	}
+	n := &indexNode{}
+	for i := 0; i < count; i++ {
+		p := buf[12+i*entry:]
+		n.keys = append(n.keys, append([]byte(nil), p[:keyLen]...))
+		n.recnos = append(n.recnos, binary.BigEndian.Uint32(p[keyLen:]))
+		n.children = append(n.children, int64(binary.BigEndian.Uint32(p[keyLen+4:])))
+	}
+	return n, nil
+}
+
+// decodeCDXLeaf reconstrói as chaves de uma folha compacta. Cada entrada
+// guarda, em bytesPerEntry bytes LE, o registro, quantos bytes repetem a
+// chave anterior (dup) e quantos bytes de preenchimento foram cortados do
+// fim (trail).
+func decodeCDXLeaf(buf []byte, count, keyLen int, fill byte) (*indexNode, error) {
+	recMask := uint64(binary.LittleEndian.Uint32(buf[14:18]))
+	dupMask := uint64(buf[18])
+	trailMask := uint64(buf[19])
+	recBits := uint(buf[20])
+	dupBits := uint(buf[21])
+	entryLen := int(buf[23])
+	if entryLen < 1 || entryLen > 8 || cdxLeafHeader+count*entryLen > cdxNodeSize {
+		return nil, errors.New("folha compacta inválida")
+	}
+
+	n := &indexNode{leaf: true}
+	prev := make([]byte, keyLen)
+	end := cdxNodeSize
+	for i := 0; i < count; i++ {
+		var raw [8]byte
+		copy(raw[:], buf[cdxLeafHeader+i*entryLen:cdxLeafHeader+(i+1)*entryLen])
+		v := binary.LittleEndian.Uint64(raw[:])
+		recno := uint32(v & recMask)
+		dup := int((v >> recBits) & dupMask)
+		trail := int((v >> (recBits + dupBits)) & trailMask)
+
+		size := keyLen - dup - trail
+		if size < 0 || end-size < cdxLeafHeader+count*entryLen {
+			return nil, errors.New("folha compacta inválida")
+		}
+		end -= size
+		key := make([]byte, keyLen)
+		copy(key, prev[:dup])
+		copy(key[dup:], buf[end:end+size])
+		for j := keyLen - trail; j < keyLen; j++ {
+			key[j] = fill
+		}
+		n.keys = append(n.keys, key)
+		n.recnos = append(n.recnos, recno)
+		n.children = append(n.children, 0)
+		prev = key
+	}
+	return n, nil
+}
+
+// cdxSeekKey converte v para uma chave de busca da tag t.
+func cdxSeekKey(t *IndexTag, v any, enc encoding.Encoding) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	switch t.keyType {
+	case 'C':
+		return textKey(v, enc)
+	case 'D', 'T':
+		tm, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("chave de índice: esperado time.Time, recebido %T", v)
+		}
+		return cdxNumber(julianFloat(tm, t.keyType == 'T')), nil
+	case 'I':
+		n, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("chave de índice: %w", err)
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("chave de índice: %d não cabe em 4 bytes", n)
+		}
+		return cdxInt(int32(n)), nil
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, fmt.Errorf("chave de índice: %w", err)
+	}
+	return cdxNumber(f), nil
+}
+
+// cdxNumber codifica f como double big-endian ordenável byte a byte, como
+// o VFP grava chaves numéricas e de data.
+func cdxNumber(f float64) []byte {
+	if f == 0 {
+		f = 0 // normaliza -0
+	}
+	u := math.Float64bits(f)
+	if u&(1<<63) == 0 {
+		u |= 1 << 63
+	} else {
+		u = ^u
+	}
+	out := make([]byte, 8)
+	binary.BigEndian.PutUint64(out, u)
+	return out
+}
+
+// cdxInt codifica um inteiro de 4 bytes com o bit de sinal invertido.
+func cdxInt(n int32) []byte {
+	out := make([]byte, 4)
+	binary.BigEndian.PutUint32(out, uint32(n)^(1<<31))
+	return out
+}
+
+// --------------------------- CDX: gravação ---------------------------
+
+// cdxEntry é uma chave com o registro a que aponta.
+type cdxEntry struct {
+	key   []byte
+	recno uint32
+}
+
+// cdxTagData é uma tag pronta para gravação, com as chaves já ordenadas.
+type cdxTagData struct {
+	def     IndexDef
+	keyLen  int
+	fill    byte
+	entries []cdxEntry
+}
+
+const (
+	cdxLeafSpace     = cdxNodeSize - cdxLeafHeader
+	cdxInteriorSpace = cdxNodeSize - 12
+	cdxNoNode        = 0xFFFFFFFF
+)
+
+// encodeCDX monta um .CDX completo: header do diretório, header e nós de
+// cada tag e, por fim, os nós do diretório.
+func encodeCDX(tags []cdxTagData) []byte {
+	out := make([]byte, cdxHeaderSize)
+	var dir []cdxEntry
+	for _, t := range tags {
+		hdrOff := len(out)
+		out = append(out, make([]byte, cdxHeaderSize)...)
+		root := appendCDXTree(&out, t.entries, t.keyLen, t.fill)
+		putCDXHeader(out[hdrOff:], root, t.keyLen, t.def, false)
+
+		name := make([]byte, 10)
+		fillSpaces(name)
+		copy(name, strings.ToUpper(t.def.Tag))
+		dir = append(dir, cdxEntry{key: name, recno: uint32(hdrOff)})
+	}
+	sort.Slice(dir, func(i, j int) bool { return bytes.Compare(dir[i].key, dir[j].key) < 0 })
+	root := appendCDXTree(&out, dir, 10, ' ')
+	putCDXHeader(out, root, 10, IndexDef{}, true)
+	return out
+}
+
+func putCDXHeader(h []byte, root int64, keyLen int, def IndexDef, compound bool) {
+	binary.LittleEndian.PutUint32(h[0:4], uint32(root))
+	binary.LittleEndian.PutUint32(h[4:8], cdxNoNode)
+	binary.LittleEndian.PutUint16(h[12:14], uint16(keyLen))
+	h[14] = cdxCompact
+	if compound {
+		h[14] |= cdxCompound
+	}
+	if def.Unique {
+		h[14] |= cdxUnique
+	}
+	if def.For != "" {
+		h[14] |= cdxHasFor
+	}
+	h[15] = 1
+	if def.Descending {
+		h[502] = 1
+	}
+	// pool de expressões: chave e FOR, cada uma terminada por NUL
+	binary.LittleEndian.PutUint16(h[506:508], uint16(len(def.For)+1))
+	binary.LittleEndian.PutUint16(h[510:512], uint16(len(def.Expr)+1))
+	copy(h[512:], def.Expr+"\x00"+def.For+"\x00")
+}
+
+// cdxNode é um nó montado em memória; off é definido ao gravá-lo.
+type cdxNode struct {
+	buf       []byte
+	lastKey   []byte
+	lastRecno uint32
+	off       int64
+}
+
+// appendCDXTree grava as folhas e os níveis internos de uma B-tree em out
+// e devolve a posição da raiz.
+func appendCDXTree(out *[]byte, entries []cdxEntry, keyLen int, fill byte) int64 {
+	level := packCDXLeaves(entries, keyLen, fill)
+	placeCDXLevel(out, level)
+	for len(level) > 1 {
+		level = packCDXInterior(level, keyLen)
+		placeCDXLevel(out, level)
+	}
+	return level[0].off
+}
+
+// placeCDXLevel grava os nós de um nível no fim de out, encadeados pelos
+// ponteiros de irmãos; um nível de nó único é a raiz.
+func placeCDXLevel(out *[]byte, level []*cdxNode) {
+	base := int64(len(*out))
+	for i, n := range level {
+		n.off = base + int64(i)*cdxNodeSize
+	}
+	for i, n := range level {
+		left, right := uint32(cdxNoNode), uint32(cdxNoNode)
+		if i > 0 {
+			left = uint32(level[i-1].off)
+		}
+		if i < len(level)-1 {
+			right = uint32(level[i+1].off)
+		}
+		binary.LittleEndian.PutUint32(n.buf[4:8], left)
+		binary.LittleEndian.PutUint32(n.buf[8:12], right)
+		if len(level) == 1 {
+			n.buf[0] |= cdxNodeRoot
+		}
+		*out = append(*out, n.buf...)
+	}
+}
+
+// packCDXLeaves distribui as chaves em folhas compactas. dup e trail usam
+// bits suficientes para keyLen; o número de registro ocupa o restante de
+// cada entrada.
+func packCDXLeaves(entries []cdxEntry, keyLen int, fill byte) []*cdxNode {
+	var maxRecno uint32
+	for _, e := range entries {
+		if e.recno > maxRecno {
+			maxRecno = e.recno
+		}
+	}
+	kb := uint(bits.Len(uint(keyLen)))
+	entryLen := (bits.Len32(maxRecno) + 2*int(kb) + 7) / 8
+	if entryLen < 3 {
+		entryLen = 3
+	}
+	recBits := uint(entryLen*8) - 2*kb
+	if recBits > 32 {
+		recBits = 32
+	}
+
+	newLeaf := func() *cdxNode {
+		n := &cdxNode{buf: make([]byte, cdxNodeSize)}
+		binary.LittleEndian.PutUint16(n.buf[0:2], cdxNodeLeaf)
+		binary.LittleEndian.PutUint32(n.buf[14:18], uint32(uint64(1)<<recBits-1))
+		n.buf[18], n.buf[19] = byte(1<<kb-1), byte(1<<kb-1)
+		n.buf[20], n.buf[21], n.buf[22], n.buf[23] = byte(recBits), byte(kb), byte(kb), byte(entryLen)
+		return n
+	}
+	finish := func(n *cdxNode, count, used int) {
+		binary.LittleEndian.PutUint16(n.buf[2:4], uint16(count))
+		binary.LittleEndian.PutUint16(n.buf[12:14], uint16(cdxLeafSpace-used))
+	}
+
+	var nodes []*cdxNode
+	cur := newLeaf()
+	count, used, end := 0, 0, cdxNodeSize
+	var prev []byte
+	for _, e := range entries {
+		dup, trail := cdxCompress(prev, e.key, fill)
+		if count > 0 && used+entryLen+keyLen-dup-trail > cdxLeafSpace {
+			finish(cur, count, used)
+			nodes = append(nodes, cur)
+			cur = newLeaf()
+			count, used, end, prev = 0, 0, cdxNodeSize, nil
+			dup, trail = cdxCompress(nil, e.key, fill)
+		}
+		size := keyLen - dup - trail
+		end -= size
+		copy(cur.buf[end:], e.key[dup:dup+size])
+
+		v := uint64(e.recno) | uint64(dup)<<recBits | uint64(trail)<<(recBits+kb)
+		var raw [8]byte
+		binary.LittleEndian.PutUint64(raw[:], v)
+		copy(cur.buf[cdxLeafHeader+count*entryLen:], raw[:entryLen])
+
+		count++
+		used += entryLen + size
+		prev = e.key
+		cur.lastKey, cur.lastRecno = e.key, e.recno
+	}
+	finish(cur, count, used)
+	return append(nodes, cur)
+}
+
+// cdxCompress devolve quantos bytes de key repetem prev e quantos bytes de
+// preenchimento podem ser cortados do fim.
+func cdxCompress(prev, key []byte, fill byte) (dup, trail int) {
+	for dup < len(prev) && dup < len(key) && prev[dup] == key[dup] {
+		dup++
+	}
+	for trail < len(key)-dup && key[len(key)-1-trail] == fill {
+		trail++
+	}
+	return dup, trail
+}
+
+// packCDXInterior monta o nível acima de children: cada entrada traz a
+// maior chave do filho, seu último registro e sua posição.
+func packCDXInterior(children []*cdxNode, keyLen int) []*cdxNode {
+	per := cdxInteriorSpace / (keyLen + 8)
+	var nodes []*cdxNode
+	for i := 0; i < len(children); i += per {
+		group := children[i:min(i+per, len(children))]
+		n := &cdxNode{buf: make([]byte, cdxNodeSize)}
+		binary.LittleEndian.PutUint16(n.buf[2:4], uint16(len(group)))
+		for j, c := range group {
+			p := n.buf[12+j*(keyLen+8):]
+			copy(p, c.lastKey)
+			binary.BigEndian.PutUint32(p[keyLen:], c.lastRecno)
+			binary.BigEndian.PutUint32(p[keyLen+4:], uint32(c.off))
+		}
+		last := group[len(group)-1]
+		n.lastKey, n.lastRecno = last.lastKey, last.lastRecno
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
@@ -0,0 +1,319 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type cdxTestTag struct {
+	name, expr, forExpr string
+	keyLen              int
+	fill                byte
+	descending          bool
+	keys                [][]byte
+	recnos              []uint32
+}
+
+// buildCDX monta um .CDX com folhas de no máximo perLeaf chaves e, se
+// necessário, um nó raiz interno.
+func buildCDX(tags []cdxTestTag, perLeaf int) []byte {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].name < tags[j].name })
+
+	var body []byte
+	off := cdxHeaderSize + cdxNodeSize
+	var dirKeys [][]byte
+	var dirRecnos []uint32
+	for _, t := range tags {
+		hdrOff := off
+		dirKeys = append(dirKeys, padKey(t.name, 10, ' '))
+		dirRecnos = append(dirRecnos, uint32(hdrOff))
+		off += cdxHeaderSize
+
+		var leaves [][]byte
+		var lastKeys [][]byte
+		var lastRecnos []uint32
+		var offs []uint32
+		nLeaves := (len(t.keys) + perLeaf - 1) / perLeaf
+		for i := 0; i < nLeaves; i++ {
+			lo, hi := i*perLeaf, (i+1)*perLeaf
+			if hi > len(t.keys) {
+				hi = len(t.keys)
+			}
+			attr := uint16(cdxNodeLeaf)
+			if nLeaves == 1 {
+				attr |= cdxNodeRoot
+			}
+			leaves = append(leaves, cdxTestLeaf(t.keys[lo:hi], t.recnos[lo:hi], t.keyLen, t.fill, attr))
+			lastKeys = append(lastKeys, t.keys[hi-1])
+			lastRecnos = append(lastRecnos, t.recnos[hi-1])
+			offs = append(offs, uint32(off+i*cdxNodeSize))
+		}
+		root := off
+		if nLeaves > 1 {
+			root = off + nLeaves*cdxNodeSize
+		}
+		body = append(body, cdxTestHeader(root, t.keyLen, t.expr, t.forExpr, t.descending)...)
+		for _, l := range leaves {
+			body = append(body, l...)
+		}
+		off += nLeaves * cdxNodeSize
+		if nLeaves > 1 {
+			body = append(body, cdxTestInterior(lastKeys, lastRecnos, offs, t.keyLen)...)
+			off += cdxNodeSize
+		}
+	}
+
+	out := cdxTestHeader(cdxHeaderSize, 10, "", "", false)
+	out[14] |= cdxCompound
+	out = append(out, cdxTestLeaf(dirKeys, dirRecnos, 10, ' ', cdxNodeLeaf|cdxNodeRoot)...)
+	return append(out, body...)
+}
+
+func padKey(s string, n int, fill byte) []byte {
+	k := bytes.Repeat([]byte{fill}, n)
+	copy(k, s)
+	return k
+}
+
+func cdxTestHeader(root, keyLen int, expr, forExpr string, desc bool) []byte {
+	h := make([]byte, cdxHeaderSize)
+	binary.LittleEndian.PutUint32(h[0:4], uint32(root))
+	binary.LittleEndian.PutUint32(h[4:8], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint16(h[12:14], uint16(keyLen))
+	h[14] = cdxCompact
+	if forExpr != "" {
+		h[14] |= cdxHasFor
+	}
+	if desc {
+		h[502] = 1
+	}
+	copy(h[512:], expr+"\x00"+forExpr+"\x00")
+	return h
+}
+
+func cdxTestLeaf(keys [][]byte, recnos []uint32, keyLen int, fill byte, attr uint16) []byte {
+	n := make([]byte, cdxNodeSize)
+	binary.LittleEndian.PutUint16(n[0:2], attr)
+	binary.LittleEndian.PutUint16(n[2:4], uint16(len(keys)))
+	binary.LittleEndian.PutUint32(n[4:8], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(n[8:12], 0xFFFFFFFF)
+
+	kb := uint(bits.Len(uint(keyLen)))
+	recBits := 32 - 2*kb
+	binary.LittleEndian.PutUint32(n[14:18], 1<<recBits-1)
+	n[18], n[19] = byte(1<<kb-1), byte(1<<kb-1)
+	n[20], n[21], n[22], n[23] = byte(recBits), byte(kb), byte(kb), 4
+
+	end := cdxNodeSize
+	prev := []byte{}
+	for i, k := range keys {
+		dup := 0
+		for dup < len(prev) && prev[dup] == k[dup] {
+			dup++
+		}
+		trail := 0
+		for trail < keyLen-dup && k[keyLen-1-trail] == fill {
+			trail++
+		}
+		size := keyLen - dup - trail
+		end -= size
+		copy(n[end:], k[dup:dup+size])
+		v := uint32(recnos[i]) | uint32(dup)<<recBits | uint32(trail)<<(recBits+kb)
+		binary.LittleEndian.PutUint32(n[cdxLeafHeader+4*i:], v)
+		prev = k
+	}
+	return n
+}
+
+func cdxTestInterior(keys [][]byte, recnos, children []uint32, keyLen int) []byte {
+	n := make([]byte, cdxNodeSize)
+	binary.LittleEndian.PutUint16(n[0:2], cdxNodeRoot)
+	binary.LittleEndian.PutUint16(n[2:4], uint16(len(keys)))
+	binary.LittleEndian.PutUint32(n[4:8], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(n[8:12], 0xFFFFFFFF)
+	for i, k := range keys {
+		p := n[12+i*(keyLen+8):]
+		copy(p, k)
+		binary.BigEndian.PutUint32(p[keyLen:], recnos[i])
+		binary.BigEndian.PutUint32(p[keyLen+4:], children[i])
+	}
+	return n
+}
+
+// writeCDXFixture grava people.dbf (NAME, AGE) e people.cdx com as tags
+// NAME (UPPER(NAME)), AGE (com FOR) e AGEDESC (decrescente).
+func writeCDXFixture(t *testing.T) (*DBF, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "people.dbf")
+	people := []struct {
+		name string
+		age  int
+	}{
+		{"Davi", 52}, {"ana", 30}, {"Bruno", 41}, {"Carla", 25},
+		{"Eva", 19}, {"Caio", 41}, {"Fabio", 67},
+	}
+	var rows []Record
+	for _, p := range people {
+		rows = append(rows, Record{"NAME": p.name, "AGE": p.age})
+	}
+	writePeople(t, path, rows...)
+
+	type entry struct {
+		key   []byte
+		recno uint32
+	}
+	var byName, byAge []entry
+	for i, p := range people {
+		byName = append(byName, entry{padKey(strings.ToUpper(p.name), 10, ' '), uint32(i + 1)})
+		byAge = append(byAge, entry{cdxNumber(float64(p.age)), uint32(i + 1)})
+	}
+	sortEntries := func(es []entry) ([][]byte, []uint32) {
+		sort.SliceStable(es, func(i, j int) bool { return bytes.Compare(es[i].key, es[j].key) < 0 })
+		var keys [][]byte
+		var recnos []uint32
+		for _, e := range es {
+			keys = append(keys, e.key)
+			recnos = append(recnos, e.recno)
+		}
+		return keys, recnos
+	}
+	nameKeys, nameRecnos := sortEntries(byName)
+	ageKeys, ageRecnos := sortEntries(byAge)
+
+	data := buildCDX([]cdxTestTag{
+		{name: "NAME", expr: "UPPER(NAME)", keyLen: 10, fill: ' ', keys: nameKeys, recnos: nameRecnos},
+		{name: "AGE", expr: "AGE", forExpr: "!DELETED()", keyLen: 8, keys: ageKeys, recnos: ageRecnos},
+		{name: "AGEDESC", expr: "AGE", keyLen: 8, descending: true, keys: ageKeys, recnos: ageRecnos},
+	}, 3)
+	cdxPath := writeFixture(t, dir, "people.cdx", data)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	return db, cdxPath
+}
+
+func TestOpenIndexListsCDXTags(t *testing.T) {
+	db, cdxPath := writeCDXFixture(t)
+	idx, err := db.OpenIndex(cdxPath)
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer idx.Close()
+
+	if len(idx.Tags) != 3 {
+		t.Fatalf("expected 3 tags, got %d", len(idx.Tags))
+	}
+	tag, err := idx.Tag("age")
+	if err != nil {
+		t.Fatalf("Tag returned error: %v", err)
+	}
+	if tag.Expr != "AGE" || tag.For != "!DELETED()" || tag.KeyLen != 8 || tag.Descending {
+		t.Fatalf("unexpected AGE tag: %+v", tag)
+	}
+	if tag, _ := idx.Tag("NAME"); tag.Expr != "UPPER(NAME)" || tag.For != "" {
+		t.Fatalf("unexpected NAME tag: %+v", tag)
+	}
+	if tag, _ := idx.Tag("AGEDESC"); !tag.Descending {
+		t.Fatalf("expected AGEDESC to be descending")
+	}
+	if _, err := idx.Tag("NOPE"); !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+}
+
+func TestCDXSeek(t *testing.T) {
+	db, cdxPath := writeCDXFixture(t)
+	idx, err := db.OpenIndex(cdxPath)
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer idx.Close()
+
+	cases := []struct {
+		tag   string
+		key   any
+		recno uint32
+		found bool
+	}{
+		{"NAME", "CARLA", 4, true},
+		{"NAME", "CA", 6, true}, // CAIO vem antes de CARLA
+		{"NAME", "FABIO", 7, true},
+		{"NAME", "ZECA", 0, false},
+		{"NAME", "BX", 0, false},
+		{"AGE", 41, 3, true},
+		{"AGE", 67.0, 7, true},
+		{"AGE", 18, 0, false},
+	}
+	for _, c := range cases {
+		recno, found, err := idx.Seek(c.tag, c.key)
+		if err != nil {
+			t.Fatalf("Seek(%s, %v) returned error: %v", c.tag, c.key, err)
+		}
+		if recno != c.recno || found != c.found {
+			t.Fatalf("Seek(%s, %v): expected %d/%v, got %d/%v", c.tag, c.key, c.recno, c.found, recno, found)
+		}
+	}
+
+	recno, _, _ := idx.Seek("NAME", "ANA")
+	rec, err := db.ReadAt(recno)
+	if err != nil {
+		t.Fatalf("ReadAt returned error: %v", err)
+	}
+	if rec["NAME"] != "ana" {
+		t.Fatalf("expected ana, got %v", rec["NAME"])
+	}
+
+	if _, _, err := idx.Seek("AGE", "x"); err == nil {
+		t.Fatalf("expected error for text key on numeric tag")
+	}
+}
+
+func TestCDXScanOrder(t *testing.T) {
+	db, cdxPath := writeCDXFixture(t)
+	idx, err := db.OpenIndex(cdxPath)
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer idx.Close()
+
+	collect := func(c *IndexCursor, err error) []uint32 {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("scan returned error: %v", err)
+		}
+		var out []uint32
+		for c.Next() {
+			out = append(out, c.Recno())
+		}
+		if err := c.Err(); err != nil {
+			t.Fatalf("cursor error: %v", err)
+		}
+		return out
+	}
+	equal := func(got, want []uint32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	}
+
+	equal(collect(idx.Scan("NAME")), []uint32{2, 3, 6, 4, 1, 5, 7})
+	equal(collect(idx.Scan("AGE")), []uint32{5, 4, 2, 3, 6, 1, 7})
+	equal(collect(idx.Scan("AGEDESC")), []uint32{7, 1, 6, 3, 2, 4, 5})
+	equal(collect(idx.ScanFrom("AGE", 40)), []uint32{3, 6, 1, 7})
+	equal(collect(idx.ScanFrom("NAME", "D")), []uint32{1, 5, 7})
+}
@@ -0,0 +1,381 @@
+// Comando dbfmini inspeciona tabelas DBF pela linha de comando.
+//
+//	dbfmini info clientes.dbf
+//	dbfmini fields clientes.dbf
+//	dbfmini head -n 5 clientes.dbf
+//	dbfmini tail -encoding CP850 clientes.dbf
+//	dbfmini get clientes.dbf 42
+//	dbfmini count -deleted clientes.dbf
+//	dbfmini grep -i clientes.dbf NOME '^jo'
+//
+// Todos os comandos aceitam -encoding, -loose e -deleted; veja
+// "dbfmini help".
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+const usage = `uso: dbfmini <comando> [opções] arquivo.dbf [argumentos]
+
+comandos:
+  info                  versão, página de códigos, contagem, tamanhos e arquivos associados
+  fields                estrutura da tabela
+  head                  primeiros registros (-n)
+  tail                  últimos registros (-n)
+  get N                 registro número N (base 1), mesmo se excluído
+  count                 número de registros (com -deleted, inclui os excluídos)
+  grep CAMPO PADRÃO     registros cujo CAMPO casa com a expressão regular PADRÃO
+
+opções:
+  -encoding NOME        página de códigos (ex.: CP850); default: .CPG ou header
+  -loose                tolera versões, tamanhos e valores inválidos (default: strict)
+  -deleted              inclui registros excluídos (marcados com *)
+  -n N                  registros em head/tail (default 10)
+  -i                    grep sem diferenciar maiúsculas
+`
+
+// errUsage indica argumentos inválidos; o uso é impresso e o código de
+// saída é 2.
+var errUsage = errors.New("argumentos inválidos")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "dbfmini:", err)
+		os.Exit(1)
+	}
+}
+
+// options reúne as opções comuns aos comandos.
+type options struct {
+	encoding string
+	loose    bool
+	deleted  bool
+	n        int
+	fold     bool
+}
+
+func (o options) open(path string) (*dbfmini.DBF, error) {
+	opts := &dbfmini.OpenOptions{
+		ReadMode:       dbfmini.ReadStrict,
+		Encoding:       dbfmini.Encoding{Default: o.encoding},
+		IncludeDeleted: o.deleted,
+		IncludeRecno:   true,
+	}
+	if o.loose {
+		opts.ReadMode = dbfmini.ReadLoose
+	}
+	return dbfmini.Open(path, opts)
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "help" || cmd == "-h" || cmd == "--help" {
+		fmt.Fprint(out, usage)
+		return nil
+	}
+
+	var o options
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&o.encoding, "encoding", "", "")
+	fs.BoolVar(&o.loose, "loose", false, "")
+	fs.BoolVar(&o.deleted, "deleted", false, "")
+	fs.IntVar(&o.n, "n", 10, "")
+	fs.BoolVar(&o.fold, "i", false, "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	args = fs.Args()
+
+	want := map[string]int{"info": 1, "fields": 1, "head": 1, "tail": 1, "count": 1, "get": 2, "grep": 3}
+	n, ok := want[cmd]
+	if !ok {
+		return fmt.Errorf("%w: comando desconhecido %q", errUsage, cmd)
+	}
+	if len(args) != n {
+		return fmt.Errorf("%w: %s espera %d argumento(s)", errUsage, cmd, n)
+	}
+	db, err := o.open(args[0])
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "info":
+		return info(out, db)
+	case "fields":
+		return fields(out, db)
+	case "head":
+		return head(out, db, o.n)
+	case "tail":
+		return tail(out, db, o.n)
+	case "get":
+		recno, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("%w: número de registro inválido %q", errUsage, args[1])
+		}
+		return get(out, db, uint32(recno))
+	case "count":
+		n, err := db.Query().Count()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, n)
+		return nil
+	default: // grep
+		pattern := args[2]
+		if o.fold {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("padrão inválido: %w", err)
+		}
+		return grep(out, db, args[1], re)
+	}
+}
+
+// --------------------------- Comandos ---------------------------
+
+var versionNames = map[byte]string{
+	0x02: "FoxBASE",
+	0x03: "dBase III / FoxPro sem memo",
+	0x30: "Visual FoxPro",
+	0x31: "Visual FoxPro com autoincremento",
+	0x32: "Visual FoxPro com Varchar/Varbinary",
+	0x43: "dBase IV SQL",
+	0x63: "dBase IV SQL",
+	0x83: "dBase III com memo",
+	0x8b: "dBase IV com memo",
+	0xcb: "dBase IV SQL com memo",
+	0xf5: "FoxPro 2.x com memo",
+	0xfb: "FoxBASE",
+}
+
+func info(out io.Writer, db *dbfmini.DBF) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	version := versionNames[db.Version()]
+	if version == "" {
+		version = "desconhecida"
+	}
+	codePage := db.CodePage
+	if codePage == "" {
+		codePage = "não indicada"
+	}
+	fmt.Fprintf(tw, "arquivo:\t%s\n", db.Path)
+	fmt.Fprintf(tw, "versão:\t0x%02X (%s)\n", db.Version(), version)
+	fmt.Fprintf(tw, "language driver:\t0x%02X\n", db.LanguageDriver())
+	fmt.Fprintf(tw, "página de códigos:\t%s\n", codePage)
+	fmt.Fprintf(tw, "registros:\t%d\n", db.RecordCount)
+	fmt.Fprintf(tw, "campos:\t%d\n", len(db.Fields))
+	fmt.Fprintf(tw, "header:\t%d bytes\n", db.HeaderLength())
+	fmt.Fprintf(tw, "registro:\t%d bytes\n", db.RecordLength())
+	fmt.Fprintf(tw, "última atualização:\t%s\n", db.DateOfLastUpd.Format("2006-01-02"))
+	if p := db.MemoPath(); p != "" {
+		fmt.Fprintf(tw, "memo:\t%s\n", p)
+	}
+	for _, p := range indexSiblings(db.Path) {
+		note := ""
+		if db.HasProductionIndex() && isProduction(p) {
+			note = " (estrutural)"
+		}
+		fmt.Fprintf(tw, "índice:\t%s%s\n", p, note)
+	}
+	return tw.Flush()
+}
+
+// indexSiblings lista os índices com o mesmo nome base da tabela.
+func indexSiblings(path string) []string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	var found []string
+	for _, ext := range []string{".cdx", ".mdx", ".ndx", ".ntx"} {
+		for _, e := range []string{ext, strings.ToUpper(ext)} {
+			if _, err := os.Stat(base + e); err == nil {
+				found = append(found, base+e)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func isProduction(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".cdx" || ext == ".mdx"
+}
+
+var typeNames = map[byte]string{
+	'C': "Character",
+	'N': "Numeric",
+	'F': "Float",
+	'Y': "Currency",
+	'B': "Double",
+	'I': "Integer",
+	'L': "Logical",
+	'D': "Date",
+	'T': "DateTime",
+	'M': "Memo",
+	'G': "General",
+	'W': "Blob",
+	'V': "Varchar",
+	'Q': "Varbinary",
+}
+
+func fields(out io.Writer, db *dbfmini.DBF) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tNOME\tTIPO\tTAMANHO\tDEC\tPOSIÇÃO")
+	for i, f := range db.Fields {
+		fmt.Fprintf(tw, "%d\t%s\t%c %s\t%d\t%d\t%d\n", i+1, f.Name, f.Type, typeNames[f.Type], f.Size, f.DecimalPlaces, db.FieldOffset(i))
+	}
+	return tw.Flush()
+}
+
+func head(out io.Writer, db *dbfmini.DBF, n int) error {
+	if n <= 0 {
+		return printTable(out, db, nil)
+	}
+	recs, err := db.Query().Limit(n).All()
+	if err != nil {
+		return err
+	}
+	return printTable(out, db, recs)
+}
+
+func tail(out io.Writer, db *dbfmini.DBF, n int) error {
+	var recs []dbfmini.Record
+	err := db.GoBottom()
+	for err == nil && len(recs) < n {
+		recs = append(recs, db.Record())
+		err = db.Skip(-1)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	for i, j := 0, len(recs)-1; i < j; i, j = i+1, j-1 {
+		recs[i], recs[j] = recs[j], recs[i]
+	}
+	return printTable(out, db, recs)
+}
+
+func get(out io.Writer, db *dbfmini.DBF, recno uint32) error {
+	rec, err := db.ReadAt(recno)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "RECNO\t%d\n", recno)
+	if rec["_deleted"] == true {
+		fmt.Fprintln(tw, "EXCLUÍDO\tsim")
+	}
+	for _, f := range db.Fields {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Name, cell(formatValue(f, rec[f.Name])))
+	}
+	return tw.Flush()
+}
+
+func grep(out io.Writer, db *dbfmini.DBF, name string, re *regexp.Regexp) error {
+	field := -1
+	for i, f := range db.Fields {
+		if strings.EqualFold(f.Name, name) {
+			field = i
+		}
+	}
+	if field < 0 {
+		return fmt.Errorf("campo desconhecido: %s", name)
+	}
+	f := db.Fields[field]
+	var recs []dbfmini.Record
+	for db.Next() {
+		if rec := db.Record(); re.MatchString(formatValue(f, rec[f.Name])) {
+			recs = append(recs, rec)
+		}
+	}
+	if err := db.Err(); err != nil {
+		return err
+	}
+	return printTable(out, db, recs)
+}
+
+// --------------------------- Formatação ---------------------------
+
+// printTable imprime os registros em colunas, precedidos do número do
+// registro; excluídos recebem um "*".
+func printTable(out io.Writer, db *dbfmini.DBF, recs []dbfmini.Record) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "RECNO")
+	for _, f := range db.Fields {
+		fmt.Fprintf(tw, "\t%s", f.Name)
+	}
+	fmt.Fprintln(tw)
+	for _, rec := range recs {
+		mark := ""
+		if rec["_deleted"] == true {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%v%s", rec["_recno"], mark)
+		for _, f := range db.Fields {
+			fmt.Fprintf(tw, "\t%s", cell(formatValue(f, rec[f.Name])))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// formatValue converte o valor decodificado de f em texto.
+func formatValue(f dbfmini.Field, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		prec := -1
+		if f.Type == 'N' || f.Type == 'F' {
+			prec = int(f.DecimalPlaces)
+		}
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	case bool:
+		if v {
+			return "T"
+		}
+		return "F"
+	case time.Time:
+		if f.Type == 'D' {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	}
+	return fmt.Sprint(v)
+}
+
+// cell protege as colunas de textos com tabulações ou quebras de linha
+// (comuns em memos).
+func cell(s string) string {
+	if strings.ContainsAny(s, "\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
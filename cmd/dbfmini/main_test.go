@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+	"github.com/alberto255345/dbfmini/internal/dbftest"
+)
+
+func writeTable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clientes.dbf")
+	fields := []dbfmini.Field{
+		{Name: "NOME", Type: 'C', Size: 10},
+		{Name: "SALDO", Type: 'N', Size: 8, DecimalPlaces: 2},
+		{Name: "NASC", Type: 'D'},
+	}
+	dbftest.Table(t, path, fields, &dbfmini.CreateOptions{Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)}, []dbfmini.Record{
+		{"NOME": "Ana", "SALDO": 10.5, "NASC": time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC)},
+		{"NOME": "Bruno", "SALDO": -2},
+		{"NOME": "Carla", "_deleted": true},
+		{"NOME": "João"},
+	}, dbfmini.IndexDef{Tag: "NOME", Expr: "UPPER(NOME)"})
+	return path
+}
+
+func runOK(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := run(args, &out); err != nil {
+		t.Fatalf("run(%v) returned error: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestInfoAndFields(t *testing.T) {
+	path := writeTable(t)
+	out := runOK(t, "info", path)
+	for _, want := range []string{"0x03", "registros:", "4\n", "header:", "129 bytes", "registro:", "27 bytes", "2024-03-05", "clientes.cdx (estrutural)"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("info output missing %q:\n%s", want, out)
+		}
+	}
+	out = runOK(t, "fields", path)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[2], "SALDO") || !strings.Contains(lines[2], "N Numeric") ||
+		!strings.HasSuffix(lines[3], "19") {
+		t.Fatalf("unexpected fields output:\n%s", out)
+	}
+}
+
+func TestFieldsVFPOffsets(t *testing.T) {
+	// NOME C(4), _NullFlags oculto (1 byte) e OBS V(5) anulável
+	descs := []struct {
+		name  string
+		typ   byte
+		size  byte
+		flags byte
+	}{
+		{"NOME", 'C', 4, 0},
+		{"_NullFlags", '0', 1, dbfmini.FieldSystem | dbfmini.FieldBinary},
+		{"OBS", 'V', 5, dbfmini.FieldNullable},
+	}
+	headerLen := 32 + 32*len(descs) + 1 + 263
+	buf := make([]byte, headerLen, headerLen+1)
+	buf[0], buf[1], buf[2], buf[3] = 0x30, 124, 1, 2
+	binary.LittleEndian.PutUint16(buf[8:10], uint16(headerLen))
+	binary.LittleEndian.PutUint16(buf[10:12], 1+4+1+5)
+	off := 1
+	for i, d := range descs {
+		des := buf[32+32*i : 64+32*i]
+		copy(des[0:11], d.name)
+		des[11] = d.typ
+		binary.LittleEndian.PutUint32(des[12:16], uint32(off))
+		des[16], des[18] = d.size, d.flags
+		off += int(d.size)
+	}
+	buf[32+32*len(descs)] = 0x0D
+	buf = append(buf, 0x1A)
+	path := filepath.Join(t.TempDir(), "vfp.dbf")
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	out := runOK(t, "fields", path)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], "V Varchar") || !strings.HasSuffix(lines[2], " 6") {
+		t.Fatalf("unexpected fields output:\n%s", out)
+	}
+}
+
+func TestRecordCommands(t *testing.T) {
+	path := writeTable(t)
+
+	out := runOK(t, "head", "-n", "2", path)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 ||
+		!strings.Contains(lines[1], "10.50") || !strings.Contains(lines[1], "1990-05-17") || !strings.Contains(lines[2], "-2.00") {
+		t.Fatalf("unexpected head output:\n%s", out)
+	}
+	out = runOK(t, "tail", "-n", "2", "-deleted", path)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 ||
+		!strings.HasPrefix(lines[1], "3*") || !strings.HasPrefix(lines[2], "4 ") {
+		t.Fatalf("unexpected tail output:\n%s", out)
+	}
+	out = runOK(t, "get", path, "3")
+	if !strings.Contains(out, "EXCLUÍDO") || !strings.Contains(out, "Carla") {
+		t.Fatalf("unexpected get output:\n%s", out)
+	}
+	if got := runOK(t, "count", path); got != "3\n" {
+		t.Fatalf("expected count 3, got %q", got)
+	}
+	if got := runOK(t, "count", "-deleted", path); got != "4\n" {
+		t.Fatalf("expected count 4, got %q", got)
+	}
+	out = runOK(t, "grep", "-i", path, "nome", "^(ana|jo)")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "João") {
+		t.Fatalf("unexpected grep output:\n%s", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	path := writeTable(t)
+	for _, args := range [][]string{
+		nil,
+		{"list", path},
+		{"get", path},
+		{"get", path, "x"},
+		{"head", "-x", path},
+	} {
+		if err := run(args, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+			t.Fatalf("run(%v): expected usage error, got %v", args, err)
+		}
+	}
+	if err := run([]string{"get", path, "9"}, &bytes.Buffer{}); !errors.Is(err, dbfmini.ErrRecnoOutOfRange) {
+		t.Fatalf("expected ErrRecnoOutOfRange, got %v", err)
+	}
+	if err := run([]string{"grep", path, "CIDADE", "x"}, &bytes.Buffer{}); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
@@ -0,0 +1,311 @@
+package dbfmini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// --------------------------- Páginas de código ---------------------------
+
+const defaultCodePage = "ISO-8859-1"
+
+// codePages associa o nome canônico ao encoding; nil significa UTF-8
+// (sem conversão).
+var codePages = map[string]encoding.Encoding{
+	"CP437":        charmap.CodePage437,
+	"CP850":        charmap.CodePage850,
+	"CP852":        charmap.CodePage852,
+	"CP860":        charmap.CodePage860,
+	"CP863":        charmap.CodePage863,
+	"CP865":        charmap.CodePage865,
+	"CP855":        charmap.CodePage855,
+	"CP858":        charmap.CodePage858,
+	"CP862":        charmap.CodePage862,
+	"CP866":        charmap.CodePage866,
+	"CP874":        charmap.Windows874,
+	"CP932":        japanese.ShiftJIS,
+	"CP936":        simplifiedchinese.GBK,
+	"CP949":        korean.EUCKR,
+	"CP950":        traditionalchinese.Big5,
+	"CP1250":       charmap.Windows1250,
+	"CP1251":       charmap.Windows1251,
+	"CP1252":       charmap.Windows1252,
+	"CP1253":       charmap.Windows1253,
+	"CP1254":       charmap.Windows1254,
+	"CP1255":       charmap.Windows1255,
+	"CP1256":       charmap.Windows1256,
+	"CP1257":       charmap.Windows1257,
+	"CP1258":       charmap.Windows1258,
+	"MACINTOSH":    charmap.Macintosh,
+	"MAC-CYRILLIC": charmap.MacintoshCyrillic,
+	"ISO-8859-1":   charmap.ISO8859_1,
+	"ISO-8859-2":   charmap.ISO8859_2,
+	"ISO-8859-3":   charmap.ISO8859_3,
+	"ISO-8859-4":   charmap.ISO8859_4,
+	"ISO-8859-5":   charmap.ISO8859_5,
+	"ISO-8859-6":   charmap.ISO8859_6,
+	"ISO-8859-7":   charmap.ISO8859_7,
+	"ISO-8859-8":   charmap.ISO8859_8,
+	"ISO-8859-9":   charmap.ISO8859_9,
+	"ISO-8859-10":  charmap.ISO8859_10,
+	"ISO-8859-13":  charmap.ISO8859_13,
+	"ISO-8859-14":  charmap.ISO8859_14,
+	"ISO-8859-15":  charmap.ISO8859_15,
+	"ISO-8859-16":  charmap.ISO8859_16,
+	"KOI8-R":       charmap.KOI8R,
+	"KOI8-U":       charmap.KOI8U,
+	"UTF-8":        nil,
+}
+
+var codePageAliases = map[string]string{
+	"LATIN1":    "ISO-8859-1",
+	"LATIN2":    "ISO-8859-2",
+	"LATIN3":    "ISO-8859-3",
+	"LATIN4":    "ISO-8859-4",
+	"CYRILLIC":  "ISO-8859-5",
+	"ARABIC":    "ISO-8859-6",
+	"GREEK":     "ISO-8859-7",
+	"HEBREW":    "ISO-8859-8",
+	"LATIN5":    "ISO-8859-9",
+	"LATIN6":    "ISO-8859-10",
+	"LATIN7":    "ISO-8859-13",
+	"LATIN8":    "ISO-8859-14",
+	"LATIN9":    "ISO-8859-15",
+	"LATIN10":   "ISO-8859-16",
+	"KOI8R":     "KOI8-R",
+	"KOI8U":     "KOI8-U",
+	"UTF8":      "UTF-8",
+	"SHIFT-JIS": "CP932",
+	"SJIS":      "CP932",
+	"GBK":       "CP936",
+	"EUC-KR":    "CP949",
+	"BIG5":      "CP950",
+	"MAC-ROMAN": "MACINTOSH",
+}
+
+// codePagePrefixes normaliza variações como WINDOWS-1252, IBM850 e
+// ISO8859_5 para CP1252, CP850 e ISO-8859-5.
+var codePagePrefixes = []struct{ prefix, canonical string }{
+	{"WINDOWS-", "CP"},
+	{"WINDOWS", "CP"},
+	{"WIN-", "CP"},
+	{"IBM-", "CP"},
+	{"IBM", "CP"},
+	{"CP-", "CP"},
+	{"DOS-", "CP"},
+	{"ISO-8859-", "ISO-8859-"},
+	{"ISO8859-", "ISO-8859-"},
+	{"ISO8859", "ISO-8859-"},
+}
+
+// ldidCodePages mapeia o language driver ID (byte 29 do header) para a
+// página de códigos.
+var ldidCodePages = map[byte]string{
+	0x01: "CP437",        // U.S. MS-DOS
+	0x02: "CP850",        // International MS-DOS
+	0x03: "CP1252",       // Windows ANSI
+	0x04: "MACINTOSH",    // Standard Macintosh
+	0x08: "CP865",        // Danish OEM
+	0x09: "CP437",        // Dutch OEM
+	0x0a: "CP850",        // Dutch OEM*
+	0x0b: "CP437",        // Finnish OEM
+	0x0d: "CP437",        // French OEM
+	0x0e: "CP850",        // French OEM*
+	0x0f: "CP437",        // German OEM
+	0x10: "CP850",        // German OEM*
+	0x11: "CP437",        // Italian OEM
+	0x12: "CP850",        // Italian OEM*
+	0x13: "CP932",        // Japanese Shift-JIS
+	0x14: "CP850",        // Spanish OEM*
+	0x15: "CP437",        // Swedish OEM
+	0x16: "CP850",        // Swedish OEM*
+	0x17: "CP865",        // Norwegian OEM
+	0x18: "CP437",        // Spanish OEM
+	0x19: "CP437",        // English OEM (Britain)
+	0x1a: "CP850",        // English OEM (Britain)*
+	0x1b: "CP437",        // English OEM (U.S.)
+	0x1c: "CP863",        // French OEM (Canada)
+	0x1d: "CP850",        // French OEM*
+	0x1f: "CP852",        // Czech OEM
+	0x22: "CP852",        // Hungarian OEM
+	0x23: "CP852",        // Polish OEM
+	0x24: "CP860",        // Portuguese OEM
+	0x25: "CP850",        // Portuguese OEM*
+	0x26: "CP866",        // Russian OEM
+	0x37: "CP850",        // English OEM (U.S.)*
+	0x40: "CP852",        // Romanian OEM
+	0x4d: "CP936",        // Chinese GBK (PRC)
+	0x4e: "CP949",        // Korean (ANSI/OEM)
+	0x4f: "CP950",        // Chinese Big5 (Taiwan)
+	0x50: "CP874",        // Thai (ANSI/OEM)
+	0x57: "CP1252",       // ANSI
+	0x58: "CP1252",       // Western European ANSI
+	0x59: "CP1252",       // Spanish ANSI
+	0x64: "CP852",        // Eastern European MS-DOS
+	0x65: "CP866",        // Russian MS-DOS
+	0x66: "CP865",        // Nordic MS-DOS
+	0x6c: "CP863",        // French-Canadian MS-DOS
+	0x78: "CP950",        // Taiwan Big 5
+	0x79: "CP949",        // Hangul (Wansung)
+	0x7a: "CP936",        // PRC GBK
+	0x7b: "CP932",        // Japanese Shift-JIS
+	0x7c: "CP874",        // Thai Windows/MS-DOS
+	0x87: "CP852",        // Slovenian OEM
+	0x96: "MAC-CYRILLIC", // Russian Macintosh
+	0xc8: "CP1250",       // Eastern European Windows
+	0xc9: "CP1251",       // Russian Windows
+	0xca: "CP1254",       // Turkish Windows
+	0xcb: "CP1253",       // Greek Windows
+	0xcc: "CP1257",       // Baltic Windows
+}
+
+// codePageLDIDs é o LDID gravado por Create para cada página de códigos.
+var codePageLDIDs = map[string]byte{
+	"CP437":  0x01,
+	"CP850":  0x02,
+	"CP1252": 0x03,
+	"CP852":  0x64,
+	"CP866":  0x65,
+	"CP865":  0x66,
+	"CP860":  0x24,
+	"CP863":  0x6c,
+	"CP874":  0x7c,
+	"CP932":  0x7b,
+	"CP936":  0x7a,
+	"CP949":  0x79,
+	"CP950":  0x78,
+	"CP1250": 0xc8,
+	"CP1251": 0xc9,
+	"CP1253": 0xcb,
+	"CP1254": 0xca,
+	"CP1257": 0xcc,
+}
+
+// canonicalCodePage normaliza o nome (maiúsculas, separadores e apelidos).
+func canonicalCodePage(name string) string {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	n = strings.NewReplacer("_", "-", " ", "-").Replace(n)
+	if a, ok := codePageAliases[n]; ok {
+		return a
+	}
+	for _, p := range codePagePrefixes {
+		if rest, ok := strings.CutPrefix(n, p.prefix); ok && rest != "" && isDigits(rest) {
+			return p.canonical + rest
+		}
+	}
+	return n
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCPG interpreta o conteúdo de um arquivo .CPG (ex.: "UTF-8", "1252",
+// "ANSI 1251", "88591") e devolve o nome canônico, ou "" se desconhecido.
+func parseCPG(data []byte) string {
+	s := strings.ToUpper(strings.TrimSpace(string(data)))
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(s, "ANSI"), "OEM"))
+	if s == "" {
+		return ""
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		switch {
+		case n == 65001:
+			return "UTF-8"
+		case n >= 88591 && n <= 88599:
+			s = fmt.Sprintf("ISO-8859-%d", n-88590)
+		default:
+			s = fmt.Sprintf("CP%d", n)
+		}
+	}
+	s = canonicalCodePage(s)
+	if _, ok := codePages[s]; !ok {
+		return ""
+	}
+	return s
+}
+
+// readCPG lê o sidecar .CPG; devolve "" se não puder interpretá-lo.
+func readCPG(open opener) string {
+	r, c, err := open()
+	if err != nil {
+		return ""
+	}
+	if c != nil {
+		defer c.Close()
+	}
+	buf := make([]byte, 64)
+	n, _ := r.ReadAt(buf, 0)
+	return parseCPG(buf[:n])
+}
+
+func decodeBytes(data []byte, enc encoding.Encoding) string {
+	if enc == nil {
+		return string(data)
+	}
+	out, _ := io.ReadAll(transform.NewReader(bytes.NewReader(data), enc.NewDecoder()))
+	return string(out)
+}
+
+// encodeBytes converte texto Go para a página de códigos informada.
+func encodeBytes(s string, enc encoding.Encoding) ([]byte, error) {
+	if enc == nil {
+		return []byte(s), nil
+	}
+	out, _, err := transform.Bytes(enc.NewEncoder(), []byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("texto não representável na página de códigos: %q", s)
+	}
+	return out, nil
+}
+
+// lookupCodePage devolve o encoding pelo nome; nil significa UTF-8 (sem
+// conversão).
+func lookupCodePage(name string) (encoding.Encoding, error) {
+	cm, ok := codePages[canonicalCodePage(name)]
+	if !ok {
+		return nil, fmt.Errorf("página de códigos desconhecida: %q", name)
+	}
+	return cm, nil
+}
+
+// codec resolve o encoding de field ("" para o default): PerFieldCodec,
+// PerField, Codec e, por fim, Default.
+func (e Encoding) codec(field string) (encoding.Encoding, error) {
+	if field != "" {
+		if c := e.PerFieldCodec[field]; c != nil {
+			return c, nil
+		}
+		if v := e.PerField[field]; v != "" {
+			c, err := lookupCodePage(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field, err)
+			}
+			return c, nil
+		}
+	}
+	if e.Codec != nil {
+		return e.Codec, nil
+	}
+	name := e.Default
+	if name == "" {
+		name = defaultCodePage
+	}
+	return lookupCodePage(name)
+}
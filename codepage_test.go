@@ -0,0 +1,194 @@
+package dbfmini
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var nameField = []Field{{Name: "NOME", Type: 'C', Size: 12}}
+
+func withLDID(data []byte, ldid byte) []byte {
+	data[29] = ldid
+	return data
+}
+
+func TestOpenDetectsCodePageFromLDID(t *testing.T) {
+	dir := t.TempDir()
+	// "Привет" em CP866
+	path := writeFixture(t, dir, "ru.dbf", withLDID(buildDBF(0x03, nameField, [][]string{
+		{"\x8f\xe0\xa8\xa2\xa5\xe2"},
+	}), 0x26))
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.CodePage != "CP866" || db.LanguageDriver() != 0x26 {
+		t.Fatalf("CodePage = %q, LanguageDriver = 0x%02x; want CP866/0x26", db.CodePage, db.LanguageDriver())
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if got := records[0]["NOME"]; got != "Привет" {
+		t.Fatalf("NOME = %q, want %q", got, "Привет")
+	}
+
+	// Encoding.Default explícito continua tendo prioridade
+	db, err = Open(path, &OpenOptions{Encoding: Encoding{Default: "ISO-8859-1"}})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, _ = db.ReadRecords(0)
+	if got := records[0]["NOME"]; got == "Привет" {
+		t.Fatalf("explicit Encoding.Default was ignored")
+	}
+}
+
+func TestOpenPrefersCPGSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "shape.dbf", withLDID(buildDBF(0x03, nameField, [][]string{
+		{"São Paulo"},
+	}), 0x02))
+	writeFixture(t, dir, "shape.cpg", []byte("UTF-8\n"))
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.CodePage != "UTF-8" {
+		t.Fatalf("CodePage = %q, want UTF-8", db.CodePage)
+	}
+	records, _ := db.ReadRecords(0)
+	if got := records[0]["NOME"]; got != "São Paulo" {
+		t.Fatalf("NOME = %q, want %q", got, "São Paulo")
+	}
+}
+
+func TestCreateWritesLDID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.dbf")
+	w, err := Create(path, nameField, &CreateOptions{Encoding: "cp850"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := w.Append(Record{"NOME": "Ação"}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	w.Close()
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.LanguageDriver() != 0x02 || db.CodePage != "CP850" {
+		t.Fatalf("LanguageDriver = 0x%02x, CodePage = %q; want 0x02/CP850", db.LanguageDriver(), db.CodePage)
+	}
+	records, _ := db.ReadRecords(0)
+	if got := records[0]["NOME"]; got != "Ação" {
+		t.Fatalf("NOME = %q, want %q", got, "Ação")
+	}
+}
+
+func TestParseCPG(t *testing.T) {
+	cases := map[string]string{
+		"UTF-8":     "UTF-8",
+		"65001":     "UTF-8",
+		"1252":      "CP1252",
+		"ANSI 1251": "CP1251",
+		"88591":     "ISO-8859-1",
+		"cp850\r\n": "CP850",
+		"bogus":     "",
+		"":          "",
+	}
+	for in, want := range cases {
+		if got := parseCPG([]byte(in)); got != want {
+			t.Fatalf("parseCPG(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLookupCodePageNames(t *testing.T) {
+	known := []string{
+		"CP850", "cp852", "CP866", "CP1250", "windows-1251", "CP1253", "CP1254",
+		"CP1255", "CP1256", "CP1257", "CP874", "ISO-8859-2", "iso8859_5",
+		"ISO-8859-15", "latin2", "KOI8-R", "koi8r", "IBM437", "UTF-8",
+	}
+	for _, name := range known {
+		if _, err := lookupCodePage(name); err != nil {
+			t.Fatalf("lookupCodePage(%q) returned error: %v", name, err)
+		}
+	}
+	for _, name := range []string{"CP8500", "ISO-8859-1x", "LATN1"} {
+		if _, err := lookupCodePage(name); err == nil {
+			t.Fatalf("lookupCodePage(%q) should fail", name)
+		}
+	}
+}
+
+func TestOpenUnknownEncoding(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "t.dbf", buildDBF(0x03, nameField, [][]string{{"abc"}}))
+
+	if _, err := Open(path, &OpenOptions{Encoding: Encoding{Default: "CP8500"}}); err == nil {
+		t.Fatalf("Open with unknown encoding in strict mode should fail")
+	}
+	if _, err := Open(path, &OpenOptions{Encoding: Encoding{PerField: map[string]string{"NOME": "latim"}}}); err == nil {
+		t.Fatalf("Open with unknown per-field encoding in strict mode should fail")
+	}
+
+	db, err := Open(path, &OpenOptions{ReadMode: ReadLoose, Encoding: Encoding{Default: "CP8500"}})
+	if err != nil {
+		t.Fatalf("Open in loose mode returned error: %v", err)
+	}
+	records, _ := db.ReadRecords(0)
+	if got := records[0]["NOME"]; got != "abc" {
+		t.Fatalf("NOME = %q, want abc", got)
+	}
+}
+
+func TestOpenKOI8RAndCustomCodec(t *testing.T) {
+	dir := t.TempDir()
+	fields := []Field{
+		{Name: "RU", Type: 'C', Size: 6},
+		{Name: "EBC", Type: 'C', Size: 3},
+	}
+	// "Привет" em KOI8-R e "ABC" em EBCDIC (CP037)
+	path := writeFixture(t, dir, "t.dbf", buildDBF(0x03, fields, [][]string{
+		{"\xf0\xd2\xc9\xd7\xc5\xd4", "\xc1\xc2\xc3"},
+	}))
+
+	db, err := Open(path, &OpenOptions{Encoding: Encoding{
+		Default:       "KOI8-R",
+		PerFieldCodec: map[string]encoding.Encoding{"EBC": charmap.CodePage037},
+	}})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, _ := db.ReadRecords(0)
+	if records[0]["RU"] != "Привет" || records[0]["EBC"] != "ABC" {
+		t.Fatalf("record = %#v, want Привет/ABC", records[0])
+	}
+
+	out := filepath.Join(dir, "out.dbf")
+	w, err := Create(out, []Field{{Name: "EBC", Type: 'C', Size: 3}}, &CreateOptions{Codec: charmap.CodePage037})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	w.Append(Record{"EBC": "XYZ"})
+	w.Close()
+	db, err = Open(out, &OpenOptions{Encoding: Encoding{PerFieldCodec: map[string]encoding.Encoding{"EBC": charmap.CodePage037}}})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, _ = db.ReadRecords(0)
+	if records[0]["EBC"] != "XYZ" {
+		t.Fatalf("EBC = %#v, want XYZ", records[0]["EBC"])
+	}
+
+	if _, err := Create(filepath.Join(dir, "bad.dbf"), nameField, &CreateOptions{Encoding: "CP8500"}); err == nil {
+		t.Fatalf("Create with unknown encoding should fail")
+	}
+}
@@ -0,0 +1,217 @@
+// Package csv converte tabelas DBF para CSV e de volta.
+//
+// Writer e Export gravam os registros na ordem das colunas do arquivo, com
+// separador, formatos de data e decimal e tratamento de excluídos
+// configuráveis. Import faz o caminho inverso: cria um DBF a partir de um
+// CSV com linha de cabeçalho, usando o esquema informado ou um inferido
+// pelos valores (InferFields escolhe entre C, N, D, T e L e calcula os
+// tamanhos). Com as mesmas Options, um CSV gravado por Export é lido por
+// Import com os mesmos valores, usando db.Fields como esquema.
+package csv
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// DeletedMode define o tratamento dos registros excluídos. Eles só chegam
+// ao Writer se a tabela for aberta com OpenOptions.IncludeDeleted.
+type DeletedMode int
+
+const (
+	// SkipDeleted omite os registros excluídos (padrão).
+	SkipDeleted DeletedMode = iota
+	// KeepDeleted grava os excluídos como registros comuns.
+	KeepDeleted
+	// MarkDeleted acrescenta a coluna "_deleted" (T/F). Na importação, a
+	// coluna volta a marcar os registros como excluídos.
+	MarkDeleted
+)
+
+// DeletedColumn é o nome da coluna gravada com MarkDeleted.
+const DeletedColumn = "_deleted"
+
+// Options controla a conversão. O valor zero grava CSV padrão (vírgula,
+// ponto decimal, datas ISO).
+type Options struct {
+	// Comma é o separador de colunas. Default: ','.
+	Comma rune
+	// DateFormat é o layout (pacote time) dos campos D. Default: "2006-01-02".
+	DateFormat string
+	// DateTimeFormat é o layout dos campos T. Default: "2006-01-02 15:04:05".
+	DateTimeFormat string
+	// DecimalComma usa vírgula como separador decimal (ex.: "1234,56").
+	DecimalComma bool
+	Deleted      DeletedMode
+	// Create é usado por Import ao criar a tabela (versão, página de
+	// códigos, data do header).
+	Create *dbfmini.CreateOptions
+}
+
+func (o *Options) norm() Options {
+	var n Options
+	if o != nil {
+		n = *o
+	}
+	if n.Comma == 0 {
+		n.Comma = ','
+	}
+	if n.DateFormat == "" {
+		n.DateFormat = "2006-01-02"
+	}
+	if n.DateTimeFormat == "" {
+		n.DateTimeFormat = "2006-01-02 15:04:05"
+	}
+	return n
+}
+
+// --------------------------- Valores ---------------------------
+
+// formatValue converte o valor decodificado de f no texto da célula.
+func (o Options) formatValue(f dbfmini.Field, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return formatBool(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case dbfmini.Decimal:
+		s := v.String()
+		if o.DecimalComma {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s, nil
+	case float64:
+		prec := -1
+		if f.Type == 'N' || f.Type == 'F' {
+			prec = int(f.DecimalPlaces)
+		}
+		s := strconv.FormatFloat(v, 'f', prec, 64)
+		if o.DecimalComma {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s, nil
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		if f.Type == 'D' {
+			return v.Format(o.DateFormat), nil
+		}
+		return v.Format(o.DateTimeFormat), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	}
+	return "", fmt.Errorf("%s: tipo %T não suportado", f.Name, v)
+}
+
+// parseValue converte o texto da célula no valor aceito pelo Writer do
+// dbfmini para f. Células vazias viram nil (campo vazio).
+func (o Options) parseValue(f dbfmini.Field, s string) (any, error) {
+	if f.Type != 'C' && f.Type != 'M' {
+		s = strings.TrimSpace(s)
+	}
+	if s == "" {
+		return nil, nil
+	}
+	switch f.Type {
+	case 'C', 'M':
+		return s, nil
+	case 'N', 'F', 'Y', 'B':
+		x, ok := o.parseNumber(s)
+		if !ok {
+			return nil, fmt.Errorf("número inválido: %q", s)
+		}
+		if f.Type == 'B' {
+			return x, nil
+		}
+		if o.DecimalComma {
+			s = strings.Replace(s, ",", ".", 1)
+		}
+		return dbfmini.ParseDecimal(s) // exato, sem passar por float64
+	case 'I':
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("inteiro inválido: %q", s)
+		}
+		return int32(n), nil
+	case 'L':
+		b, ok := parseBool(s)
+		if !ok {
+			if s == "?" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("lógico inválido: %q", s)
+		}
+		return b, nil
+	case 'D':
+		t, err := time.Parse(o.DateFormat, s)
+		if err != nil {
+			return nil, fmt.Errorf("data inválida: %q", s)
+		}
+		return t, nil
+	case 'T':
+		t, err := time.Parse(o.DateTimeFormat, s)
+		if err != nil {
+			return nil, fmt.Errorf("data e hora inválida: %q", s)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("tipo %q não suportado", string(f.Type))
+}
+
+// parseNumber aceita sinal, dígitos e um separador decimal (ponto ou, com
+// DecimalComma, vírgula); não aceita expoente nem separador de milhar.
+func (o Options) parseNumber(s string) (float64, bool) {
+	sep := byte('.')
+	if o.DecimalComma {
+		sep = ','
+	}
+	digits, seen := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == sep && !seen:
+			seen = true
+		case (c == '-' || c == '+') && i == 0:
+		default:
+			return 0, false
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	x, err := strconv.ParseFloat(strings.Replace(s, string(sep), ".", 1), 64)
+	return x, err == nil
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "T"
+	}
+	return "F"
+}
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToUpper(s) {
+	case "T", ".T.", "TRUE":
+		return true, true
+	case "F", ".F.", "FALSE":
+		return false, true
+	}
+	return false, false
+}
+
+var errNoHeader = errors.New("CSV sem linha de cabeçalho")
@@ -0,0 +1,313 @@
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// --------------------------- Importação ---------------------------
+
+// maxNumericSize é o maior campo N inferido; números maiores viram texto.
+const maxNumericSize = 20
+
+// maxCharSize é o maior campo C gravado pelo dbfmini.
+const maxCharSize = 254
+
+// Import cria a tabela path a partir do CSV em r, cuja primeira linha tem
+// os nomes das colunas, e devolve o número de registros gravados.
+//
+// Com fields, cada coluna é associada ao campo de mesmo nome (sem
+// diferenciar maiúsculas) e os registros são gravados à medida que são
+// lidos; colunas fora do esquema geram erro e campos sem coluna ficam
+// vazios. Com fields nil, o CSV é lido inteiro e o esquema é inferido por
+// InferFields. Em caso de erro, o arquivo criado é removido.
+func Import(r io.Reader, path string, fields []dbfmini.Field, opts *Options) (int, error) {
+	o := opts.norm()
+	cr := stdcsv.NewReader(r)
+	cr.Comma = o.Comma
+	cr.ReuseRecord = fields != nil
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, errNoHeader
+	}
+	if err != nil {
+		return 0, err
+	}
+	header = append([]string(nil), header...)
+
+	var pending [][]string
+	inferred := fields == nil
+	if inferred {
+		if pending, err = cr.ReadAll(); err != nil {
+			return 0, err
+		}
+		if fields, err = InferFields(header, pending, opts); err != nil {
+			return 0, err
+		}
+	}
+	cols, deletedCol, err := mapColumns(header, fields, inferred, o)
+	if err != nil {
+		return 0, err
+	}
+
+	w, err := dbfmini.Create(path, fields, o.Create)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	appendRow := func(row []string) error {
+		rec := dbfmini.Record{}
+		for i, s := range row {
+			if i == deletedCol {
+				if b, _ := parseBool(strings.TrimSpace(s)); b {
+					rec["_deleted"] = true
+				}
+				continue
+			}
+			f := fields[cols[i]]
+			v, err := o.parseValue(f, s)
+			if err != nil {
+				return fmt.Errorf("linha %d, coluna %s: %w", n+2, f.Name, err)
+			}
+			if v != nil {
+				rec[f.Name] = v
+			}
+		}
+		if err := w.Append(rec); err != nil {
+			return fmt.Errorf("linha %d: %w", n+2, err)
+		}
+		n++
+		return nil
+	}
+	if pending != nil {
+		for _, row := range pending {
+			if err = appendRow(row); err != nil {
+				break
+			}
+		}
+	} else {
+		for {
+			var row []string
+			if row, err = cr.Read(); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				break
+			}
+			if err = appendRow(row); err != nil {
+				break
+			}
+		}
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+// mapColumns associa cada coluna do cabeçalho a um campo de fields: pela
+// posição, se o esquema foi inferido, ou pelo nome. Devolve também a
+// posição da coluna "_deleted" (-1 se não houver ou se Deleted não for
+// MarkDeleted).
+func mapColumns(header []string, fields []dbfmini.Field, inferred bool, o Options) ([]int, int, error) {
+	cols := make([]int, len(header))
+	deletedCol := -1
+	used := make([]bool, len(fields))
+	next := 0
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if o.Deleted == MarkDeleted && name == DeletedColumn {
+			deletedCol = i
+			continue
+		}
+		if inferred {
+			cols[i] = next
+			next++
+			continue
+		}
+		cols[i] = -1
+		for j, f := range fields {
+			if strings.EqualFold(f.Name, name) || strings.EqualFold(f.Name, fieldName(name)) {
+				cols[i] = j
+				break
+			}
+		}
+		if cols[i] < 0 {
+			return nil, 0, fmt.Errorf("coluna %q não está no esquema", name)
+		}
+		if used[cols[i]] {
+			return nil, 0, fmt.Errorf("coluna %q repetida", name)
+		}
+		used[cols[i]] = true
+	}
+	return cols, deletedCol, nil
+}
+
+// InferFields escolhe um campo para cada coluna do cabeçalho a partir dos
+// valores em rows (células vazias são ignoradas):
+//
+//   - L, se todos os valores forem T, F, .T., .F., TRUE ou FALSE;
+//   - D ou T, se todos seguirem DateFormat ou DateTimeFormat;
+//   - N, se todos forem números de até 20 posições, com as casas decimais
+//     do valor mais preciso (números com zeros à esquerda, como CEPs e
+//     códigos, continuam texto);
+//   - C nos demais casos, com o tamanho do maior valor (em caracteres; em
+//     bytes se Create.Encoding for UTF-8) e no máximo 254.
+//
+// Os nomes são derivados do cabeçalho: sem acentos, em maiúsculas, com
+// até 10 caracteres e sem repetições. A coluna "_deleted" de MarkDeleted
+// é ignorada.
+func InferFields(header []string, rows [][]string, opts *Options) ([]dbfmini.Field, error) {
+	o := opts.norm()
+	utf8Size := o.Create != nil && isUTF8(o.Create.Encoding)
+	var fields []dbfmini.Field
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if o.Deleted == MarkDeleted && name == DeletedColumn {
+			continue
+		}
+		f, err := o.inferColumn(rows, i, utf8Size)
+		if err != nil {
+			return nil, fmt.Errorf("coluna %q: %w", name, err)
+		}
+		f.Name = uniqueName(fieldName(name), len(fields)+1, seen)
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, errNoHeader
+	}
+	return fields, nil
+}
+
+func (o Options) inferColumn(rows [][]string, col int, utf8Size bool) (dbfmini.Field, error) {
+	isBool, isDate, isTime, isNum := true, true, true, true
+	width, intDigits, decimals, sign, values := 0, 0, 0, 0, 0
+	for _, row := range rows {
+		if col >= len(row) {
+			continue
+		}
+		raw := row[col]
+		n := utf8.RuneCountInString(raw)
+		if utf8Size {
+			n = len(raw)
+		}
+		width = max(width, n)
+		s := strings.TrimSpace(raw)
+		if s == "" {
+			continue
+		}
+		values++
+		if _, ok := parseBool(s); !ok {
+			isBool = false
+		}
+		if isDate {
+			_, err := o.parseValue(dbfmini.Field{Type: 'D'}, s)
+			isDate = err == nil
+		}
+		if isTime {
+			_, err := o.parseValue(dbfmini.Field{Type: 'T'}, s)
+			isTime = err == nil
+		}
+		if isNum {
+			if _, ok := o.parseNumber(s); !ok {
+				isNum = false
+				continue
+			}
+			digits := strings.TrimLeft(s, "+-")
+			if s[0] == '-' {
+				sign = 1
+			}
+			whole, frac, _ := strings.Cut(strings.Replace(digits, ",", ".", 1), ".")
+			if len(whole) > 1 && whole[0] == '0' {
+				isNum = false // código com zeros à esquerda
+				continue
+			}
+			intDigits = max(intDigits, len(whole))
+			decimals = max(decimals, len(frac))
+		}
+	}
+	switch {
+	case values == 0:
+		return dbfmini.Field{Type: 'C', Size: uint8(max(width, 1))}, nil
+	case isBool:
+		return dbfmini.Field{Type: 'L', Size: 1}, nil
+	case isDate:
+		return dbfmini.Field{Type: 'D', Size: 8}, nil
+	case isTime:
+		return dbfmini.Field{Type: 'T', Size: 8}, nil
+	}
+	if isNum {
+		size := sign + max(intDigits, 1)
+		if decimals > 0 {
+			size += 1 + decimals
+		}
+		if size <= maxNumericSize {
+			return dbfmini.Field{Type: 'N', Size: uint8(size), DecimalPlaces: uint8(decimals)}, nil
+		}
+	}
+	if width > maxCharSize {
+		return dbfmini.Field{}, fmt.Errorf("valor com %d caracteres excede o campo C(%d)", width, maxCharSize)
+	}
+	return dbfmini.Field{Type: 'C', Size: uint8(width)}, nil
+}
+
+// fieldName converte o nome de uma coluna em nome de campo DBF.
+func fieldName(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.Trim(b.String(), "_")
+	if name != "" && !unicode.IsLetter(rune(name[0])) {
+		name = "F" + name
+	}
+	if len(name) > 10 {
+		name = name[:10]
+	}
+	return name
+}
+
+// uniqueName evita nomes vazios ou repetidos acrescentando um número.
+func uniqueName(name string, pos int, seen map[string]bool) string {
+	if name == "" {
+		name = "CAMPO" + strconv.Itoa(pos)
+	}
+	base := name
+	for i := 2; seen[name]; i++ {
+		suffix := "_" + strconv.Itoa(i)
+		name = base[:min(len(base), 10-len(suffix))] + suffix
+	}
+	seen[name] = true
+	return name
+}
+
+func isUTF8(name string) bool {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "UTF-8", "UTF8":
+		return true
+	}
+	return false
+}
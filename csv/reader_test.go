@@ -0,0 +1,147 @@
+package csv
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+func readAll(t *testing.T, path string) []dbfmini.Record {
+	t.Helper()
+	db, err := dbfmini.Open(path, &dbfmini.OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	return recs
+}
+
+func TestImportRoundTrip(t *testing.T) {
+	src := writeTable(t)
+	db, err := dbfmini.Open(src, &dbfmini.OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	for _, opts := range []*Options{
+		{Deleted: MarkDeleted},
+		{Comma: ';', DecimalComma: true, DateFormat: "02/01/2006", DateTimeFormat: time.RFC3339, Deleted: MarkDeleted},
+	} {
+		var buf bytes.Buffer
+		if _, err := Export(&buf, db, opts); err != nil {
+			t.Fatalf("Export returned error: %v", err)
+		}
+		dst := filepath.Join(t.TempDir(), "copia.dbf")
+		n, err := Import(&buf, dst, db.Fields, opts)
+		if err != nil || n != 3 {
+			t.Fatalf("expected 3 rows, got %d/%v", n, err)
+		}
+		want, got := readAll(t, src), readAll(t, dst)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("round trip mismatch:\n got %v\nwant %v", got, want)
+		}
+	}
+}
+
+func TestImportRoundTripDecimal(t *testing.T) {
+	src := writeTable(t)
+	open := func(path string) *dbfmini.DBF {
+		db, err := dbfmini.Open(path, &dbfmini.OpenOptions{Numeric: dbfmini.NumericDecimal})
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		return db
+	}
+	db := open(src)
+	opts := &Options{Comma: ';', DecimalComma: true}
+	var buf bytes.Buffer
+	if _, err := Export(&buf, db, opts); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "copia.dbf")
+	if _, err := Import(&buf, dst, db.Fields, opts); err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	want, err := open(src).ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	got, err := open(dst).ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestImportInfersSchema(t *testing.T) {
+	data := "Código;Nome do cliente;Saldo;Ativo;Nascimento;CEP;Obs;Saldo\n" +
+		"1;Ana;10,5;T;1990-05-17;01310100;;1\n" +
+		"22;João;-3;.F.;;09000000;;2\n" +
+		"333;;1234,125;;2001-01-31;;;\n"
+	path := filepath.Join(t.TempDir(), "inferido.dbf")
+	opts := &Options{Comma: ';', DecimalComma: true, Create: &dbfmini.CreateOptions{Encoding: "CP1252"}}
+	n, err := Import(strings.NewReader(data), path, nil, opts)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 rows, got %d/%v", n, err)
+	}
+	db, err := dbfmini.Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	want := []dbfmini.Field{
+		{Name: "CODIGO", Type: 'N', Size: 3},
+		{Name: "NOME_DO_CL", Type: 'C', Size: 4},
+		{Name: "SALDO", Type: 'N', Size: 9, DecimalPlaces: 3},
+		{Name: "ATIVO", Type: 'L', Size: 1},
+		{Name: "NASCIMENTO", Type: 'D', Size: 8},
+		{Name: "CEP", Type: 'C', Size: 8},
+		{Name: "OBS", Type: 'C', Size: 1},
+		{Name: "SALDO_2", Type: 'N', Size: 1},
+	}
+	if !reflect.DeepEqual(db.Fields, want) {
+		t.Fatalf("unexpected schema:\n got %+v\nwant %+v", db.Fields, want)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if recs[1]["NOME_DO_CL"] != "João" || recs[1]["ATIVO"] != false || recs[0]["CEP"] != "01310100" ||
+		math.Abs(recs[2]["SALDO"].(float64)-1234.125) > 1e-9 || recs[1]["NASCIMENTO"] != nil {
+		t.Fatalf("unexpected records: %v", recs)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	fields := testFields[:2]
+	cases := map[string]string{
+		"empty":          "",
+		"unknown column": "NOME,CIDADE\nA,B\n",
+		"bad number":     "NOME,SALDO\nA,1e3\n",
+		"too long":       "NOME,SALDO\nNome muito comprido,1\n",
+		"short row":      "NOME,SALDO\nA\n",
+	}
+	for name, data := range cases {
+		path := filepath.Join(dir, "erro.dbf")
+		if _, err := Import(strings.NewReader(data), path, fields, nil); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if _, err := dbfmini.Open(path, nil); err == nil {
+			t.Fatalf("%s: expected table to be removed", name)
+		}
+	}
+	long := "X\n" + strings.Repeat("a", 300) + "\n"
+	if _, err := Import(strings.NewReader(long), filepath.Join(dir, "longo.dbf"), nil, nil); err == nil {
+		t.Fatalf("expected error for text longer than 254 characters")
+	}
+}
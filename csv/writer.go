@@ -0,0 +1,97 @@
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// --------------------------- Exportação ---------------------------
+
+// Writer grava registros de uma tabela como linhas CSV, na ordem de
+// fields.
+type Writer struct {
+	w      *stdcsv.Writer
+	fields []dbfmini.Field
+	opt    Options
+	row    []string
+}
+
+// NewWriter cria um Writer para registros com os campos fields
+// (normalmente db.Fields).
+func NewWriter(w io.Writer, fields []dbfmini.Field, opts *Options) *Writer {
+	cw := stdcsv.NewWriter(w)
+	o := opts.norm()
+	cw.Comma = o.Comma
+	return &Writer{w: cw, fields: fields, opt: o}
+}
+
+// WriteHeader grava a linha com os nomes dos campos.
+func (w *Writer) WriteHeader() error {
+	w.row = w.row[:0]
+	for _, f := range w.fields {
+		w.row = append(w.row, f.Name)
+	}
+	if w.opt.Deleted == MarkDeleted {
+		w.row = append(w.row, DeletedColumn)
+	}
+	return w.w.Write(w.row)
+}
+
+// Write grava rec, ou nada se ele estiver excluído e Deleted for
+// SkipDeleted.
+func (w *Writer) Write(rec dbfmini.Record) error {
+	deleted := rec["_deleted"] == true
+	if deleted && w.opt.Deleted == SkipDeleted {
+		return nil
+	}
+	w.row = w.row[:0]
+	for _, f := range w.fields {
+		s, err := w.opt.formatValue(f, rec[f.Name])
+		if err != nil {
+			return err
+		}
+		w.row = append(w.row, s)
+	}
+	if w.opt.Deleted == MarkDeleted {
+		w.row = append(w.row, formatBool(deleted))
+	}
+	return w.w.Write(w.row)
+}
+
+// Flush grava os dados pendentes e devolve o primeiro erro de escrita.
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Export grava o cabeçalho e todos os registros de db em w e devolve o
+// número de linhas de dados; registros excluídos seguem Options.Deleted.
+func Export(w io.Writer, db *dbfmini.DBF, opts *Options) (int, error) {
+	cw := NewWriter(w, db.Fields, opts)
+	if err := cw.WriteHeader(); err != nil {
+		return 0, err
+	}
+	rows, err := db.Query().Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		rec := rows.Record()
+		if rec["_deleted"] == true && cw.opt.Deleted == SkipDeleted {
+			continue
+		}
+		if err := cw.Write(rec); err != nil {
+			return n, fmt.Errorf("registro %d: %w", rows.Recno(), err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, cw.Flush()
+}
@@ -0,0 +1,88 @@
+package csv
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+	"github.com/alberto255345/dbfmini/internal/dbftest"
+)
+
+var testFields = []dbfmini.Field{
+	{Name: "NOME", Type: 'C', Size: 12},
+	{Name: "SALDO", Type: 'N', Size: 10, DecimalPlaces: 2},
+	{Name: "TAXA", Type: 'F', Size: 8, DecimalPlaces: 3},
+	{Name: "CURR", Type: 'Y'},
+	{Name: "ATIVO", Type: 'L'},
+	{Name: "NASC", Type: 'D'},
+	{Name: "QTD", Type: 'I'},
+	{Name: "RATIO", Type: 'B'},
+	{Name: "STAMP", Type: 'T'},
+}
+
+func writeTable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clientes.dbf")
+	dbftest.Table(t, path, testFields, nil, []dbfmini.Record{
+		{"NOME": "José, \"Zé\"", "SALDO": 1234.5, "TAXA": 0.125, "CURR": 12.3456, "ATIVO": true,
+			"NASC": time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC), "QTD": int32(-7), "RATIO": 3.14159,
+			"STAMP": time.Date(2024, time.January, 2, 10, 30, 15, 0, time.UTC)},
+		{"NOME": "Vazio"},
+		{"NOME": "Excluído", "SALDO": -1, "_deleted": true},
+	})
+	return path
+}
+
+func TestExport(t *testing.T) {
+	path := writeTable(t)
+	db, err := dbfmini.Open(path, &dbfmini.OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := Export(&buf, db, nil)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 rows, got %d/%v", n, err)
+	}
+	want := "NOME,SALDO,TAXA,CURR,ATIVO,NASC,QTD,RATIO,STAMP\n" +
+		"\"José, \"\"Zé\"\"\",1234.50,0.125,12.3456,T,1990-05-17,-7,3.14159,2024-01-02 10:30:15\n" +
+		"Vazio,,,0,,,0,0,\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	opts := &Options{Comma: ';', DecimalComma: true, DateFormat: "02/01/2006", Deleted: MarkDeleted}
+	if n, err = Export(&buf, db, opts); err != nil || n != 3 {
+		t.Fatalf("expected 3 rows, got %d/%v", n, err)
+	}
+	want = "NOME;SALDO;TAXA;CURR;ATIVO;NASC;QTD;RATIO;STAMP;_deleted\n" +
+		"\"José, \"\"Zé\"\"\";1234,50;0,125;12,3456;T;17/05/1990;-7;3,14159;2024-01-02 10:30:15;F\n" +
+		"Vazio;;;0;;;0;0;;F\n" +
+		"Excluído;-1,00;;0;;;0;0;;T\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriterSkipsDeleted(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, testFields[:2], &Options{Comma: '\t'})
+	if err := w.WriteHeader(); err != nil {
+		t.Fatalf("WriteHeader returned error: %v", err)
+	}
+	w.Write(dbfmini.Record{"NOME": "A", "SALDO": 1.0})
+	w.Write(dbfmini.Record{"NOME": "B", "_deleted": true})
+	if err := w.Write(dbfmini.Record{"NOME": 1}); err == nil {
+		t.Fatalf("expected error for unsupported value")
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if got := buf.String(); got != "NOME\tSALDO\nA\t1.00\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
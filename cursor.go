@@ -0,0 +1,201 @@
+package dbfmini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// --------------------------- Acesso por número de registro ---------------------------
+
+// ErrRecnoOutOfRange indica número de registro fora de 1..RecordCount.
+var ErrRecnoOutOfRange = errors.New("número de registro fora da faixa")
+
+// ReadAt lê o registro de número recno (base 1, como RECNO() do xBase) sem
+// mover o cursor. Registros excluídos também são devolvidos, com
+// "_deleted": true, independentemente de IncludeDeleted. Em ReadLoose, um
+// registro com campos ilegíveis volta sem esses campos em vez de dar erro
+// (o mesmo vale para o registro carregado por GoTo, Skip, GoTop e GoBottom).
+func (d *DBF) ReadAt(recno uint32) (Record, error) {
+	if err := d.checkRecno(recno); err != nil {
+		return nil, err
+	}
+	f, c, err := d.src()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		defer c.Close()
+	}
+	release, err := d.attachMemo()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return d.readRecno(f, recno)
+}
+
+// Recno devolve o número (base 1) do registro atual: o último carregado
+// por Next, GoTo, Skip, GoTop ou GoBottom, ou o último devolvido por
+// ReadRecords. É 0 antes do primeiro registro e RecordCount+1 depois do
+// último (EOF).
+func (d *DBF) Recno() uint32 { return d.recno }
+
+// GoTo posiciona o cursor no registro recno e o carrega em Record, mesmo
+// que esteja excluído (como GOTO do xBase). Next e ReadRecords continuam a
+// partir do registro seguinte.
+func (d *DBF) GoTo(recno uint32) error {
+	if err := d.checkRecno(recno); err != nil {
+		return err
+	}
+	return d.withSource(func(f io.ReaderAt) error {
+		return d.load(f, recno)
+	})
+}
+
+// GoTop posiciona o cursor no primeiro registro visível (os excluídos são
+// pulados, a menos que IncludeDeleted esteja ativo). Devolve io.EOF se não
+// houver nenhum.
+func (d *DBF) GoTop() error {
+	return d.skipFrom(0, 1)
+}
+
+// GoBottom posiciona o cursor no último registro visível. Devolve io.EOF
+// se não houver nenhum.
+func (d *DBF) GoBottom() error {
+	return d.skipFrom(d.RecordCount+1, -1)
+}
+
+// Skip move o cursor n registros visíveis para a frente (n>0) ou para trás
+// (n<0) a partir de Recno. Ao passar do último registro o cursor fica em
+// EOF (Recno = RecordCount+1) e, antes do primeiro, em 0; nos dois casos
+// Record fica nil e Skip devolve io.EOF.
+func (d *DBF) Skip(n int) error {
+	if n == 0 {
+		return nil
+	}
+	return d.skipFrom(d.recno, n)
+}
+
+func (d *DBF) skipFrom(from uint32, n int) error {
+	return d.withSource(func(f io.ReaderAt) error {
+		step := int64(1)
+		if n < 0 {
+			step, n = -1, -n
+		}
+		pos := int64(from)
+		for ; n > 0; n-- {
+			next, err := d.nextVisible(f, pos+step, step)
+			if err != nil {
+				return err
+			}
+			pos = next
+			if pos < 1 || pos > int64(d.RecordCount) {
+				d.cur = nil
+				if pos < 1 {
+					d.recno, d.recordsRead = 0, 0
+				} else {
+					d.recno, d.recordsRead = d.RecordCount+1, d.RecordCount
+				}
+				return io.EOF
+			}
+		}
+		return d.load(f, uint32(pos))
+	})
+}
+
+// nextVisible procura, a partir de pos e no sentido step, o primeiro
+// registro visível. Devolve 0 ou RecordCount+1 se sair da tabela.
+func (d *DBF) nextVisible(f io.ReaderAt, pos, step int64) (int64, error) {
+	flag := make([]byte, 1)
+	for ; pos >= 1 && pos <= int64(d.RecordCount); pos += step {
+		if d.opt.IncludeDeleted {
+			return pos, nil
+		}
+		if _, err := f.ReadAt(flag, d.recordOffset(int(pos-1))); err != nil {
+			return 0, fmt.Errorf("lendo registro %d: %w", pos, err)
+		}
+		if flag[0] != 0x2A {
+			return pos, nil
+		}
+	}
+	return pos, nil
+}
+
+// withSource interrompe a leitura em fluxo e abre tabela e memo para fn.
+func (d *DBF) withSource(fn func(f io.ReaderAt) error) error {
+	d.stopScan()
+	d.err = nil
+	f, c, err := d.src()
+	if err != nil {
+		return err
+	}
+	if c != nil {
+		defer c.Close()
+	}
+	release, err := d.attachMemo()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(f)
+}
+
+// load carrega o registro recno como registro atual do cursor.
+func (d *DBF) load(f io.ReaderAt, recno uint32) error {
+	rec, err := d.readRecno(f, recno)
+	if err != nil {
+		return err
+	}
+	d.cur = rec
+	d.recno = recno
+	d.recordsRead = recno
+	return nil
+}
+
+func (d *DBF) readRecno(f io.ReaderAt, recno uint32) (Record, error) {
+	buf := make([]byte, d.recordLen)
+	if _, err := f.ReadAt(buf, d.recordOffset(int(recno-1))); err != nil {
+		return nil, fmt.Errorf("lendo registro %d: %w", recno, err)
+	}
+	rec, _, err := d.decodeRecord(buf, true)
+	if err != nil {
+		if d.opt.ReadMode == ReadStrict {
+			return nil, fmt.Errorf("registro %d: %w", recno, err)
+		}
+		// Next pularia o registro; aqui ele foi pedido pelo número, então
+		// devolvemos o que foi possível ler
+		rec = d.decodeReadable(buf)
+	}
+	d.tagRecno(rec, recno)
+	return rec, nil
+}
+
+// decodeReadable converte o registro bruto b deixando de fora os campos
+// que não decodificam (ReadLoose).
+func (d *DBF) decodeReadable(b []byte) Record {
+	rec := Record{}
+	for i, f := range d.Fields {
+		if v, ok, err := d.decodeCell(i, b); err == nil && ok {
+			rec[f.Name] = v
+		}
+	}
+	if b[0] == 0x2A {
+		rec["_deleted"] = true
+	}
+	return rec
+}
+
+// tagRecno acrescenta "_recno" a rec se IncludeRecno estiver ativo.
+func (d *DBF) tagRecno(rec Record, recno uint32) {
+	if d.opt.IncludeRecno {
+		rec["_recno"] = recno
+	}
+}
+
+func (d *DBF) checkRecno(recno uint32) error {
+	if recno < 1 || recno > d.RecordCount {
+		return fmt.Errorf("%w: %d (1..%d)", ErrRecnoOutOfRange, recno, d.RecordCount)
+	}
+	return nil
+}
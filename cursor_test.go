@@ -0,0 +1,190 @@
+package dbfmini
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCursorFixture(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41, "_deleted": true},
+		Record{"NAME": "Carla", "AGE": 25},
+		Record{"NAME": "Davi", "AGE": 52},
+		Record{"NAME": "Eva", "AGE": 19, "_deleted": true},
+	)
+	return path
+}
+
+// writeTruncatedFixture grava uma tabela de um registro cujo header
+// declara registros de 5 bytes, curtos demais para o campo B (ReadLoose a
+// abre; ReadStrict a rejeita).
+func writeTruncatedFixture(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "short.dbf")
+	w, err := Create(path, []Field{{Name: "A", Type: 'C', Size: 3}, {Name: "B", Type: 'C', Size: 3}}, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := w.Append(Record{"A": "Ana", "B": "Bia"}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	binary.LittleEndian.PutUint16(data[10:12], 5)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestReadAtLooseOmitsUnreadableFields(t *testing.T) {
+	db, err := Open(writeTruncatedFixture(t), &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	rec, err := db.ReadAt(1)
+	if err != nil {
+		t.Fatalf("ReadAt returned error: %v", err)
+	}
+	if _, ok := rec["B"]; rec["A"] != "Ana" || ok {
+		t.Fatalf("ReadAt(1) = %v, want A only", rec)
+	}
+	if err := db.GoTop(); err != nil || db.Record()["A"] != "Ana" {
+		t.Fatalf("GoTop = %v, record %v", err, db.Record())
+	}
+}
+
+func TestReadAtReturnsRecordByNumber(t *testing.T) {
+	db, err := Open(writeCursorFixture(t), nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	rec, err := db.ReadAt(3)
+	if err != nil {
+		t.Fatalf("ReadAt returned error: %v", err)
+	}
+	if rec["NAME"] != "Carla" {
+		t.Fatalf("expected Carla, got %v", rec["NAME"])
+	}
+
+	rec, err = db.ReadAt(2)
+	if err != nil {
+		t.Fatalf("ReadAt returned error: %v", err)
+	}
+	if rec["NAME"] != "Bruno" || rec["_deleted"] != true {
+		t.Fatalf("expected deleted Bruno, got %v", rec)
+	}
+
+	for _, n := range []uint32{0, 6} {
+		if _, err := db.ReadAt(n); !errors.Is(err, ErrRecnoOutOfRange) {
+			t.Fatalf("ReadAt(%d): expected ErrRecnoOutOfRange, got %v", n, err)
+		}
+	}
+
+	// ReadAt não move o cursor.
+	if !db.Next() || db.Record()["NAME"] != "Ana" || db.Recno() != 1 {
+		t.Fatalf("expected Next to start at record 1, got %v (recno %d)", db.Record(), db.Recno())
+	}
+}
+
+func TestCursorNavigation(t *testing.T) {
+	db, err := Open(writeCursorFixture(t), nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	if db.Recno() != 0 {
+		t.Fatalf("expected recno 0 before reading, got %d", db.Recno())
+	}
+
+	if err := db.GoTo(2); err != nil {
+		t.Fatalf("GoTo returned error: %v", err)
+	}
+	if db.Recno() != 2 || db.Record()["NAME"] != "Bruno" {
+		t.Fatalf("expected record 2 (Bruno), got %d %v", db.Recno(), db.Record())
+	}
+	if !db.Next() || db.Recno() != 3 || db.Record()["NAME"] != "Carla" {
+		t.Fatalf("expected Next after GoTo to load record 3, got %d %v", db.Recno(), db.Record())
+	}
+
+	if err := db.Skip(1); err != nil {
+		t.Fatalf("Skip returned error: %v", err)
+	}
+	if db.Recno() != 4 {
+		t.Fatalf("expected recno 4, got %d", db.Recno())
+	}
+	if err := db.Skip(-2); err != nil {
+		t.Fatalf("Skip returned error: %v", err)
+	}
+	if db.Recno() != 1 {
+		t.Fatalf("expected Skip(-2) to pass over deleted record 2, got %d", db.Recno())
+	}
+
+	if err := db.GoBottom(); err != nil {
+		t.Fatalf("GoBottom returned error: %v", err)
+	}
+	if db.Recno() != 4 || db.Record()["NAME"] != "Davi" {
+		t.Fatalf("expected last visible record 4, got %d %v", db.Recno(), db.Record())
+	}
+	if err := db.Skip(1); err != io.EOF {
+		t.Fatalf("expected io.EOF past the end, got %v", err)
+	}
+	if db.Recno() != db.RecordCount+1 || db.Record() != nil {
+		t.Fatalf("expected EOF position, got %d %v", db.Recno(), db.Record())
+	}
+	if db.Next() {
+		t.Fatalf("expected Next to return false at EOF")
+	}
+
+	if err := db.GoTop(); err != nil {
+		t.Fatalf("GoTop returned error: %v", err)
+	}
+	if db.Recno() != 1 {
+		t.Fatalf("expected GoTop at record 1, got %d", db.Recno())
+	}
+	if err := db.Skip(-1); err != io.EOF || db.Recno() != 0 {
+		t.Fatalf("expected io.EOF and recno 0 before the start, got %v %d", err, db.Recno())
+	}
+
+	if err := db.GoTo(9); !errors.Is(err, ErrRecnoOutOfRange) {
+		t.Fatalf("expected ErrRecnoOutOfRange, got %v", err)
+	}
+}
+
+func TestIncludeRecno(t *testing.T) {
+	db, err := Open(writeCursorFixture(t), &OpenOptions{IncludeRecno: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	want := []uint32{1, 3, 4}
+	if len(recs) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(recs))
+	}
+	for i, rec := range recs {
+		if rec["_recno"] != want[i] {
+			t.Fatalf("record %d: expected _recno %d, got %v", i, want[i], rec["_recno"])
+		}
+	}
+	if db.Recno() != 5 {
+		t.Fatalf("expected recno 5 after ReadRecords, got %d", db.Recno())
+	}
+}
@@ -8,13 +8,13 @@ import (
 	"io"
 	"math"
 	"os"
-	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
 )
 
 // --------------------------- Tipos públicos ---------------------------
@@ -26,60 +26,130 @@ const (
 	ReadLoose  ReadMode = "loose"
 )
 
-// Encoding permite um default e (opcional) overrides por campo.
+// NumericMode define o tipo devolvido para campos N, F e Y.
+type NumericMode string
+
+const (
+	// NumericFloat devolve float64 (default).
+	NumericFloat NumericMode = "float"
+	// NumericDecimal devolve Decimal exato; campos N sem casas decimais
+	// são devolvidos como int64.
+	NumericDecimal NumericMode = "decimal"
+)
+
+// Encoding permite um default e (opcional) overrides por campo, por nome
+// de página de códigos ou diretamente com qualquer encoding.Encoding.
 type Encoding struct {
 	Default  string
 	PerField map[string]string // ex.: {"NOME": "CP1252"}
+
+	// Codec, se definido, substitui Default.
+	Codec encoding.Encoding
+	// PerFieldCodec tem prioridade sobre PerField.
+	PerFieldCodec map[string]encoding.Encoding
 }
 
 type OpenOptions struct {
 	ReadMode       ReadMode
-	Encoding       Encoding // Default: ISO-8859-1
+	Encoding       Encoding // Default: .CPG, LDID do header ou ISO-8859-1
 	IncludeDeleted bool
+	// IncludeRecno adiciona a cada registro a chave "_recno" (uint32) com o
+	// número físico do registro, base 1.
+	IncludeRecno bool
+	// Numeric escolhe entre float64 (default) e Decimal para N, F e Y.
+	Numeric NumericMode
 }
 
 type Field struct {
 	Name          string
-	Type          byte // 'C','N','F','Y','L','D','I','M','T','B'
+	Type          byte // 'C','N','F','Y','L','D','I','M','T','B','G','W','V','Q'
 	Size          uint8
 	DecimalPlaces uint8
+	// Flags é o byte 18 do descritor no Visual FoxPro (FieldNullable,
+	// FieldBinary...). Create não o grava.
+	Flags byte
 }
 
+// Flags de campo do Visual FoxPro.
+const (
+	FieldSystem   byte = 0x01 // coluna de sistema, oculta (_NullFlags)
+	FieldNullable byte = 0x02 // aceita .NULL., marcado em _NullFlags
+	FieldBinary   byte = 0x04 // sem tradução de página de códigos
+)
+
 type DBF struct {
 	Path          string
 	RecordCount   uint32
 	DateOfLastUpd time.Time
 	Fields        []Field
+	// CodePage é a página de códigos detectada pelo .CPG ou pelo language
+	// driver ID do header ("" se nenhum dos dois a indica).
+	CodePage string
 
 	// internos
 	version     byte
+	flags       byte // byte 28: 0x01 = índice de produção (.MDX/.CDX)
+	ldid        byte
 	headerLen   uint16
 	recordLen   uint16
+	codecs      []encoding.Encoding // por campo, resolvidos na abertura
+	offsets     []int               // posição de cada campo no registro
+	nullOff     int                 // posição de _NullFlags
+	nullBits    []flagBits          // bits de cada campo em _NullFlags; nil sem _NullFlags
 	memoPath    string
+	src         opener // abre a tabela para leitura
+	sibling     siblingFunc
+	memoSrc     opener // abre o memo; nil se não houver
+	memo        *memoFile
+	memoRefs    int      // leituras em andamento que usam memo (attachMemo)
+	rw          *os.File // handle de escrita (OpenForUpdate)
+	scan        *scanState
+	cur         Record
+	err         error
+	plans       map[reflect.Type]*decodePlan
 	opt         OpenOptions
 	recordsRead uint32
+	recno       uint32     // registro atual (0 = antes do primeiro)
+	indexes     []IndexDef // tags do .cdx estrutural mantidas na atualização
+	indexPath   string
+	tags        []indexTag // chaves do .cdx estrutural em memória; nil até a 1ª alteração
 }
 
 // Record é um mapa com valores tipados por Go nativo.
-// Se IncludeDeleted=true, adicionamos a chave "_deleted": bool.
+// Se IncludeDeleted=true, adicionamos a chave "_deleted": bool; se
+// IncludeRecno=true, a chave "_recno": uint32.
 type Record map[string]any
 
 // --------------------------- Abertura ---------------------------
 
 func Open(path string, opts *OpenOptions) (*DBF, error) {
-	if opts == nil {
-		opts = &OpenOptions{}
+	if _, err := os.Stat(path + packSuffix); err == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrPackPending)
+	}
+	return openTable(fileOpener(path), path, fileSibling, nil, opts)
+}
+
+// openTable lê header e descritores a partir de open. Os arquivos de memo
+// são procurados ao lado de path via sibling ou, se sibling for nil,
+// recebidos prontos em memo.
+func openTable(open opener, path string, sibling siblingFunc, memo opener, opts *OpenOptions) (*DBF, error) {
+	o := OpenOptions{}
+	if opts != nil {
+		o = *opts
 	}
+	opts = &o
 	normOptions(opts)
 
-	f, err := os.Open(path)
+	f, c, err := open()
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	if c != nil {
+		defer c.Close()
+	}
 
 	hdr := make([]byte, 32)
-	if _, err := io.ReadFull(f, hdr); err != nil {
+	if _, err := f.ReadAt(hdr, 0); err != nil {
 		return nil, fmt.Errorf("lendo header: %w", err)
 	}
 
@@ -100,38 +170,56 @@ func Open(path string, opts *OpenOptions) (*DBF, error) {
 
 	// Localiza arquivo de memo (quando aplicável)
 	memoPath := ""
-	ext := strings.ToLower(filepath.Ext(path))
-	if version == 0x83 || version == 0x8b { // dBase III/IV com memo .dbt
-		for _, e := range []string{".dbt", ".DBT"} {
-			p := path[:len(path)-len(ext)] + e
-			if _, err := os.Stat(p); err == nil {
-				memoPath = p
-				break
+	if sibling != nil {
+		switch {
+		case version == 0x83 || version == 0x8b: // dBase III/IV com memo .dbt
+			memo, memoPath = findSibling(path, sibling, ".dbt", ".DBT")
+			if memo == nil && opts.ReadMode == ReadStrict {
+				return nil, errors.New("memo .DBT não encontrado (modo strict)")
 			}
+		case usesFPT(version): // VFP/FoxPro podem usar .fpt
+			memo, memoPath = findSibling(path, sibling, ".fpt", ".FPT")
 		}
-		if memoPath == "" && opts.ReadMode == ReadStrict {
-			return nil, errors.New("memo .DBT não encontrado (modo strict)")
+	} else if memo != nil && !(version == 0x83 || version == 0x8b || usesFPT(version)) {
+		memo = nil // versão sem memo
+	} else if memo == nil && (version == 0x83 || version == 0x8b) && opts.ReadMode == ReadStrict {
+		return nil, errors.New("memo .DBT não informado (modo strict)")
+	}
+
+	// Página de códigos: .CPG ao lado da tabela > LDID do header; só é usada
+	// quando o chamador não define Encoding.Default.
+	ldid := hdr[29]
+	codePage := ldidCodePages[ldid]
+	if sibling != nil {
+		if cpg, _ := findSibling(path, sibling, ".cpg", ".CPG"); cpg != nil {
+			if name := readCPG(cpg); name != "" {
+				codePage = name
+			}
 		}
 	}
-	if version == 0x30 || version == 0xf5 { // VFP/FoxPro podem usar .fpt
-		for _, e := range []string{".fpt", ".FPT"} {
-			p := path[:len(path)-len(ext)] + e
-			if _, err := os.Stat(p); err == nil {
-				memoPath = p
-				break
-			}
+	if opts.Encoding.Default == "" {
+		opts.Encoding.Default = codePage
+		if codePage == "" {
+			opts.Encoding.Default = defaultCodePage
 		}
 	}
+	nameEnc, err := opts.resolveCodec("")
+	if err != nil {
+		return nil, err
+	}
 
 	// Lê descritores de campos (32 bytes cada) até 0x0D
 	var fields []Field
+	var offsets []int
+	off := 1 // posição do campo no registro (após o flag de exclusão)
+	nullOff, nullSize := 0, 0
 	pos := int64(32)
 	for {
 		if pos >= int64(headerLen) {
 			break
 		}
 		des := make([]byte, 32)
-		if _, err := f.ReadAt(des, pos); err != nil {
+		if n, err := f.ReadAt(des, pos); err != nil && (n == 0 || des[0] != 0x0D) {
 			return nil, fmt.Errorf("lendo field descriptor: %w", err)
 		}
 		pos += 32
@@ -146,7 +234,7 @@ func Open(path string, opts *OpenOptions) (*DBF, error) {
 		if zero == -1 {
 			zero = 11
 		}
-		name := decodeBytes(nameRaw[:zero], fieldEncoding(opts.Encoding, "")) // nome usa encoding default
+		name := decodeBytes(nameRaw[:zero], nameEnc) // nome usa encoding default
 
 		ftype := des[11] // 0x0B
 		size := des[16]  // field length
@@ -157,6 +245,7 @@ func Open(path string, opts *OpenOptions) (*DBF, error) {
 			Type:          ftype,
 			Size:          size,
 			DecimalPlaces: dec,
+			Flags:         des[18],
 		}
 
 		// Validações básicas em modo strict
@@ -170,11 +259,41 @@ func Open(path string, opts *OpenOptions) (*DBF, error) {
 				}
 			}
 		}
-		fields = append(fields, field)
+		if ftype == '0' { // _NullFlags (VFP): oculto, só guarda os bits
+			nullOff, nullSize = off, int(size)
+		} else {
+			fields = append(fields, field)
+			offsets = append(offsets, off)
+		}
+		off += int(size)
+	}
+
+	nullBits, err := assignFlagBits(fields, nullSize)
+	if err != nil && opts.ReadMode == ReadStrict {
+		return nil, err
+	}
+
+	// VFP só indica memo pelos campos: sem .FPT os blobs seriam perdidos
+	if usesFPT(version) && memo == nil && opts.ReadMode == ReadStrict {
+		for _, f := range fields {
+			if isMemoType(f.Type) {
+				return nil, errors.New("memo .FPT não encontrado (modo strict)")
+			}
+		}
+	}
+
+	codecs := make([]encoding.Encoding, len(fields))
+	for i, f := range fields {
+		if f.Flags&FieldBinary != 0 && (f.Type == 'C' || f.Type == 'V' || f.Type == 'M') {
+			continue // binário: sem tradução de página de códigos (codec nil)
+		}
+		if codecs[i], err = opts.resolveCodec(f.Name); err != nil {
+			return nil, err
+		}
 	}
 
 	// Confere comprimento de registro
-	calculated := calcRecordLen(fields)
+	calculated := uint16(off)
 	if opts.ReadMode == ReadStrict && calculated != recordLen {
 		return nil, fmt.Errorf("tamanho de registro inconsistente: header=%d calculado=%d", recordLen, calculated)
 	}
@@ -185,9 +304,19 @@ func Open(path string, opts *OpenOptions) (*DBF, error) {
 		DateOfLastUpd: date,
 		Fields:        fields,
 		version:       version,
+		flags:         hdr[28],
 		headerLen:     headerLen,
 		recordLen:     recordLen,
+		CodePage:      codePage,
+		ldid:          ldid,
 		memoPath:      memoPath,
+		codecs:        codecs,
+		offsets:       offsets,
+		nullOff:       nullOff,
+		nullBits:      nullBits,
+		src:           open,
+		sibling:       sibling,
+		memoSrc:       memo,
 		opt:           *opts,
 	}
 	return db, nil
@@ -200,15 +329,24 @@ func (d *DBF) ReadRecords(maxCount int) ([]Record, error) {
 	if maxCount <= 0 {
 		maxCount = int(d.RecordCount - d.recordsRead)
 	}
+	d.stopScan() // o cursor de Next passa a ser o de ReadRecords
 	if d.recordsRead >= d.RecordCount || maxCount == 0 {
 		return []Record{}, nil
 	}
 
-	f, err := os.Open(d.Path)
+	f, c, err := d.src()
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	if c != nil {
+		defer c.Close()
+	}
+
+	release, err := d.attachMemo()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	start := int64(d.headerLen) + int64(d.recordsRead)*int64(d.recordLen)
 
@@ -234,145 +372,299 @@ func (d *DBF) ReadRecords(maxCount int) ([]Record, error) {
 			}
 		}
 		if rec != nil {
+			d.tagRecno(rec, d.recordsRead)
 			out = append(out, rec)
 		}
 	}
+	d.recno = d.recordsRead
 	return out, nil
 }
 
 // Reset reinicia o cursor interno para nova leitura.
-func (d *DBF) Reset() { d.recordsRead = 0 }
+func (d *DBF) Reset() {
+	d.stopScan()
+	d.recordsRead = 0
+	d.recno = 0
+	d.cur = nil
+	d.err = nil
+}
 
 // Version retorna o byte de versão do arquivo DBF.
 func (d *DBF) Version() byte { return d.version }
 
+// LanguageDriver retorna o language driver ID (byte 29 do header).
+func (d *DBF) LanguageDriver() byte { return d.ldid }
+
+// HeaderLength retorna o tamanho do header em bytes (bytes 8-9), isto é, a
+// posição do primeiro registro.
+func (d *DBF) HeaderLength() int { return int(d.headerLen) }
+
+// RecordLength retorna o tamanho de cada registro em bytes (bytes 10-11),
+// incluindo o byte de exclusão.
+func (d *DBF) RecordLength() int { return int(d.recordLen) }
+
+// FieldOffset retorna a posição do campo Fields[i] dentro do registro
+// (o byte 0 é a marca de exclusão), já descontadas colunas ocultas como
+// _NullFlags.
+func (d *DBF) FieldOffset(i int) int { return d.fieldOffset(i) }
+
+// MemoPath retorna o caminho do .DBT/.FPT associado ("" se não houver ou se
+// a tabela não foi aberta de um arquivo).
+func (d *DBF) MemoPath() string { return d.memoPath }
+
 // --------------------------- Parsing de registro ---------------------------
 
 func (d *DBF) parseRecord(b []byte) (Record, bool, error) {
+	return d.decodeRecord(b, d.opt.IncludeDeleted)
+}
+
+// decodeRecord converte o registro bruto b; com keepDeleted=false,
+// registros excluídos são descartados (skip=true).
+func (d *DBF) decodeRecord(b []byte, keepDeleted bool) (Record, bool, error) {
 	if len(b) == 0 {
 		return nil, true, nil
 	}
 	deleted := b[0] == 0x2A // '*' = deletado; ' ' = normal
-	if deleted && !d.opt.IncludeDeleted {
+	if deleted && !keepDeleted {
 		return nil, true, nil
 	}
 
 	rec := Record{}
-	offset := 1
 
-	for _, f := range d.Fields {
-		if offset+int(f.Size) > len(b) {
-			return nil, true, fmt.Errorf("registro truncado")
+	for i, f := range d.Fields {
+		v, ok, err := d.decodeCell(i, b)
+		if err != nil {
+			return nil, true, err
+		}
+		if ok {
+			rec[f.Name] = v
 		}
-		fieldBytes := b[offset : offset+int(f.Size)]
-		offset += int(f.Size)
+	}
 
-		enc := fieldEncoding(d.opt.Encoding, f.Name)
-		switch f.Type {
-		case 'C': // texto
-			val := rtrimSpaces(decodeBytes(fieldBytes, enc))
-			rec[f.Name] = val
+	if deleted {
+		rec["_deleted"] = true
+	}
+	return rec, false, nil
+}
 
-		case 'N', 'F': // número/float (ASCII)
-			s := strings.TrimSpace(decodeBytes(fieldBytes, enc))
-			if s == "" {
-				rec[f.Name] = nil
-				break
-			}
-			// aceita vírgula decimal
-			if strings.Contains(s, ",") && !strings.Contains(s, ".") {
-				s = strings.ReplaceAll(s, ",", ".")
-			}
-			fl, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				if d.opt.ReadMode == ReadStrict {
-					return nil, true, fmt.Errorf("%s: número inválido: %q", f.Name, s)
-				}
-				rec[f.Name] = nil
-			} else {
-				rec[f.Name] = fl
-			}
+// decodeCell converte o campo i do registro bruto b, respeitando o bit de
+// .NULL. em _NullFlags.
+func (d *DBF) decodeCell(i int, b []byte) (any, bool, error) {
+	off, size := d.fieldOffset(i), int(d.Fields[i].Size)
+	if off+size > len(b) {
+		return nil, false, fmt.Errorf("registro truncado")
+	}
+	if d.isNull(i, b) {
+		return nil, true, nil
+	}
+	field := b[off : off+size]
+	if isVarType(d.Fields[i].Type) && size > 0 && d.nullBits != nil && d.flag(b, d.nullBits[i].varLen) {
+		// mais curto que o campo: o tamanho real está no último byte
+		if n := int(field[size-1]); n < size {
+			field = field[:n]
+		}
+	}
+	return d.decodeField(i, field)
+}
 
-		case 'Y': // currency 64 bits int / 10000 (LE)
-			if len(fieldBytes) != 8 {
-				rec[f.Name] = nil
-				break
-			}
-			u := binary.LittleEndian.Uint64(fieldBytes)
-			i := int64(u)
-			rec[f.Name] = float64(i) / 10000.0
-
-		case 'L': // lógico
-			c := byte(' ')
-			if len(fieldBytes) > 0 {
-				c = fieldBytes[0]
-			}
-			switch c {
-			case 'T', 't', 'Y', 'y':
-				rec[f.Name] = true
-			case 'F', 'f', 'N', 'n':
-				rec[f.Name] = false
-			default:
-				rec[f.Name] = nil
-			}
+// decodeField converte o campo i a partir dos seus bytes no registro. ok
+// é false para tipos desconhecidos ignorados em ReadLoose.
+func (d *DBF) decodeField(i int, fieldBytes []byte) (v any, ok bool, err error) {
+	f := d.Fields[i]
+	enc := d.fieldCodec(i)
+	switch f.Type {
+	case 'C': // texto
+		return rtrimSpaces(decodeBytes(fieldBytes, enc)), true, nil
 
-		case 'D': // data "YYYYMMDD"
-			s := decodeBytes(fieldBytes, enc)
-			s = strings.TrimSpace(s)
-			if len(s) != 8 || strings.Contains(s, " ") || s == "00000000" {
-				rec[f.Name] = nil
-				break
-			}
-			t, err := time.Parse("20060102", s)
-			if err != nil {
-				rec[f.Name] = nil
-			} else {
-				rec[f.Name] = t
+	case 'N', 'F': // número/float (ASCII)
+		s := strings.TrimSpace(decodeBytes(fieldBytes, enc))
+		if s == "" {
+			return nil, true, nil
+		}
+		// aceita vírgula decimal
+		if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+			s = strings.ReplaceAll(s, ",", ".")
+		}
+		if d.opt.Numeric == NumericDecimal {
+			return d.decodeDecimal(f, s)
+		}
+		fl, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			if d.opt.ReadMode == ReadStrict {
+				return nil, false, fmt.Errorf("%s: número inválido: %q", f.Name, s)
 			}
+			return nil, true, nil
+		}
+		return fl, true, nil
 
-		case 'I': // int32 LE
-			if len(fieldBytes) != 4 {
-				rec[f.Name] = nil
-				break
-			}
-			rec[f.Name] = int32(binary.LittleEndian.Uint32(fieldBytes))
+	case 'Y': // currency 64 bits int / 10000 (LE)
+		if len(fieldBytes) != 8 {
+			return nil, true, nil
+		}
+		u := binary.LittleEndian.Uint64(fieldBytes)
+		if d.opt.Numeric == NumericDecimal {
+			return Decimal{Unscaled: int64(u), Scale: 4}, true, nil
+		}
+		return float64(int64(u)) / 10000.0, true, nil
 
-		case 'B': // double LE
-			if len(fieldBytes) != 8 {
-				rec[f.Name] = nil
-				break
-			}
-			bits := binary.LittleEndian.Uint64(fieldBytes)
-			rec[f.Name] = math.Float64frombits(bits)
+	case 'L': // lógico
+		c := byte(' ')
+		if len(fieldBytes) > 0 {
+			c = fieldBytes[0]
+		}
+		switch c {
+		case 'T', 't', 'Y', 'y':
+			return true, true, nil
+		case 'F', 'f', 'N', 'n':
+			return false, true, nil
+		default:
+			return nil, true, nil
+		}
 
-		case 'T': // VFP DateTime (julian int32 LE, msSinceMidnight int32 LE)
-			if len(fieldBytes) != 8 {
-				rec[f.Name] = nil
-				break
-			}
-			jd := int32(binary.LittleEndian.Uint32(fieldBytes[:4]))
-			ms := int32(binary.LittleEndian.Uint32(fieldBytes[4:8]))
-			rec[f.Name] = vfpDateTimeToUTC(int(jd), int(ms))
+	case 'D': // data "YYYYMMDD"
+		s := decodeBytes(fieldBytes, enc)
+		s = strings.TrimSpace(s)
+		if len(s) != 8 || strings.Contains(s, " ") || s == "00000000" {
+			return nil, true, nil
+		}
+		t, err := time.Parse("20060102", s)
+		if err != nil {
+			return nil, true, nil
+		}
+		return t, true, nil
+
+	case 'I': // int32 LE
+		if len(fieldBytes) != 4 {
+			return nil, true, nil
+		}
+		return int32(binary.LittleEndian.Uint32(fieldBytes)), true, nil
+
+	case 'B': // double LE
+		if len(fieldBytes) != 8 {
+			return nil, true, nil
+		}
+		bits := binary.LittleEndian.Uint64(fieldBytes)
+		return math.Float64frombits(bits), true, nil
+
+	case 'T': // VFP DateTime (julian int32 LE, msSinceMidnight int32 LE)
+		if len(fieldBytes) != 8 {
+			return nil, true, nil
+		}
+		jd := int32(binary.LittleEndian.Uint32(fieldBytes[:4]))
+		ms := int32(binary.LittleEndian.Uint32(fieldBytes[4:8]))
+		// datetime vazio: o VFP e Create gravam 8 bytes zero. Lido como dia
+		// juliano 0 viraria 24/11/-4713 e a ida e volta de um T vazio
+		// gravaria essa data; devolvemos nil, como na data D vazia.
+		if jd == 0 && ms == 0 {
+			return nil, true, nil
+		}
+		return vfpDateTimeToUTC(int(jd), int(ms)), true, nil
+
+	case 'V': // varchar (VFP 9); decodeCell já recortou o tamanho real
+		return decodeBytes(fieldBytes, enc), true, nil
 
-		case 'M':
-			// TODO: Implementar leitura de memo (DBT/FPT). Por ora:
+	case 'Q': // varbinary (VFP 9)
+		return bytes.Clone(fieldBytes), true, nil
+
+	case 'M', 'G', 'W': // memo, general e blob (ponteiro para bloco no .DBT/.FPT)
+		data, text, err := d.readMemo(f, fieldBytes)
+		if err != nil {
 			if d.opt.ReadMode == ReadStrict {
-				return nil, true, fmt.Errorf("campo memo não suportado (ainda)")
+				return nil, false, err
 			}
-			rec[f.Name] = nil
+			return nil, true, nil
+		}
+		if data == nil {
+			return nil, true, nil
+		}
+		if f.Type == 'M' && text {
+			return decodeBytes(data, enc), true, nil
+		}
+		return data, true, nil
 
-		default:
+	default:
+		if d.opt.ReadMode == ReadStrict {
+			return nil, false, fmt.Errorf("tipo de campo não suportado: %q", string(f.Type))
+		}
+		// loose -> ignora
+	}
+	return nil, false, nil
+}
+
+// decodeDecimal converte o texto s de um campo N ou F em Decimal com a
+// escala do campo (ou maior, se o texto tiver mais casas); N sem casas
+// decimais vira int64.
+func (d *DBF) decodeDecimal(f Field, s string) (any, bool, error) {
+	x, err := ParseDecimal(s)
+	if err == nil {
+		var ok bool
+		if x, ok = x.rescale(max(x.Scale, f.DecimalPlaces)); !ok {
+			err = errDecimalRange
+		}
+	}
+	if err != nil {
+		if d.opt.ReadMode == ReadStrict {
+			return nil, false, fmt.Errorf("%s: número inválido: %q", f.Name, s)
+		}
+		return nil, true, nil
+	}
+	if f.Type == 'N' && f.DecimalPlaces == 0 {
+		if n, exact := x.Int64(); exact {
+			return n, true, nil
+		}
+	}
+	return x, true, nil
+}
+
+// attachMemo abre o arquivo de memo (se houver e ainda não estiver aberto)
+// e devolve a função que libera esta referência. O handle é compartilhado
+// pelas leituras em andamento (Next, Rows, cursor) e só é fechado quando a
+// última o libera.
+func (d *DBF) attachMemo() (func(), error) {
+	if d.memoSrc == nil {
+		return func() {}, nil
+	}
+	if d.memo == nil {
+		m, err := openMemo(d.memoSrc, d.version)
+		if err != nil {
 			if d.opt.ReadMode == ReadStrict {
-				return nil, true, fmt.Errorf("tipo de campo não suportado: %q", string(f.Type))
+				return nil, fmt.Errorf("abrindo memo: %w", err)
 			}
-			// loose -> ignora
+			return func() {}, nil
 		}
+		d.memo = m
 	}
+	d.memoRefs++
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		if d.memoRefs--; d.memoRefs == 0 {
+			d.memo.close()
+			d.memo = nil
+		}
+	}, nil
+}
 
-	if deleted {
-		rec["_deleted"] = true
+// readMemo segue o ponteiro do campo; devolve nil para memo vazio.
+func (d *DBF) readMemo(f Field, fieldBytes []byte) ([]byte, bool, error) {
+	block, err := memoBlock(fieldBytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", f.Name, err)
 	}
-	return rec, false, nil
+	if block == 0 {
+		return nil, false, nil
+	}
+	if d.memo == nil {
+		return nil, false, fmt.Errorf("%s: arquivo de memo indisponível", f.Name)
+	}
+	data, text, err := d.memo.read(block)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return data, text, nil
 }
 
 // --------------------------- Utilitários ---------------------------
@@ -381,8 +673,8 @@ func normOptions(o *OpenOptions) {
 	if o.ReadMode != ReadStrict && o.ReadMode != ReadLoose {
 		o.ReadMode = ReadStrict
 	}
-	if o.Encoding.Default == "" {
-		o.Encoding.Default = "ISO-8859-1"
+	if o.Numeric != NumericDecimal {
+		o.Numeric = NumericFloat
 	}
 	if o.Encoding.PerField == nil {
 		o.Encoding.PerField = map[string]string{}
@@ -391,7 +683,7 @@ func normOptions(o *OpenOptions) {
 
 func isValidVersion(v byte) bool {
 	switch v {
-	case 0x03, 0x83, 0x8b, 0x30, 0xf5:
+	case 0x03, 0x83, 0x8b, 0x30, 0x31, 0x32, 0xf5:
 		return true
 	default:
 		return false
@@ -403,7 +695,8 @@ func validateField(f Field, version byte) error {
 		return fmt.Errorf("nome de campo inválido: %q", f.Name)
 	}
 	switch f.Type {
-	case 'C', 'N', 'F', 'Y', 'L', 'D', 'I', 'M', 'T', 'B':
+	case 'C', 'N', 'F', 'Y', 'L', 'D', 'I', 'M', 'T', 'B', 'G', 'W':
+	case 'V', 'Q', '0': // varchar, varbinary e _NullFlags (VFP 9)
 	default:
 		return fmt.Errorf("tipo não suportado: %q", string(f.Type))
 	}
@@ -429,56 +722,111 @@ func validateField(f Field, version byte) error {
 	if f.Type == 'B' && f.Size != 8 {
 		return fmt.Errorf("%s: double deve ter 8 bytes", f.Name)
 	}
+	if isVarType(f.Type) && f.Size == 0 {
+		return fmt.Errorf("%s: tamanho zero", f.Name)
+	}
 	// memo size (DBT dBaseIII=10, VFP=4)
 	memoSize := uint8(10)
-	if version == 0x30 {
+	if isVFP(version) {
 		memoSize = 4
 	}
-	if f.Type == 'M' && f.Size != memoSize {
+	if isMemoType(f.Type) && f.Size != memoSize {
 		return fmt.Errorf("%s: memo size inválido (esperado %d)", f.Name, memoSize)
 	}
 	return nil
 }
 
-func calcRecordLen(fields []Field) uint16 {
+// flagBits são as posições dos bits de um campo em _NullFlags (-1: sem
+// bit).
+type flagBits struct {
+	null   int // valor .NULL.
+	varLen int // V/Q mais curto que o campo: tamanho no último byte
+}
+
+// assignFlagBits numera os bits de _NullFlags (nullSize bytes) na ordem
+// dos campos: primeiro o de tamanho variável (V e Q), depois o de .NULL.
+// (campos anuláveis). Devolve nil se a tabela não tem _NullFlags.
+func assignFlagBits(fields []Field, nullSize int) ([]flagBits, error) {
+	if nullSize == 0 {
+		return nil, nil
+	}
+	bits := make([]flagBits, len(fields))
+	next := 0
+	take := func() int {
+		n := next
+		next++
+		if n >= nullSize*8 {
+			return -1
+		}
+		return n
+	}
+	for i, f := range fields {
+		bits[i] = flagBits{null: -1, varLen: -1}
+		if isVarType(f.Type) {
+			bits[i].varLen = take()
+		}
+		if f.Flags&FieldNullable != 0 {
+			bits[i].null = take()
+		}
+	}
+	if next > nullSize*8 {
+		return bits, fmt.Errorf("_NullFlags com %d bytes não comporta %d bits", nullSize, next)
+	}
+	return bits, nil
+}
+
+// isVarType indica os tipos de tamanho variável do VFP 9.
+func isVarType(t byte) bool { return t == 'V' || t == 'Q' }
+
+// flag informa se o bit de _NullFlags está ligado no registro b.
+func (d *DBF) flag(b []byte, bit int) bool {
+	if bit < 0 {
+		return false
+	}
+	pos := d.nullOff + bit/8
+	return pos < len(b) && b[pos]&(1<<(bit%8)) != 0
+}
+
+// isNull informa se o bit de .NULL. do campo i está ligado no registro b.
+func (d *DBF) isNull(i int, b []byte) bool {
+	return d.nullBits != nil && d.flag(b, d.nullBits[i].null)
+}
+
+func calcRecordLen(fields []Field) int {
 	sum := 1 // flag de deletado
 	for _, f := range fields {
 		sum += int(f.Size)
 	}
-	return uint16(sum)
+	return sum
 }
 
 func rtrimSpaces(s string) string {
 	return strings.TrimRight(s, " ")
 }
 
-func fieldEncoding(enc Encoding, field string) string {
-	if v, ok := enc.PerField[field]; ok && v != "" {
-		return v
+// fieldCodec devolve o encoding do campo i.
+func (d *DBF) fieldCodec(i int) encoding.Encoding {
+	if i < len(d.codecs) {
+		return d.codecs[i]
 	}
-	return enc.Default
+	c, err := d.opt.Encoding.codec(d.Fields[i].Name)
+	if err != nil {
+		return charmap.ISO8859_1
+	}
+	return c
 }
 
-func decodeBytes(data []byte, enc string) string {
-	enc = strings.ToUpper(strings.TrimSpace(enc))
-	var tr *transform.Reader
-	switch enc {
-	case "CP850":
-		tr = transform.NewReader(bytes.NewReader(data), charmap.CodePage850.NewDecoder())
-	case "CP437":
-		tr = transform.NewReader(bytes.NewReader(data), charmap.CodePage437.NewDecoder())
-	case "CP1252", "WINDOWS-1252":
-		tr = transform.NewReader(bytes.NewReader(data), charmap.Windows1252.NewDecoder())
-	case "ISO-8859-1", "ISO8859-1", "LATIN1":
-		tr = transform.NewReader(bytes.NewReader(data), charmap.ISO8859_1.NewDecoder())
-	case "UTF-8", "UTF8":
-		return string(data)
-	default:
-		// fallback: ISO-8859-1
-		tr = transform.NewReader(bytes.NewReader(data), charmap.ISO8859_1.NewDecoder())
+// resolveCodec resolve o encoding do campo; nomes desconhecidos são erro
+// em modo strict e caem para ISO-8859-1 em modo loose.
+func (o *OpenOptions) resolveCodec(field string) (encoding.Encoding, error) {
+	c, err := o.Encoding.codec(field)
+	if err != nil {
+		if o.ReadMode == ReadStrict {
+			return nil, err
+		}
+		return charmap.ISO8859_1, nil
 	}
-	out, _ := io.ReadAll(tr)
-	return string(out)
+	return c, nil
 }
 
 // ---------------- VFP DateTime (juliano <-> UTC) ----------------
@@ -5,6 +5,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -305,9 +306,235 @@ func TestParseRecordTimeFieldAtEndOfDay(t *testing.T) {
 	}
 }
 
+func TestParseRecordEmptyTimeField(t *testing.T) {
+	d := &DBF{Fields: []Field{{Name: "DT", Type: 'T', Size: 8}}}
+	record := make([]byte, 1+8)
+	record[0] = ' '
+
+	rec, _, err := d.parseRecord(record)
+	if err != nil {
+		t.Fatalf("parseRecord returned error: %v", err)
+	}
+	if v, ok := rec["DT"]; !ok || v != nil {
+		t.Fatalf("empty DT = %#v, want nil", v)
+	}
+}
+
 func julianDay(year int, month time.Month, day int) int {
 	a := (14 - int(month)) / 12
 	y := year + 4800 - a
 	m := int(month) + 12*a - 3
 	return day + (153*m+2)/5 + 365*y + y/4 - y/100 + y/400 - 32045
 }
+
+// vfpFixture monta uma tabela Visual FoxPro (0x30) com os descritores
+// fields, incluindo colunas ocultas como _NullFlags, e os registros rows já
+// codificados (com o byte de exclusão).
+func vfpFixture(t *testing.T, fields []Field, rows ...[]byte) string {
+	t.Helper()
+	recLen := 1
+	for _, f := range fields {
+		recLen += int(f.Size)
+	}
+	headerLen := 32 + 32*len(fields) + 1 + 263 // 263: backlink do .DBC
+	buf := make([]byte, headerLen)
+	buf[0] = 0x30
+	buf[1], buf[2], buf[3] = 124, 1, 2
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(rows)))
+	binary.LittleEndian.PutUint16(buf[8:10], uint16(headerLen))
+	binary.LittleEndian.PutUint16(buf[10:12], uint16(recLen))
+	buf[29] = 0x03 // CP1252
+	off := 1
+	for i, f := range fields {
+		des := buf[32+32*i : 64+32*i]
+		copy(des[0:11], f.Name)
+		des[11] = f.Type
+		binary.LittleEndian.PutUint32(des[12:16], uint32(off))
+		des[16], des[17], des[18] = f.Size, f.DecimalPlaces, f.Flags
+		off += int(f.Size)
+	}
+	buf[32+32*len(fields)] = 0x0D
+	for _, r := range rows {
+		if len(r) != recLen {
+			t.Fatalf("fixture row has %d bytes, want %d", len(r), recLen)
+		}
+		buf = append(buf, r...)
+	}
+	buf = append(buf, 0x1A)
+	path := filepath.Join(t.TempDir(), "vfp.dbf")
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestReadRecordsVFPNullFlags(t *testing.T) {
+	fields := []Field{
+		{Name: "NOME", Type: 'C', Size: 6, Flags: FieldNullable},
+		{Name: "QTD", Type: 'I', Size: 4, Flags: FieldNullable},
+		{Name: "VALOR", Type: 'N', Size: 6, DecimalPlaces: 2},
+		{Name: "_NullFlags", Type: '0', Size: 1, Flags: FieldSystem | FieldBinary},
+	}
+	path := vfpFixture(t, fields,
+		[]byte(" Ana   \x05\x00\x00\x00 12.50\x00"),
+		[]byte("       \x00\x00\x00\x00  0.00\x03"),
+	)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if len(db.Fields) != 3 || db.Fields[0].Flags != FieldNullable || db.Fields[2].Flags != 0 {
+		t.Fatalf("Fields = %+v", db.Fields)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if recs[0]["NOME"] != "Ana" || recs[0]["QTD"] != int32(5) || recs[0]["VALOR"] != 12.5 {
+		t.Fatalf("record 1 = %v", recs[0])
+	}
+	if v, ok := recs[1]["NOME"]; !ok || v != nil {
+		t.Fatalf("record 2 NOME = %#v, want nil", v)
+	}
+	if recs[1]["QTD"] != nil || recs[1]["VALOR"] != 0.0 {
+		t.Fatalf("record 2 = %v", recs[1])
+	}
+	if _, ok := recs[0]["_NullFlags"]; ok {
+		t.Fatalf("_NullFlags should be hidden: %v", recs[0])
+	}
+
+	for _, c := range []struct {
+		p    Predicate
+		want int
+	}{
+		{Col("QTD").Eq(0), 0},
+		{Col("QTD").IsEmpty(), 1},
+		{Col("NOME").IsEmpty(), 1},
+		{Col("VALOR").Eq(0), 1},
+		{Cond("ISNULL(QTD)"), 1},
+	} {
+		if n, err := db.Query().Where(c.p).Count(); err != nil || n != c.want {
+			t.Fatalf("Count = %d, %v; want %d", n, err, c.want)
+		}
+	}
+}
+
+func TestUpdateVFPNullFlags(t *testing.T) {
+	fields := []Field{
+		{Name: "NOME", Type: 'C', Size: 6, Flags: FieldNullable},
+		{Name: "QTD", Type: 'I', Size: 4, Flags: FieldNullable},
+		{Name: "_NullFlags", Type: '0', Size: 1, Flags: FieldSystem},
+	}
+	path := vfpFixture(t, fields, []byte(" Ana   \x05\x00\x00\x00\x00"))
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.UpdateRecord(0, Record{"QTD": nil}); err != nil {
+		t.Fatalf("UpdateRecord returned error: %v", err)
+	}
+	if err := db.Append(Record{"NOME": "Bia", "QTD": nil}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	// campo ausente fica vazio, não .NULL.
+	if err := db.Append(Record{"NOME": "Caio"}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if flags := raw[len(raw)-14]; flags != 0x02 {
+		t.Fatalf("appended _NullFlags = %#x, want 0x02", flags)
+	}
+	if flags := raw[len(raw)-2]; flags != 0x00 {
+		t.Fatalf("appended _NullFlags = %#x, want 0x00", flags)
+	}
+	db, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if recs[0]["NOME"] != "Ana" || recs[0]["QTD"] != nil || recs[1]["NOME"] != "Bia" || recs[1]["QTD"] != nil ||
+		recs[2]["NOME"] != "Caio" || recs[2]["QTD"] != int32(0) {
+		t.Fatalf("records = %v", recs)
+	}
+}
+
+func TestReadRecordsVFPVarchar(t *testing.T) {
+	fields := []Field{
+		{Name: "NOME", Type: 'V', Size: 8, Flags: FieldNullable},
+		{Name: "DADOS", Type: 'Q', Size: 4, Flags: FieldBinary},
+		{Name: "OBS", Type: 'C', Size: 4, Flags: FieldBinary},
+		{Name: "COD", Type: 'V', Size: 3},
+		{Name: "_NullFlags", Type: '0', Size: 1, Flags: FieldSystem | FieldBinary},
+	}
+	// bits: NOME tamanho=0 e null=1, DADOS tamanho=2, COD tamanho=3
+	path := vfpFixture(t, fields,
+		[]byte(" Ana\x00\x00\x00\x00\x03"+"\x01\x02\x00\x02"+"\xe9   "+"XYZ"+"\x05"),
+		[]byte(" \x00\x00\x00\x00\x00\x00\x00\x00"+"\xff\xfe\xfd\xfc"+"ab  "+"\xe9\x00\x01"+"\x0a"),
+	)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	raw[0] = 0x32 // VFP 9 com Varchar/Varbinary
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	want := []Record{
+		{"NOME": "Ana", "DADOS": []byte{1, 2}, "OBS": "\xe9", "COD": "XYZ"},
+		{"NOME": nil, "DADOS": []byte{0xff, 0xfe, 0xfd, 0xfc}, "OBS": "ab", "COD": "é"},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		t.Fatalf("records = %#v, want %#v", recs, want)
+	}
+
+	if n, err := db.Query().Where(Cond("COD == 'XYZ' .OR. ISNULL(NOME)")).Count(); err != nil || n != 2 {
+		t.Fatalf("Count = %d, %v; want 2", n, err)
+	}
+	if _, err := db.Query().Where(Col("NOME").Eq("Ana")).Count(); err == nil {
+		t.Fatalf("raw predicate on V field returned nil error")
+	}
+}
+
+func TestReadRecordsVFPVarcharZeroSize(t *testing.T) {
+	fields := []Field{
+		{Name: "NOME", Type: 'V', Size: 0},
+		{Name: "COD", Type: 'C', Size: 2},
+		{Name: "_NullFlags", Type: '0', Size: 1, Flags: FieldSystem | FieldBinary},
+	}
+	path := vfpFixture(t, fields, []byte(" AB\x01"))
+
+	if _, err := Open(path, nil); err == nil {
+		t.Fatalf("Open in strict mode should reject a V field with size 0")
+	}
+	db, err := Open(path, &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open in loose mode returned error: %v", err)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(recs) != 1 || recs[0]["NOME"] != "" || recs[0]["COD"] != "AB" {
+		t.Fatalf("records = %#v", recs)
+	}
+}
@@ -0,0 +1,225 @@
+package dbfmini
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Decimal ---------------------------
+
+// Decimal é um número decimal exato, Unscaled × 10^-Scale. Com
+// OpenOptions.Numeric = NumericDecimal, campos N e F são devolvidos como
+// Decimal com Scale = DecimalPlaces, e campos Y com Scale = 4.
+//
+// Valores iguais com escalas diferentes (1.5 e 1.50) não são iguais por ==;
+// compare com Rat.
+type Decimal struct {
+	Unscaled int64
+	Scale    uint8
+}
+
+var errDecimalRange = errors.New("decimal fora da faixa")
+
+// pow10 contém as potências de 10 que cabem em int64.
+var pow10 = [...]int64{
+	1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18,
+}
+
+// ParseDecimal interpreta s ("-12.50", "+3", ".5", "1.5E3"). A escala é o
+// número de dígitos após o ponto, ajustado pelo expoente.
+func ParseDecimal(s string) (Decimal, error) {
+	in := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	var u uint64
+	scale, digits, exp := 0, 0, 0
+	dot := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			if u > (math.MaxUint64-9)/10 {
+				return Decimal{}, fmt.Errorf("%q: %w", in, errDecimalRange)
+			}
+			u = u*10 + uint64(c-'0')
+			digits++
+			if dot {
+				scale++
+			}
+		case c == '.' && !dot:
+			dot = true
+		case (c == 'e' || c == 'E') && digits > 0:
+			e, err := strconv.Atoi(s[i+1:])
+			if err != nil {
+				return Decimal{}, fmt.Errorf("decimal inválido: %q", in)
+			}
+			exp = e
+			i = len(s)
+		default:
+			return Decimal{}, fmt.Errorf("decimal inválido: %q", in)
+		}
+	}
+	if digits == 0 {
+		return Decimal{}, fmt.Errorf("decimal inválido: %q", in)
+	}
+	scale -= exp
+	for ; scale < 0; scale++ {
+		if u > math.MaxUint64/10 {
+			return Decimal{}, fmt.Errorf("%q: %w", in, errDecimalRange)
+		}
+		u *= 10
+	}
+	if scale > math.MaxUint8 || u > math.MaxInt64+1 || u == math.MaxInt64+1 && !neg {
+		return Decimal{}, fmt.Errorf("%q: %w", in, errDecimalRange)
+	}
+	d := Decimal{Unscaled: int64(u), Scale: uint8(scale)}
+	if neg {
+		d.Unscaled = -d.Unscaled
+	}
+	return d, nil
+}
+
+// String devolve o valor com exatamente Scale casas decimais.
+func (d Decimal) String() string {
+	u := uint64(d.Unscaled)
+	if d.Unscaled < 0 {
+		u = -u
+	}
+	s := strconv.FormatUint(u, 10)
+	if n := int(d.Scale); n > 0 {
+		if len(s) <= n {
+			s = strings.Repeat("0", n-len(s)+1) + s
+		}
+		s = s[:len(s)-n] + "." + s[len(s)-n:]
+	}
+	if d.Unscaled < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Int64 devolve a parte inteira (truncada em direção a zero); exact é
+// false se havia parte fracionária.
+func (d Decimal) Int64() (n int64, exact bool) {
+	if int(d.Scale) >= len(pow10) {
+		return 0, d.Unscaled == 0
+	}
+	p := pow10[d.Scale]
+	return d.Unscaled / p, d.Unscaled%p == 0
+}
+
+// Rat devolve o valor como big.Rat, sem perda.
+func (d Decimal) Rat() *big.Rat {
+	den := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Scale)), nil)
+	return new(big.Rat).SetFrac(big.NewInt(d.Unscaled), den)
+}
+
+// Float64 devolve o float64 mais próximo do valor.
+func (d Decimal) Float64() float64 {
+	x, _ := strconv.ParseFloat(d.String(), 64)
+	return x
+}
+
+// rescale muda a escala para scale. Ao reduzir, arredonda a metade para
+// longe do zero; ok é false se o resultado não cabe em int64.
+func (d Decimal) rescale(scale uint8) (Decimal, bool) {
+	for d.Scale < scale {
+		if d.Unscaled > math.MaxInt64/10 || d.Unscaled < math.MinInt64/10 {
+			return d, false
+		}
+		d.Unscaled *= 10
+		d.Scale++
+	}
+	if d.Scale > scale {
+		drop := d.Scale - scale
+		if int(drop) >= len(pow10) {
+			return Decimal{Scale: scale}, true
+		}
+		p := pow10[drop]
+		q, r := d.Unscaled/p, d.Unscaled%p
+		switch {
+		case r >= (p+1)/2:
+			q++
+		case r <= -(p+1)/2:
+			q--
+		}
+		d = Decimal{Unscaled: q, Scale: scale}
+	}
+	return d, true
+}
+
+// MarshalJSON grava o valor como número JSON, sem passar por float64.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON aceita números e textos JSON; null não altera d.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if uq, err := strconv.Unquote(s); err == nil {
+		s = uq
+	}
+	x, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = x
+	return nil
+}
+
+// Scan implementa sql.Scanner. Aceita Decimal, inteiros, float64, string e
+// []byte; nil zera d.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case Decimal:
+		*d = v
+		return nil
+	case int64:
+		*d = Decimal{Unscaled: v}
+		return nil
+	case int32:
+		*d = Decimal{Unscaled: int64(v)}
+		return nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("decimal inválido: %v", v)
+		}
+		x, err := ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+		*d = x
+		return nil
+	case string:
+		x, err := ParseDecimal(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+		*d = x
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	}
+	return fmt.Errorf("não é possível converter %T para Decimal", src)
+}
+
+// Value implementa driver.Valuer; o valor vai como texto para não perder
+// precisão.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
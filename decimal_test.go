@@ -0,0 +1,187 @@
+package dbfmini
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Decimal
+		str  string
+	}{
+		{"12.50", Decimal{1250, 2}, "12.50"},
+		{"-0.05", Decimal{-5, 2}, "-0.05"},
+		{"+3", Decimal{3, 0}, "3"},
+		{".5", Decimal{5, 1}, "0.5"},
+		{"1.5E3", Decimal{1500, 0}, "1500"},
+		{"25e-3", Decimal{25, 3}, "0.025"},
+		{"-9223372036854775808", Decimal{-9223372036854775808, 0}, "-9223372036854775808"},
+	}
+	for _, c := range cases {
+		got, err := ParseDecimal(c.in)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q) returned error: %v", c.in, err)
+		}
+		if got != c.want || got.String() != c.str {
+			t.Fatalf("ParseDecimal(%q) = %+v (%s), want %+v (%s)", c.in, got, got, c.want, c.str)
+		}
+	}
+	for _, in := range []string{"", "-", ".", "1.2.3", "1,5", "abc", "1e", "9223372036854775808"} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Fatalf("ParseDecimal(%q) returned nil error", in)
+		}
+	}
+}
+
+func TestDecimalConversions(t *testing.T) {
+	d := Decimal{Unscaled: -123456, Scale: 3}
+	if n, exact := d.Int64(); n != -123 || exact {
+		t.Fatalf("Int64 = %d, %v", n, exact)
+	}
+	if n, exact := (Decimal{Unscaled: 4200, Scale: 2}).Int64(); n != 42 || !exact {
+		t.Fatalf("Int64 = %d, %v", n, exact)
+	}
+	if r := d.Rat(); r.Cmp(big.NewRat(-123456, 1000)) != 0 {
+		t.Fatalf("Rat = %v", r)
+	}
+	if x := (Decimal{Unscaled: 1, Scale: 1}).Float64(); x != 0.1 {
+		t.Fatalf("Float64 = %v", x)
+	}
+	for _, c := range []struct {
+		in    Decimal
+		scale uint8
+		want  Decimal
+	}{
+		{Decimal{15, 1}, 3, Decimal{1500, 3}},
+		{Decimal{125, 2}, 1, Decimal{13, 1}},
+		{Decimal{-125, 2}, 1, Decimal{-13, 1}},
+		{Decimal{124, 2}, 1, Decimal{12, 1}},
+	} {
+		if got, ok := c.in.rescale(c.scale); !ok || got != c.want {
+			t.Fatalf("rescale(%v, %d) = %+v, %v", c.in, c.scale, got, ok)
+		}
+	}
+	if _, ok := (Decimal{Unscaled: 1 << 62}).rescale(2); ok {
+		t.Fatalf("rescale overflow returned ok")
+	}
+}
+
+func TestDecimalJSONAndSQL(t *testing.T) {
+	b, err := json.Marshal(map[string]Decimal{"v": {Unscaled: 10, Scale: 2}})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	if string(b) != `{"v":0.10}` {
+		t.Fatalf("Marshal = %s", b)
+	}
+	var got struct{ A, B Decimal }
+	if err := json.Unmarshal([]byte(`{"A":1234.5678,"B":"-7.0"}`), &got); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if got.A != (Decimal{12345678, 4}) || got.B != (Decimal{-70, 1}) {
+		t.Fatalf("Unmarshal = %+v", got)
+	}
+
+	var _ driver.Valuer = Decimal{}
+	if v, _ := (Decimal{Unscaled: 5, Scale: 3}).Value(); v != "0.005" {
+		t.Fatalf("Value = %v", v)
+	}
+	for _, c := range []struct {
+		src  any
+		want Decimal
+	}{
+		{"12.30", Decimal{1230, 2}},
+		{[]byte("-1"), Decimal{-1, 0}},
+		{int64(7), Decimal{7, 0}},
+		{0.25, Decimal{25, 2}},
+		{nil, Decimal{}},
+	} {
+		d := Decimal{Unscaled: 99}
+		if err := d.Scan(c.src); err != nil || d != c.want {
+			t.Fatalf("Scan(%v) = %+v, %v", c.src, d, err)
+		}
+	}
+	var d Decimal
+	if err := d.Scan(true); err == nil {
+		t.Fatalf("Scan(bool) returned nil error")
+	}
+}
+
+func TestOpenNumericDecimal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saldos.dbf")
+	fields := []Field{
+		{Name: "SALDO", Type: 'N', Size: 20, DecimalPlaces: 2},
+		{Name: "QTD", Type: 'N', Size: 19},
+		{Name: "TAXA", Type: 'F', Size: 10, DecimalPlaces: 4},
+		{Name: "CURR", Type: 'Y'},
+	}
+	w, err := Create(path, fields, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	recs := []Record{
+		{"SALDO": Decimal{Unscaled: 1234567890123456789, Scale: 2}, "QTD": int64(9007199254740993),
+			"TAXA": Decimal{Unscaled: 1, Scale: 1}, "CURR": Decimal{Unscaled: 922337203685477, Scale: 2}},
+		{"SALDO": 0.1, "QTD": int32(-3)},
+	}
+	for _, r := range recs {
+		if err := w.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, err := Open(path, &OpenOptions{Numeric: NumericDecimal})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+	got, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	want := []Record{
+		{"SALDO": Decimal{1234567890123456789, 2}, "QTD": int64(9007199254740993),
+			"TAXA": Decimal{1000, 4}, "CURR": Decimal{92233720368547700, 4}},
+		{"SALDO": Decimal{10, 2}, "QTD": int64(-3), "TAXA": nil, "CURR": Decimal{0, 4}},
+	}
+	for i := range want {
+		for k, v := range want[i] {
+			if got[i][k] != v {
+				t.Fatalf("record %d %s = %#v, want %#v", i+1, k, got[i][k], v)
+			}
+		}
+	}
+
+	n, err := db.Query().Where(Cond("SALDO > 1000 .AND. QTD > 0")).Count()
+	if err != nil || n != 1 {
+		t.Fatalf("Count with decimal values = %d, %v", n, err)
+	}
+	type row struct {
+		Saldo float64 `dbf:"SALDO"`
+		Qtd   int64   `dbf:"QTD"`
+		Taxa  Decimal `dbf:"TAXA"`
+	}
+	var rows []row
+	db.Reset()
+	for {
+		var r row
+		if err := db.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		rows = append(rows, r)
+	}
+	if rows[1].Saldo != 0.1 || rows[1].Qtd != -3 || rows[0].Taxa != (Decimal{1000, 4}) {
+		t.Fatalf("Decode = %+v", rows)
+	}
+}
@@ -0,0 +1,391 @@
+package dbfmini
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------- Decodificação em structs ---------------------------
+
+// Decode lê o próximo registro (como Next) e o copia para dst, um ponteiro
+// para struct. Os campos da struct são associados às colunas pela tag
+// `dbf:"NOME"`; sem tag, pelo nome do campo em maiúsculas, se a coluna
+// existir. `dbf:"-"` ignora o campo. Devolve io.EOF no fim da tabela.
+func (d *DBF) Decode(dst any) error {
+	if !d.Next() {
+		if err := d.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	return d.DecodeRecord(d.Record(), dst)
+}
+
+// ReadInto lê todos os registros restantes e os acrescenta a dst, que deve
+// ser um ponteiro para slice de struct (ou de ponteiro para struct).
+func (d *DBF) ReadInto(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ReadInto: esperado ponteiro para slice, recebido %T", dst)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Pointer {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("ReadInto: elemento deve ser struct, recebido %s", elemType)
+	}
+	plan, err := d.decodePlanFor(structType)
+	if err != nil {
+		return err
+	}
+
+	for d.Next() {
+		item := reflect.New(structType)
+		if err := plan.apply(d.Record(), item.Elem()); err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Pointer {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	rv.Elem().Set(slice)
+	return d.Err()
+}
+
+// DecodeRecord copia rec (lido desta tabela) para dst, um ponteiro para
+// struct, com as mesmas regras de Decode.
+func (d *DBF) DecodeRecord(rec Record, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Decode: esperado ponteiro para struct, recebido %T", dst)
+	}
+	plan, err := d.decodePlanFor(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	return plan.apply(rec, rv.Elem())
+}
+
+// decodePlan associa colunas da tabela a campos de uma struct.
+type decodePlan struct {
+	steps []decodeStep
+}
+
+type decodeStep struct {
+	column string
+	goName string
+	index  []int
+	set    setter
+}
+
+type setter func(dst reflect.Value, v any) error
+
+func (p *decodePlan) apply(rec Record, dst reflect.Value) error {
+	for _, s := range p.steps {
+		fv, err := fieldByIndexAlloc(dst, s.index)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.goName, err)
+		}
+		if err := s.set(fv, rec[s.column]); err != nil {
+			return fmt.Errorf("campo %s (%s): %w", s.column, s.goName, err)
+		}
+	}
+	return nil
+}
+
+func (d *DBF) decodePlanFor(t reflect.Type) (*decodePlan, error) {
+	if p, ok := d.plans[t]; ok {
+		return p, nil
+	}
+	plan := &decodePlan{}
+	for _, sf := range structFieldsOf(t) {
+		i := fieldIndex(d.Fields, sf.column)
+		if i < 0 {
+			if sf.tagged {
+				return nil, fmt.Errorf("campo %s (%s.%s) não existe na tabela", sf.column, t.Name(), sf.goName)
+			}
+			continue
+		}
+		plan.steps = append(plan.steps, decodeStep{
+			column: sf.column,
+			goName: t.Name() + "." + sf.goName,
+			index:  sf.index,
+			set:    sf.set,
+		})
+	}
+	if d.plans == nil {
+		d.plans = map[reflect.Type]*decodePlan{}
+	}
+	d.plans[t] = plan
+	return plan, nil
+}
+
+// --------------------------- Reflexão (cache por tipo) ---------------------------
+
+type structField struct {
+	column string
+	goName string
+	tagged bool
+	index  []int
+	set    setter
+}
+
+var structFieldCache sync.Map // reflect.Type -> []structField
+
+func structFieldsOf(t reflect.Type) []structField {
+	if v, ok := structFieldCache.Load(t); ok {
+		return v.([]structField)
+	}
+	fields := collectStructFields(t, nil)
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+func collectStructFields(t reflect.Type, parent []int) []structField {
+	var out []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("dbf")
+		if tag == "-" {
+			continue
+		}
+		index := append(append([]int(nil), parent...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && !tagged && ft.Kind() == reflect.Struct && ft != timeType {
+			out = append(out, collectStructFields(ft, index)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name := strings.TrimSpace(tag)
+		if name == "" {
+			name = strings.ToUpper(sf.Name)
+		}
+		out = append(out, structField{
+			column: name,
+			goName: sf.Name,
+			tagged: tagged && tag != "",
+			index:  index,
+			set:    setterFor(sf.Type),
+		})
+	}
+	return out
+}
+
+// fieldByIndexAlloc é reflect.Value.FieldByIndex alocando structs
+// embutidas por ponteiro.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, errors.New("struct embutida não exportada")
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// setterFor monta, uma única vez por tipo, a conversão do valor do
+// registro para o tipo Go de destino.
+func setterFor(t reflect.Type) setter {
+	if reflect.PointerTo(t).Implements(scannerType) {
+		return func(dst reflect.Value, v any) error {
+			return dst.Addr().Interface().(sql.Scanner).Scan(driverValue(v))
+		}
+	}
+	if t == timeType {
+		return func(dst reflect.Value, v any) error {
+			switch x := v.(type) {
+			case nil:
+				dst.Set(reflect.Zero(t))
+			case time.Time:
+				dst.Set(reflect.ValueOf(x))
+			default:
+				return incompatible(v, t)
+			}
+			return nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := setterFor(t.Elem())
+		return func(dst reflect.Value, v any) error {
+			if v == nil {
+				dst.Set(reflect.Zero(t))
+				return nil
+			}
+			p := reflect.New(t.Elem())
+			if err := elem(p.Elem(), v); err != nil {
+				return err
+			}
+			dst.Set(p)
+			return nil
+		}
+
+	case reflect.Interface:
+		return func(dst reflect.Value, v any) error {
+			if v == nil {
+				dst.Set(reflect.Zero(t))
+				return nil
+			}
+			rv := reflect.ValueOf(v)
+			if !rv.Type().AssignableTo(t) {
+				return incompatible(v, t)
+			}
+			dst.Set(rv)
+			return nil
+		}
+
+	case reflect.String:
+		return func(dst reflect.Value, v any) error {
+			switch x := v.(type) {
+			case nil:
+				dst.SetString("")
+			case string:
+				dst.SetString(x)
+			case []byte:
+				dst.SetString(string(x))
+			default:
+				return incompatible(v, t)
+			}
+			return nil
+		}
+
+	case reflect.Bool:
+		return func(dst reflect.Value, v any) error {
+			switch x := v.(type) {
+			case nil:
+				dst.SetBool(false)
+			case bool:
+				dst.SetBool(x)
+			default:
+				return incompatible(v, t)
+			}
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(dst reflect.Value, v any) error {
+			if v == nil {
+				dst.SetInt(0)
+				return nil
+			}
+			n, err := toInt(v)
+			if err != nil {
+				return incompatible(v, t)
+			}
+			if dst.OverflowInt(n) {
+				return fmt.Errorf("valor %d não cabe em %s", n, t)
+			}
+			dst.SetInt(n)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(dst reflect.Value, v any) error {
+			if v == nil {
+				dst.SetUint(0)
+				return nil
+			}
+			n, err := toInt(v)
+			if err != nil {
+				return incompatible(v, t)
+			}
+			if n < 0 || dst.OverflowUint(uint64(n)) {
+				return fmt.Errorf("valor %d não cabe em %s", n, t)
+			}
+			dst.SetUint(uint64(n))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		return func(dst reflect.Value, v any) error {
+			if v == nil {
+				dst.SetFloat(0)
+				return nil
+			}
+			x, err := toFloat(v)
+			if err != nil {
+				return incompatible(v, t)
+			}
+			if t.Kind() == reflect.Float32 && math.Abs(x) > math.MaxFloat32 {
+				return fmt.Errorf("valor %v não cabe em %s", x, t)
+			}
+			dst.SetFloat(x)
+			return nil
+		}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return func(dst reflect.Value, v any) error {
+				switch x := v.(type) {
+				case nil:
+					dst.Set(reflect.Zero(t))
+				case []byte:
+					dst.SetBytes(append([]byte(nil), x...))
+				case string:
+					dst.SetBytes([]byte(x))
+				default:
+					return incompatible(v, t)
+				}
+				return nil
+			}
+		}
+	}
+
+	return func(dst reflect.Value, v any) error {
+		if v == nil {
+			dst.Set(reflect.Zero(t))
+			return nil
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Type().AssignableTo(t) {
+			dst.Set(rv)
+			return nil
+		}
+		return incompatible(v, t)
+	}
+}
+
+// driverValue ajusta os tipos do registro aos aceitos por sql.Scanner.
+func driverValue(v any) any {
+	switch x := v.(type) {
+	case int32:
+		return int64(x)
+	case Decimal:
+		return x.String()
+	}
+	return v
+}
+
+func incompatible(v any, t reflect.Type) error {
+	return fmt.Errorf("não é possível converter %T para %s", v, t)
+}
@@ -0,0 +1,121 @@
+package dbfmini
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type customer struct {
+	Name    string     `dbf:"NAME"`
+	Age     int        `dbf:"AGE"`
+	Balance *float64   `dbf:"BALANCE"`
+	Active  bool       `dbf:"ACTIVE"`
+	Birth   *time.Time `dbf:"BIRTH"`
+	Count   int64
+	Stamp   sql.NullTime `dbf:"STAMP"`
+	Ignored string       `dbf:"-"`
+}
+
+func TestReadIntoStructs(t *testing.T) {
+	path := writeAllFieldsFixture(t)
+	db, err := Open(path, &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	var out []customer
+	if err := db.ReadInto(&out); err != nil {
+		t.Fatalf("ReadInto returned error: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("len(out) = %d, want 2", len(out))
+	}
+
+	c := out[0]
+	if c.Name != "José" || c.Age != 42 || !c.Active || c.Count != 123456 {
+		t.Fatalf("customer 0 = %+v", c)
+	}
+	if c.Balance == nil || *c.Balance != 123.45 {
+		t.Fatalf("Balance = %v, want 123.45", c.Balance)
+	}
+	if c.Birth == nil || !c.Birth.Equal(time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Birth = %v, want 1990-01-01", c.Birth)
+	}
+	if !c.Stamp.Valid || c.Stamp.Time.Year() != 2023 {
+		t.Fatalf("Stamp = %+v, want valid 2023 timestamp", c.Stamp)
+	}
+
+	if out[1].Balance != nil || out[1].Age != 0 {
+		t.Fatalf("customer 1 nullable fields = %v/%d, want nil/0", out[1].Balance, out[1].Age)
+	}
+}
+
+func TestDecodeIteratesUntilEOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path, Record{"NAME": "Ana", "AGE": 30}, Record{"NAME": "Bruno", "AGE": 41})
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	type person struct {
+		Name string
+		Age  uint8
+	}
+	var names []string
+	for {
+		var p person
+		err := db.Decode(&p)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "Ana,Bruno" {
+		t.Fatalf("names = %v, want [Ana Bruno]", names)
+	}
+}
+
+func TestDecodeReportsSchemaErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path, Record{"NAME": "Ana", "AGE": 30})
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	var missing struct {
+		Email string `dbf:"EMAIL"`
+	}
+	if err := db.DecodeRecord(Record{}, &missing); err == nil || !strings.Contains(err.Error(), "EMAIL") {
+		t.Fatalf("missing field error = %v, want mention of EMAIL", err)
+	}
+
+	var wrong struct {
+		Name time.Time `dbf:"NAME"`
+	}
+	err = db.DecodeRecord(Record{"NAME": "Ana"}, &wrong)
+	if err == nil || !strings.Contains(err.Error(), "NAME") {
+		t.Fatalf("incompatible field error = %v, want mention of NAME", err)
+	}
+
+	var small struct {
+		Age int8 `dbf:"AGE"`
+	}
+	if err := db.DecodeRecord(Record{"AGE": 300.0}, &small); err == nil {
+		t.Fatalf("overflow should fail")
+	}
+
+	if err := db.ReadInto(&[]int{}); err == nil {
+		t.Fatalf("ReadInto with non-struct slice should fail")
+	}
+}
@@ -0,0 +1,507 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --------------------------- Avaliação ---------------------------
+
+// datetime distingue, durante a avaliação, valores do tipo T dos do tipo
+// D: somar 1 a uma data avança um dia; a uma data e hora, um segundo.
+type datetime struct{ time.Time }
+
+// ErrDivByZero indica divisão (ou MOD) por zero.
+var ErrDivByZero = errors.New("divisão por zero")
+
+func (n *node) eval(rec map[string]any) (any, error) {
+	v, err := n.value(rec)
+	if dt, ok := v.(datetime); ok {
+		return dt.Time, err
+	}
+	return v, err
+}
+
+// value avalia n mantendo a representação interna dos valores.
+func (n *node) value(rec map[string]any) (any, error) {
+	switch n.kind {
+	case nodeLit:
+		return n.val, nil
+
+	case nodeField:
+		return n.fieldValue(rec)
+
+	case nodeUnary:
+		v, err := n.args[0].value(rec)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if n.op == "NOT" {
+			b, err := asBool(v)
+			return !b, err
+		}
+		f, err := asNumber(v)
+		if n.op == "-" {
+			f = -f
+		}
+		return f, err
+
+	case nodeBinary:
+		a, err := n.args[0].value(rec)
+		if err != nil {
+			return nil, err
+		}
+		// .AND. e .OR. param assim que o resultado está definido
+		if b, ok := a.(bool); ok && (n.op == "AND" && !b || n.op == "OR" && b) {
+			return b, nil
+		}
+		b, err := n.args[1].value(rec)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(n.op, a, b)
+
+	case nodeCall:
+		if n.fn.lazy != nil {
+			return n.fn.lazy(rec, n.args)
+		}
+		args := make([]any, len(n.args))
+		for i, a := range n.args {
+			v, err := a.value(rec)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !n.fn.nulls {
+				return nil, nil
+			}
+			args[i] = v
+		}
+		if err := n.fn.checkArgs(args); err != nil {
+			return nil, fmt.Errorf("%s(): %w", n.op, err)
+		}
+		v, err := n.fn.call(rec, args)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", n.op, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("nó inválido: %d", n.kind)
+}
+
+// fieldValue lê o campo do registro. Com esquema, campos vazios (nil)
+// viram o valor vazio do tipo (exceto em campos anuláveis) e textos são
+// completados até o tamanho do campo; sem esquema, nil segue como .NULL..
+func (n *node) fieldValue(rec map[string]any) (any, error) {
+	v, ok := rec[n.name]
+	if !ok {
+		for k, x := range rec {
+			if strings.EqualFold(k, n.name) {
+				v, ok = x, true
+				break
+			}
+		}
+	}
+	if !ok && n.field == nil {
+		return nil, fmt.Errorf("campo desconhecido: %s", n.name)
+	}
+	v, err := normalize(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	f := n.field
+	if f == nil || f.Nullable && v == nil {
+		return v, nil
+	}
+	switch n.typ {
+	case 'C':
+		s, _ := v.(string)
+		if v != nil && !isString(v) {
+			return nil, fmt.Errorf("%s: esperado texto, recebido %s", n.name, typeName(v))
+		}
+		if pad := f.Size - utf8.RuneCountInString(s); !varWidth(f.Type) && pad > 0 {
+			s += strings.Repeat(" ", pad)
+		}
+		return s, nil
+	case 'N':
+		if v == nil {
+			return 0.0, nil
+		}
+	case 'L':
+		if v == nil {
+			return false, nil
+		}
+	case 'D':
+		if v == nil {
+			return time.Time{}, nil
+		}
+	case 'T':
+		switch t := v.(type) {
+		case nil:
+			return datetime{}, nil
+		case time.Time:
+			return datetime{t}, nil
+		}
+	}
+	return v, nil
+}
+
+func isString(v any) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// normalize converte os valores de um registro para a representação da
+// avaliação: string, float64, bool, time.Time, datetime ou nil.
+func normalize(v any) (any, error) {
+	switch x := v.(type) {
+	case nil, string, float64, bool, datetime:
+		return v, nil
+	case time.Time:
+		if x.Hour() != 0 || x.Minute() != 0 || x.Second() != 0 || x.Nanosecond() != 0 {
+			return datetime{x}, nil
+		}
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int8:
+		return float64(x), nil
+	case int16:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint8:
+		return float64(x), nil
+	case uint16:
+		return float64(x), nil
+	case uint32:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case interface{ Float64() float64 }: // dbfmini.Decimal
+		return x.Float64(), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	}
+	return nil, fmt.Errorf("valor de tipo %T não suportado", v)
+}
+
+// --------------------------- Operadores ---------------------------
+
+func binaryOp(op string, a, b any) (any, error) {
+	if op == "AND" || op == "OR" {
+		return logicOp(op, a, b)
+	}
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("operador %s: tipos incompatíveis (%s e %s)", op, typeName(a), typeName(b))
+	}
+	switch op {
+	case "+":
+		switch x := a.(type) {
+		case string:
+			if y, ok := b.(string); ok {
+				return x + y, nil
+			}
+		case float64:
+			switch y := b.(type) {
+			case float64:
+				return x + y, nil
+			case time.Time, datetime:
+				return addTime(y, x), nil
+			}
+		case time.Time, datetime:
+			if y, ok := b.(float64); ok {
+				return addTime(x, y), nil
+			}
+		}
+		return nil, mismatch()
+
+	case "-":
+		switch x := a.(type) {
+		case string:
+			if y, ok := b.(string); ok {
+				// os espaços finais do primeiro operando vão para o fim
+				t := strings.TrimRight(x, " ")
+				return t + y + strings.Repeat(" ", len(x)-len(t)), nil
+			}
+		case float64:
+			if y, ok := b.(float64); ok {
+				return x - y, nil
+			}
+		case time.Time:
+			switch y := b.(type) {
+			case float64:
+				return addTime(x, -y), nil
+			case time.Time:
+				return float64(dayNumber(x) - dayNumber(y)), nil
+			}
+		case datetime:
+			switch y := b.(type) {
+			case float64:
+				return addTime(x, -y), nil
+			case datetime:
+				return x.Sub(y.Time).Seconds(), nil
+			}
+		}
+		return nil, mismatch()
+
+	case "*", "/", "%", "**", "^":
+		x, ok1 := a.(float64)
+		y, ok2 := b.(float64)
+		if !ok1 || !ok2 {
+			return nil, mismatch()
+		}
+		switch op {
+		case "*":
+			return x * y, nil
+		case "/":
+			if y == 0 {
+				return nil, ErrDivByZero
+			}
+			return x / y, nil
+		case "%":
+			return mod(x, y)
+		}
+		return math.Pow(x, y), nil
+
+	case "$":
+		x, ok1 := a.(string)
+		y, ok2 := b.(string)
+		if !ok1 || !ok2 {
+			return nil, mismatch()
+		}
+		return strings.Contains(y, x), nil
+	}
+
+	c, ok := compareValues(op, a, b)
+	if !ok {
+		return nil, mismatch()
+	}
+	switch op {
+	case "=", "==":
+		return c == 0, nil
+	case "<>", "#", "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case ">":
+		return c > 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("operador desconhecido: %s", op)
+}
+
+// logicOp segue a lógica de três valores do VFP: .F. .AND. .NULL. é .F.
+// e .T. .OR. .NULL. é .T.; os demais casos com .NULL. dão .NULL..
+func logicOp(op string, a, b any) (any, error) {
+	for _, v := range []any{a, b} {
+		if v == nil {
+			continue
+		}
+		x, err := asBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("operador %s: %w", op, err)
+		}
+		if op == "AND" && !x || op == "OR" && x {
+			return x, nil
+		}
+	}
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	return op == "AND", nil
+}
+
+// compareValues compara a e b do mesmo tipo. Textos seguem SET EXACT OFF
+// em = e <> (basta o primeiro começar pelo segundo) e são comparados com
+// espaços à direita nas demais relações; == exige textos idênticos.
+func compareValues(op string, a, b any) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch op {
+		case "==":
+			return strings.Compare(x, y), true
+		case "=", "<>", "#", "!=":
+			if softEqual(x, y) {
+				return 0, true
+			}
+		}
+		return comparePadded(x, y), true
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		return compareFloat(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x == y:
+			return 0, true
+		case y:
+			return -1, true
+		}
+		return 1, true
+	case time.Time, datetime:
+		tx, _ := asTime(a)
+		ty, ok := asTime(b)
+		if !ok {
+			return 0, false
+		}
+		return tx.Compare(ty), true
+	}
+	return 0, false
+}
+
+func softEqual(x, y string) bool {
+	if n := len(y) - len(x); n > 0 {
+		x += strings.Repeat(" ", n)
+	}
+	return x[:len(y)] == y
+}
+
+func comparePadded(x, y string) int {
+	if n := len(y) - len(x); n > 0 {
+		x += strings.Repeat(" ", n)
+	} else if n < 0 {
+		y += strings.Repeat(" ", -n)
+	}
+	return strings.Compare(x, y)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// mod segue o MOD() do xBase: o resto tem o sinal do divisor.
+func mod(x, y float64) (float64, error) {
+	if y == 0 {
+		return 0, ErrDivByZero
+	}
+	r := math.Mod(x, y)
+	if r != 0 && (r < 0) != (y < 0) {
+		r += y
+	}
+	return r, nil
+}
+
+// addTime soma n dias a uma data ou n segundos a uma data e hora. Datas
+// vazias continuam vazias.
+func addTime(t any, n float64) any {
+	switch x := t.(type) {
+	case time.Time:
+		if x.IsZero() {
+			return x
+		}
+		return x.AddDate(0, 0, int(n))
+	case datetime:
+		if x.IsZero() {
+			return x
+		}
+		return datetime{x.Add(time.Duration(n * float64(time.Second)))}
+	}
+	return nil
+}
+
+// dayNumber conta dias desde 1970-01-01, sem os limites de time.Duration.
+func dayNumber(t time.Time) int64 {
+	y, m, d := t.Date()
+	u := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()
+	if u < 0 {
+		return (u - 86399) / 86400
+	}
+	return u / 86400
+}
+
+// --------------------------- Conversões ---------------------------
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return ".NULL."
+	case string:
+		return typeLabel('C')
+	case float64:
+		return typeLabel('N')
+	case bool:
+		return typeLabel('L')
+	case time.Time:
+		return typeLabel('D')
+	case datetime:
+		return typeLabel('T')
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func asString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("esperado texto, recebido %s", typeName(v))
+	}
+	return s, nil
+}
+
+func asNumber(v any) (float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("esperado número, recebido %s", typeName(v))
+	}
+	return f, nil
+}
+
+func asBool(v any) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("esperado lógico, recebido %s", typeName(v))
+	}
+	return b, nil
+}
+
+// asTime aceita datas e datas com hora.
+func asTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case datetime:
+		return t.Time, true
+	}
+	return time.Time{}, false
+}
+
+func asDate(v any) (time.Time, error) {
+	t, ok := asTime(v)
+	if !ok {
+		return time.Time{}, fmt.Errorf("esperado data, recebido %s", typeName(v))
+	}
+	return t, nil
+}
@@ -0,0 +1,153 @@
+// Package expr interpreta e avalia expressões xBase, como as gravadas em
+// chaves e filtros FOR de índices (UPPER(NOME)+DTOS(NASC),
+// SALDO > 0 .AND. !DELETED()) ou em tabelas de configuração de sistemas
+// legados.
+//
+// As expressões são avaliadas sobre um registro map[string]any, no formato
+// devolvido por dbfmini (dbfmini.Record pode ser passado diretamente). As
+// chaves especiais "_deleted" e "_recno" alimentam DELETED() e RECNO().
+//
+// Os valores seguem os tipos do xBase: C (string), N (float64), L (bool),
+// D (time.Time, com time.Time{} como data vazia) e T (data e hora).
+// Comparações de texto com = seguem SET EXACT OFF: "ABC" = "AB" é
+// verdadeiro; use == para igualdade exata. Valores nil (campos vazios em
+// expressões sem esquema) se comportam como o .NULL. do VFP: propagam-se
+// pelos operadores e funções e, num filtro, contam como falso.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Field descreve uma coluna da tabela. Com o esquema, os tipos são
+// checados na compilação, Width é calculado e os campos texto são
+// completados com espaços até Size, como no registro gravado.
+type Field struct {
+	Name string
+	Type byte // tipo DBF: 'C','N','F','Y','B','I','L','D','T','M','V','Q'...
+	Size int
+	Dec  int
+	// Nullable indica campo anulável (VFP): nil é .NULL., não o valor vazio.
+	Nullable bool
+}
+
+// Expr é uma expressão compilada. É segura para uso concorrente.
+type Expr struct {
+	src    string
+	root   *node
+	fields []string
+}
+
+// Compile interpreta src. Com fields, nomes desconhecidos e tipos
+// incompatíveis geram erro; com fields nil, os campos são procurados no
+// registro a cada avaliação e os tipos só são checados nesse momento.
+func Compile(src string, fields []Field) (*Expr, error) {
+	p := &parser{src: src, fields: fields}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	if len(p.toks) == 1 {
+		return nil, errors.New("expressão vazia")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "símbolo inesperado %q", t.text)
+	}
+	e := &Expr{src: src, root: root}
+	seen := map[string]bool{}
+	root.walk(func(n *node) {
+		if n.kind == nodeField && !seen[n.name] {
+			seen[n.name] = true
+			e.fields = append(e.fields, n.name)
+		}
+	})
+	return e, nil
+}
+
+// Parse interpreta src sem esquema; equivale a Compile(src, nil).
+func Parse(src string) (*Expr, error) { return Compile(src, nil) }
+
+// MustCompile é como Compile, mas entra em pânico em caso de erro.
+func MustCompile(src string, fields []Field) *Expr {
+	e, err := Compile(src, fields)
+	if err != nil {
+		panic(fmt.Sprintf("expr: %q: %v", src, err))
+	}
+	return e
+}
+
+// String devolve o texto original da expressão.
+func (e *Expr) String() string { return e.src }
+
+// Type devolve o tipo do resultado ('C', 'N', 'L', 'D' ou 'T'), ou 0 quando
+// ele depende dos valores do registro (expressão sem esquema).
+func (e *Expr) Type() byte { return e.root.typ }
+
+// Width devolve o tamanho do resultado: o maior texto possível para o tipo
+// C (0 se não puder ser determinado), 8 para D e T, 1 para L e 0 para N.
+func (e *Expr) Width() int {
+	switch e.root.typ {
+	case 'C':
+		return e.root.width
+	case 'D', 'T':
+		return 8
+	case 'L':
+		return 1
+	}
+	return 0
+}
+
+// Fields devolve os nomes dos campos citados, na ordem em que aparecem.
+func (e *Expr) Fields() []string { return append([]string(nil), e.fields...) }
+
+// Eval avalia a expressão sobre rec.
+func (e *Expr) Eval(rec map[string]any) (any, error) {
+	v, err := e.root.eval(rec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// Bool avalia uma expressão lógica, como um filtro FOR. Um resultado
+// .NULL. conta como falso.
+func (e *Expr) Bool(rec map[string]any) (bool, error) {
+	v, err := e.Eval(rec)
+	if err != nil || v == nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s: resultado não lógico (%s)", e.src, typeName(v))
+	}
+	return b, nil
+}
+
+// valueType devolve o tipo xBase de um campo DBF.
+func valueType(t byte) byte {
+	switch t {
+	case 'N', 'F', 'Y', 'B', 'I':
+		return 'N'
+	case 'L', 'D', 'T':
+		return t
+	}
+	return 'C'
+}
+
+// varWidth indica os campos de texto sem largura fixa (memo, varchar e
+// varbinary), que não são completados com espaços.
+func varWidth(t byte) bool { return t == 'M' || t == 'V' || t == 'Q' }
+
+func lookupField(fields []Field, name string) *Field {
+	for i := range fields {
+		if strings.EqualFold(fields[i].Name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
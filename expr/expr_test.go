@@ -0,0 +1,204 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = []Field{
+	{Name: "NOME", Type: 'C', Size: 10},
+	{Name: "SALDO", Type: 'N', Size: 10, Dec: 2},
+	{Name: "QTD", Type: 'I', Size: 4},
+	{Name: "ATIVO", Type: 'L', Size: 1},
+	{Name: "NASC", Type: 'D', Size: 8},
+	{Name: "CRIADO", Type: 'T', Size: 8},
+	{Name: "OBS", Type: 'M', Size: 10},
+}
+
+func testRecord() map[string]any {
+	return map[string]any{
+		"NOME":   "Ana",
+		"SALDO":  150.5,
+		"QTD":    int32(3),
+		"ATIVO":  true,
+		"NASC":   time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC),
+		"CRIADO": time.Date(2024, time.January, 2, 10, 30, 0, 0, time.UTC),
+		"OBS":    "cliente antigo",
+	}
+}
+
+func eval(t *testing.T, src string, fields []Field, rec map[string]any) any {
+	t.Helper()
+	e, err := Compile(src, fields)
+	if err != nil {
+		t.Fatalf("Compile(%q) returned error: %v", src, err)
+	}
+	v, err := e.Eval(rec)
+	if err != nil {
+		t.Fatalf("Eval(%q) returned error: %v", src, err)
+	}
+	return v
+}
+
+func TestEvalOperators(t *testing.T) {
+	rec := testRecord()
+	cases := []struct {
+		src  string
+		want any
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"2 ** 3 ^ 1", 8.0},
+		{"-7 % 3", 2.0},
+		{"7 % -3", -2.0},
+		{"SALDO > 100 .AND. !DELETED()", true},
+		{"SALDO > 100 AND NOT ATIVO", false},
+		{".F. .OR. QTD = 3", true},
+		{"NOME + '|'", "Ana       |"},
+		{"NOME - '|'", "Ana|       "},
+		{"NOME = 'An'", true},
+		{"NOME == 'Ana'", false},
+		{"NOME == 'Ana       '", true},
+		{"NOME <> 'Bia'", true},
+		{"NOME # 'Ana'", false},
+		{"'An' $ NOME", true},
+		{"NOME < 'Bia'", true},
+		{"NASC + 15", time.Date(1990, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"{^1990-05-20} - NASC", 3.0},
+		{"NASC = {05/17/1990}", true},
+		{"NASC > {}", true},
+		{"CRIADO + 60", time.Date(2024, time.January, 2, 10, 31, 0, 0, time.UTC)},
+		{"CRIADO - {^2024-01-02 10:00}", 1800.0},
+		{"OBS", "cliente antigo"},
+		{"cli->saldo", 150.5},
+	}
+	for _, c := range cases {
+		if got := eval(t, c.src, testFields, rec); got != c.want {
+			t.Fatalf("%s: expected %#v, got %#v", c.src, c.want, got)
+		}
+	}
+}
+
+func TestEvalEmptyFields(t *testing.T) {
+	rec := map[string]any{"NOME": "", "SALDO": nil, "NASC": nil, "ATIVO": nil}
+	for src, want := range map[string]any{
+		"NOME":          "          ",
+		"SALDO + 1":     1.0,
+		"EMPTY(NASC)":   true,
+		"DTOS(NASC)":    "        ",
+		"ATIVO":         false,
+		"EMPTY(NOME)":   true,
+		"NASC + 1 = {}": true,
+	} {
+		if got := eval(t, src, testFields, rec); got != want {
+			t.Fatalf("%s: expected %#v, got %#v", src, want, got)
+		}
+	}
+}
+
+func TestEvalNullableFields(t *testing.T) {
+	fields := []Field{
+		{Name: "SALDO", Type: 'N', Size: 10, Dec: 2, Nullable: true},
+		{Name: "NOME", Type: 'C', Size: 10, Nullable: true},
+	}
+	rec := map[string]any{"SALDO": nil, "NOME": "Ana"}
+	for src, want := range map[string]any{
+		"ISNULL(SALDO)":  true,
+		"ISNULL(NOME)":   false,
+		"NVL(SALDO, -1)": -1.0,
+		"NOME":           "Ana       ",
+	} {
+		if got := eval(t, src, fields, rec); got != want {
+			t.Fatalf("%s: expected %#v, got %#v", src, want, got)
+		}
+	}
+}
+
+func TestEvalWithoutSchema(t *testing.T) {
+	rec := map[string]any{"uf": "SP", "SALDO": nil, "_recno": uint32(7)}
+	if got := eval(t, "UF = 'SP' .AND. RECNO() = 7", nil, rec); got != true {
+		t.Fatalf("expected true, got %#v", got)
+	}
+	// nil se comporta como .NULL.
+	if got := eval(t, "SALDO > 0", nil, rec); got != nil {
+		t.Fatalf("expected nil, got %#v", got)
+	}
+	if got := eval(t, "SALDO > 0 .OR. UF = 'SP'", nil, rec); got != true {
+		t.Fatalf("expected true, got %#v", got)
+	}
+	e := MustCompile("SALDO > 0", nil)
+	if ok, err := e.Bool(rec); ok || err != nil {
+		t.Fatalf("expected false/nil, got %v/%v", ok, err)
+	}
+	if _, err := MustCompile("UF + 1", nil).Eval(rec); err == nil {
+		t.Fatalf("expected type error at evaluation")
+	}
+	if _, err := MustCompile("CIDADE", nil).Eval(rec); err == nil {
+		t.Fatalf("expected error for missing field")
+	}
+	if _, err := MustCompile("UPPER(SALDO2)", nil).Eval(map[string]any{"SALDO2": 1}); err == nil {
+		t.Fatalf("expected argument type error")
+	}
+}
+
+func TestCompileTypesAndWidths(t *testing.T) {
+	cases := []struct {
+		src   string
+		typ   byte
+		width int
+	}{
+		{"UPPER(NOME)+DTOS(NASC)", 'C', 18},
+		{"SUBSTR(NOME, 3)", 'C', 8},
+		{"SUBS(NOME, 2, 4)", 'C', 4},
+		{"STR(SALDO, 12, 2) + LEFT(NOME, 3)", 'C', 15},
+		{"IIF(ATIVO, NOME, 'X')", 'C', 10},
+		{"SALDO * QTD", 'N', 0},
+		{"NASC", 'D', 8},
+		{"CRIADO", 'T', 8},
+		{"SALDO > 0 .AND. !DELETED()", 'L', 1},
+	}
+	for _, c := range cases {
+		e, err := Compile(c.src, testFields)
+		if err != nil {
+			t.Fatalf("Compile(%q) returned error: %v", c.src, err)
+		}
+		if e.Type() != c.typ || e.Width() != c.width {
+			t.Fatalf("%s: expected %c/%d, got %c/%d", c.src, c.typ, c.width, e.Type(), e.Width())
+		}
+	}
+	e := MustCompile("UPPER(nome) + DTOS(NASC) + nome", testFields)
+	if got := strings.Join(e.Fields(), ","); got != "NOME,NASC" {
+		t.Fatalf("unexpected fields: %s", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"NOME +",
+		"NOME + 1",
+		"UPPER(SALDO)",
+		"SUBSTR(NOME)",
+		"FOO(NOME)",
+		"CIDADE = 'X'",
+		"(SALDO > 1",
+		"'aberto",
+		".XOR.",
+		"SALDO > 1 2",
+		"{^2024-13-01}",
+		"ATIVO .AND. SALDO",
+	} {
+		if _, err := Compile(src, testFields); err == nil {
+			t.Fatalf("Compile(%q) should fail", src)
+		}
+	}
+}
+
+func TestEvalDivisionByZero(t *testing.T) {
+	_, err := MustCompile("SALDO / 0", testFields).Eval(testRecord())
+	if !errors.Is(err, ErrDivByZero) {
+		t.Fatalf("expected ErrDivByZero, got %v", err)
+	}
+}
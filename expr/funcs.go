@@ -0,0 +1,539 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --------------------------- Funções ---------------------------
+
+// function descreve uma função xBase. params traz o tipo esperado de cada
+// argumento ('C', 'N', 'L', 'D' aceita data ou data e hora, '?' qualquer);
+// com variadic, o último se repete.
+type function struct {
+	params   string
+	min      int
+	variadic bool
+	result   byte // tipo do resultado; 0: o do argumento sameAs
+	sameAs   int
+	width    func(args []*node) int
+	nulls    bool // recebe argumentos .NULL. em vez de propagá-los
+	call     func(rec map[string]any, args []any) (any, error)
+	lazy     func(rec map[string]any, args []*node) (any, error) // avalia os argumentos por conta própria
+}
+
+var functions map[string]*function
+
+func init() {
+	same := func(i int) func([]*node) int {
+		return func(a []*node) int { return a[i].width }
+	}
+	fixed := func(w int) func([]*node) int {
+		return func([]*node) int { return w }
+	}
+	constArg := func(i int) func([]*node) int {
+		return func(a []*node) int {
+			if i < len(a) {
+				if n, ok := a[i].constInt(); ok && n > 0 {
+					return n
+				}
+			}
+			return 0
+		}
+	}
+	text := func(f func(string) string) func(map[string]any, []any) (any, error) {
+		return func(_ map[string]any, a []any) (any, error) {
+			s, err := asString(a[0])
+			return f(s), err
+		}
+	}
+	num := func(f func(float64) float64) func(map[string]any, []any) (any, error) {
+		return func(_ map[string]any, a []any) (any, error) {
+			x, err := asNumber(a[0])
+			return f(x), err
+		}
+	}
+	datePart := func(f func(time.Time) int) func(map[string]any, []any) (any, error) {
+		return func(_ map[string]any, a []any) (any, error) {
+			t, err := asDate(a[0])
+			if err != nil || t.IsZero() {
+				return 0.0, err
+			}
+			return float64(f(t)), nil
+		}
+	}
+
+	functions = map[string]*function{
+		"UPPER":   {params: "C", result: 'C', width: same(0), call: text(strings.ToUpper)},
+		"LOWER":   {params: "C", result: 'C', width: same(0), call: text(strings.ToLower)},
+		"TRIM":    {params: "C", result: 'C', width: same(0), call: text(rtrim)},
+		"RTRIM":   {params: "C", result: 'C', width: same(0), call: text(rtrim)},
+		"LTRIM":   {params: "C", result: 'C', width: same(0), call: text(ltrim)},
+		"ALLTRIM": {params: "C", result: 'C', width: same(0), call: text(func(s string) string { return ltrim(rtrim(s)) })},
+		"SUBSTR": {params: "CNN", min: 2, result: 'C', call: substr,
+			width: func(a []*node) int {
+				if n := constArg(2)(a); n > 0 {
+					return n
+				}
+				if start, ok := a[1].constInt(); ok && start >= 1 && a[0].width >= start {
+					return a[0].width - start + 1
+				}
+				return a[0].width
+			}},
+		"LEFT":  {params: "CN", result: 'C', width: constArg(1), call: left},
+		"RIGHT": {params: "CN", result: 'C', width: constArg(1), call: right},
+		"LEN": {params: "C", result: 'N', call: func(_ map[string]any, a []any) (any, error) {
+			return float64(utf8.RuneCountInString(a[0].(string))), nil
+		}},
+		"AT":        {params: "CC", result: 'N', call: at},
+		"SPACE":     {params: "N", result: 'C', width: constArg(0), call: space},
+		"REPLICATE": {params: "CN", result: 'C', call: replicate, width: func(a []*node) int { return a[0].width * constArg(1)(a) }},
+		"PADL":      {params: "CNC", min: 2, result: 'C', width: constArg(1), call: pad('L')},
+		"PADR":      {params: "CNC", min: 2, result: 'C', width: constArg(1), call: pad('R')},
+		"PADC":      {params: "CNC", min: 2, result: 'C', width: constArg(1), call: pad('C')},
+		"CHR":       {params: "N", result: 'C', width: fixed(1), call: chr},
+		"ASC":       {params: "C", result: 'N', call: asc},
+		"STR":       {params: "NNN", min: 1, result: 'C', width: strWidth, call: str(' ')},
+		"STRZERO":   {params: "NNN", min: 1, result: 'C', width: strWidth, call: str('0')},
+		"VAL":       {params: "C", result: 'N', call: func(_ map[string]any, a []any) (any, error) { return val(a[0].(string)), nil }},
+		"INT":       {params: "N", result: 'N', call: num(math.Trunc)},
+		"ABS":       {params: "N", result: 'N', call: num(math.Abs)},
+		"ROUND":     {params: "NN", result: 'N', call: round},
+		"MOD":       {params: "NN", result: 'N', call: func(_ map[string]any, a []any) (any, error) { return mod(a[0].(float64), a[1].(float64)) }},
+		"MAX":       {params: "??", width: maxWidth, call: minMax(1)},
+		"MIN":       {params: "??", width: maxWidth, call: minMax(-1)},
+		"DTOS":      {params: "D", result: 'C', width: fixed(8), call: dtos},
+		"DTOC":      {params: "D", result: 'C', width: fixed(8), call: dtoc},
+		"CTOD":      {params: "C", result: 'D', call: func(_ map[string]any, a []any) (any, error) { return ctod(a[0].(string)), nil }},
+		"YEAR":      {params: "D", result: 'N', call: datePart(time.Time.Year)},
+		"MONTH":     {params: "D", result: 'N', call: datePart(func(t time.Time) int { return int(t.Month()) })},
+		"DAY":       {params: "D", result: 'N', call: datePart(time.Time.Day)},
+		"DATE": {result: 'D', call: func(map[string]any, []any) (any, error) {
+			y, m, d := time.Now().Date()
+			return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+		}},
+		"EMPTY":  {params: "?", result: 'L', nulls: true, call: func(_ map[string]any, a []any) (any, error) { return empty(a[0]), nil }},
+		"ISNULL": {params: "?", result: 'L', nulls: true, call: func(_ map[string]any, a []any) (any, error) { return a[0] == nil, nil }},
+		"NVL": {params: "??", width: maxWidth, nulls: true, call: func(_ map[string]any, a []any) (any, error) {
+			if a[0] == nil {
+				return a[1], nil
+			}
+			return a[0], nil
+		}},
+		"IIF":     {params: "L??", sameAs: 1, width: func(a []*node) int { return max(a[1].width, a[2].width) }, lazy: iif},
+		"BETWEEN": {params: "???", result: 'L', call: between},
+		"INLIST":  {params: "??", min: 2, variadic: true, result: 'L', call: inlist},
+		"DELETED": {result: 'L', call: func(rec map[string]any, _ []any) (any, error) {
+			del, _ := rec["_deleted"].(bool)
+			return del, nil
+		}},
+		"RECNO": {result: 'N', call: func(rec map[string]any, _ []any) (any, error) {
+			v, err := normalize(rec["_recno"])
+			if v == nil || err != nil {
+				return 0.0, err
+			}
+			return v, nil
+		}},
+	}
+	for _, fn := range functions {
+		if fn.min == 0 && !fn.variadic {
+			fn.min = len(fn.params)
+		}
+	}
+}
+
+// lookupFunction aceita o nome completo ou, como no xBase, abreviado a
+// partir de 4 letras (SUBS, ALLT...).
+func lookupFunction(name string) (*function, string, error) {
+	up := strings.ToUpper(name)
+	if fn, ok := functions[up]; ok {
+		return fn, up, nil
+	}
+	if len(up) >= 4 {
+		var found []string
+		for k := range functions {
+			if strings.HasPrefix(k, up) {
+				found = append(found, k)
+			}
+		}
+		if len(found) == 1 {
+			return functions[found[0]], found[0], nil
+		}
+		if len(found) > 1 {
+			sort.Strings(found)
+			return nil, "", fmt.Errorf("função ambígua: %s (%s)", name, strings.Join(found, ", "))
+		}
+	}
+	return nil, "", fmt.Errorf("função desconhecida: %s()", name)
+}
+
+// check valida a quantidade e os tipos conhecidos dos argumentos e define
+// o tipo e o tamanho do resultado.
+func (fn *function) check(n *node) error {
+	most := len(fn.params)
+	if fn.variadic {
+		most = math.MaxInt
+	}
+	if len(n.args) < fn.min || len(n.args) > most {
+		if fn.min == len(fn.params) && !fn.variadic {
+			return fmt.Errorf("esperado(s) %d argumento(s), recebido(s) %d", fn.min, len(n.args))
+		}
+		return fmt.Errorf("quantidade de argumentos inválida: %d", len(n.args))
+	}
+	for i, a := range n.args {
+		want := fn.params[min(i, len(fn.params)-1)]
+		got := a.typ
+		if want == '?' || got == 0 || got == want || want == 'D' && got == 'T' {
+			continue
+		}
+		return fmt.Errorf("argumento %d: esperado %s, recebido %s", i+1, typeLabel(want), typeLabel(got))
+	}
+	n.typ = fn.result
+	if n.typ == 0 && fn.params != "" {
+		n.typ = n.args[fn.sameAs].typ
+	}
+	if n.typ == 'C' && fn.width != nil {
+		n.width = fn.width(n.args)
+	}
+	return nil
+}
+
+// checkArgs confere, na avaliação, os tipos que a compilação não pôde
+// checar (expressões sem esquema).
+func (fn *function) checkArgs(args []any) error {
+	for i, v := range args {
+		want := fn.params[min(i, len(fn.params)-1)]
+		ok := true
+		switch want {
+		case 'C':
+			_, ok = v.(string)
+		case 'N':
+			_, ok = v.(float64)
+		case 'L':
+			_, ok = v.(bool)
+		case 'D':
+			_, ok = asTime(v)
+		}
+		if !ok && v != nil {
+			return fmt.Errorf("argumento %d: esperado %s, recebido %s", i+1, typeLabel(want), typeName(v))
+		}
+	}
+	return nil
+}
+
+func rtrim(s string) string { return strings.TrimRight(s, " ") }
+func ltrim(s string) string { return strings.TrimLeft(s, " ") }
+
+func substr(_ map[string]any, a []any) (any, error) {
+	r := []rune(a[0].(string))
+	start := int(a[1].(float64))
+	if start < 1 || start > len(r) {
+		return "", nil
+	}
+	end := len(r)
+	if len(a) > 2 {
+		n := int(a[2].(float64))
+		if n < 0 {
+			n = 0
+		}
+		end = min(start-1+n, len(r))
+	}
+	return string(r[start-1 : end]), nil
+}
+
+func left(_ map[string]any, a []any) (any, error) {
+	r := []rune(a[0].(string))
+	n := max(0, min(int(a[1].(float64)), len(r)))
+	return string(r[:n]), nil
+}
+
+func right(_ map[string]any, a []any) (any, error) {
+	r := []rune(a[0].(string))
+	n := max(0, min(int(a[1].(float64)), len(r)))
+	return string(r[len(r)-n:]), nil
+}
+
+func at(_ map[string]any, a []any) (any, error) {
+	i := strings.Index(a[1].(string), a[0].(string))
+	if i < 0 || a[0].(string) == "" {
+		return 0.0, nil
+	}
+	return float64(utf8.RuneCountInString(a[1].(string)[:i]) + 1), nil
+}
+
+func space(_ map[string]any, a []any) (any, error) {
+	return strings.Repeat(" ", max(0, int(a[0].(float64)))), nil
+}
+
+func replicate(_ map[string]any, a []any) (any, error) {
+	return strings.Repeat(a[0].(string), max(0, int(a[1].(float64)))), nil
+}
+
+// pad completa (ou corta) o texto até n caracteres à esquerda, à direita
+// ou dos dois lados.
+func pad(side byte) func(map[string]any, []any) (any, error) {
+	return func(_ map[string]any, a []any) (any, error) {
+		r := []rune(a[0].(string))
+		n := max(0, int(a[1].(float64)))
+		fill := " "
+		if len(a) > 2 {
+			if fill = a[2].(string); fill == "" {
+				fill = " "
+			}
+			fill = string([]rune(fill)[:1])
+		}
+		if len(r) >= n {
+			return string(r[:n]), nil
+		}
+		missing := n - len(r)
+		switch side {
+		case 'L':
+			return strings.Repeat(fill, missing) + string(r), nil
+		case 'R':
+			return string(r) + strings.Repeat(fill, missing), nil
+		}
+		return strings.Repeat(fill, missing/2) + string(r) + strings.Repeat(fill, missing-missing/2), nil
+	}
+}
+
+func chr(_ map[string]any, a []any) (any, error) {
+	n := int(a[0].(float64))
+	if n < 0 || n > 255 {
+		return nil, fmt.Errorf("código fora da faixa: %d", n)
+	}
+	return string(rune(n)), nil
+}
+
+func asc(_ map[string]any, a []any) (any, error) {
+	r, size := utf8.DecodeRuneInString(a[0].(string))
+	if size == 0 {
+		return 0.0, nil
+	}
+	return float64(r), nil
+}
+
+func strWidth(a []*node) int {
+	if len(a) > 1 {
+		n, _ := a[1].constInt()
+		return max(n, 0)
+	}
+	return 10
+}
+
+// str implementa STR() e STRZERO(): o número arredondado em dec casas
+// (padrão 0) e alinhado à direita em width posições (padrão 10). Se não
+// couber, as casas decimais são reduzidas e, em último caso, o resultado
+// é preenchido com asteriscos.
+func str(fill byte) func(map[string]any, []any) (any, error) {
+	return func(_ map[string]any, a []any) (any, error) {
+		f := a[0].(float64)
+		width, dec := 10, 0
+		if len(a) > 1 {
+			width = int(a[1].(float64))
+		}
+		if len(a) > 2 {
+			dec = int(a[2].(float64))
+		}
+		width, dec = max(width, 0), max(dec, 0)
+		s := ""
+		for d := dec; d >= 0; d-- {
+			if s = formatRound(f, d); len(s) <= width {
+				break
+			}
+		}
+		if len(s) > width {
+			return strings.Repeat("*", width), nil
+		}
+		padding := strings.Repeat(string(fill), width-len(s))
+		if fill == '0' && strings.HasPrefix(s, "-") {
+			return "-" + padding + s[1:], nil
+		}
+		return padding + s, nil
+	}
+}
+
+// formatRound formata f com dec casas, arredondando metades para longe
+// do zero como o xBase.
+func formatRound(f float64, dec int) string {
+	p := math.Pow10(dec)
+	r := math.Round(f*p) / p
+	if r == 0 {
+		r = 0 // sem -0
+	}
+	return strconv.FormatFloat(r, 'f', dec, 64)
+}
+
+// val converte o número no início de s, ignorando espaços à esquerda;
+// sem dígitos, devolve 0.
+func val(s string) float64 {
+	s = strings.TrimLeft(s, " ")
+	end := 0
+	if end < len(s) && (s[end] == '-' || s[end] == '+') {
+		end++
+	}
+	dot := false
+	for end < len(s) && (isDigit(s[end]) || s[end] == '.' && !dot) {
+		dot = dot || s[end] == '.'
+		end++
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s[:end], "."), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// round arredonda em n casas; n negativo arredonda dezenas, centenas...
+func round(_ map[string]any, a []any) (any, error) {
+	x, n := a[0].(float64), int(a[1].(float64))
+	if n < 0 {
+		p := math.Pow10(-n)
+		return math.Round(x/p) * p, nil
+	}
+	return strconv.ParseFloat(formatRound(x, n), 64)
+}
+
+func maxWidth(a []*node) int { return max(a[0].width, a[1].width) }
+
+func minMax(sign int) func(map[string]any, []any) (any, error) {
+	return func(_ map[string]any, a []any) (any, error) {
+		c, ok := compareValues("<", a[0], a[1])
+		if !ok {
+			return nil, fmt.Errorf("tipos incompatíveis (%s e %s)", typeName(a[0]), typeName(a[1]))
+		}
+		if c*sign >= 0 {
+			return a[0], nil
+		}
+		return a[1], nil
+	}
+}
+
+func dtos(_ map[string]any, a []any) (any, error) {
+	t, err := asDate(a[0])
+	if err != nil || t.IsZero() {
+		return "        ", err
+	}
+	return t.Format("20060102"), nil
+}
+
+// dtoc usa o formato padrão do xBase (SET DATE AMERICAN, SET CENTURY OFF).
+func dtoc(_ map[string]any, a []any) (any, error) {
+	t, err := asDate(a[0])
+	if err != nil || t.IsZero() {
+		return "  /  /  ", err
+	}
+	return t.Format("01/02/06"), nil
+}
+
+// ctod interpreta MM/DD/AA[AA] ou ^AAAA-MM-DD; textos inválidos dão a
+// data vazia. Anos de dois dígitos ficam no século XX, como no xBase.
+func ctod(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "^") {
+		t, _ := ymd(strings.TrimSpace(s[1:]))
+		return t
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}
+		}
+		n[i] = v
+	}
+	if len(parts[2]) <= 2 {
+		n[2] += 1900
+	}
+	return validDate(n[2], n[0], n[1])
+}
+
+// ymd interpreta AAAA-MM-DD (ou com / e .).
+func ymd(s string) (time.Time, bool) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, false
+		}
+		n[i] = v
+	}
+	t := validDate(n[0], n[1], n[2])
+	return t, !t.IsZero()
+}
+
+func validDate(y, m, d int) time.Time {
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if y < 1 || y > 9999 || t.Month() != time.Month(m) || t.Day() != d {
+		return time.Time{}
+	}
+	return t
+}
+
+func empty(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	case float64:
+		return x == 0
+	case bool:
+		return !x
+	case time.Time:
+		return x.IsZero()
+	case datetime:
+		return x.IsZero()
+	}
+	return false
+}
+
+func iif(rec map[string]any, args []*node) (any, error) {
+	c, err := args[0].value(rec)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		if _, ok := c.(bool); !ok {
+			return nil, fmt.Errorf("IIF(): condição não lógica (%s)", typeName(c))
+		}
+	}
+	if c == true {
+		return args[1].value(rec)
+	}
+	return args[2].value(rec)
+}
+
+func between(_ map[string]any, a []any) (any, error) {
+	lo, ok1 := compareValues(">=", a[0], a[1])
+	hi, ok2 := compareValues("<=", a[0], a[2])
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("tipos incompatíveis (%s, %s e %s)", typeName(a[0]), typeName(a[1]), typeName(a[2]))
+	}
+	return lo >= 0 && hi <= 0, nil
+}
+
+func inlist(_ map[string]any, a []any) (any, error) {
+	for _, v := range a[1:] {
+		c, ok := compareValues("=", a[0], v)
+		if !ok {
+			return nil, fmt.Errorf("tipos incompatíveis (%s e %s)", typeName(a[0]), typeName(v))
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package expr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFunctions(t *testing.T) {
+	rec := testRecord()
+	rec["_deleted"] = true
+	rec["_recno"] = uint32(12)
+	cases := []struct {
+		src  string
+		want any
+	}{
+		{"UPPER(NOME)", "ANA       "},
+		{"LOWER('ABC')", "abc"},
+		{"TRIM(NOME) + '!'", "Ana!"},
+		{"LTRIM('  x ')", "x "},
+		{"ALLTRIM('  x ')", "x"},
+		{"ALLT('  x ')", "x"},
+		{"SUBSTR('abcdef', 2, 3)", "bcd"},
+		{"SUBSTR('abcdef', 4)", "def"},
+		{"SUBSTR('abc', 9)", ""},
+		{"LEFT('abcdef', 2)", "ab"},
+		{"RIGHT('abcdef', 2)", "ef"},
+		{"LEN(NOME)", 10.0},
+		{"AT('c', 'abcabc')", 3.0},
+		{"AT('z', 'abc')", 0.0},
+		{"SPACE(3)", "   "},
+		{"REPLICATE('ab', 3)", "ababab"},
+		{"PADL('7', 3, '0')", "007"},
+		{"PADR('ab', 4)", "ab  "},
+		{"PADC('ab', 5, '*')", "*ab**"},
+		{"CHR(65) + CHR(66)", "AB"},
+		{"ASC('A')", 65.0},
+		{"STR(42)", "        42"},
+		{"STR(3.14159, 6, 2)", "  3.14"},
+		{"STR(2.5, 3)", "  3"},
+		{"STR(123.456, 5, 2)", "123.5"},
+		{"STR(123456, 3)", "***"},
+		{"STRZERO(42, 5)", "00042"},
+		{"STRZERO(-42, 5)", "-0042"},
+		{"VAL('  12.5abc')", 12.5},
+		{"VAL('-3')", -3.0},
+		{"VAL('x')", 0.0},
+		{"INT(-7.9)", -7.0},
+		{"ABS(-2)", 2.0},
+		{"ROUND(2.345, 2)", 2.35},
+		{"ROUND(1234, -2)", 1200.0},
+		{"MOD(10, 3)", 1.0},
+		{"MAX(SALDO, 200)", 200.0},
+		{"MIN('b', 'a')", "a"},
+		{"DTOS(NASC)", "19900517"},
+		{"DTOC(NASC)", "05/17/90"},
+		{"CTOD('12/25/2023')", time.Date(2023, time.December, 25, 0, 0, 0, 0, time.UTC)},
+		{"CTOD('12/25/23')", time.Date(1923, time.December, 25, 0, 0, 0, 0, time.UTC)},
+		{"CTOD('^2023-02-30')", time.Time{}},
+		{"YEAR(NASC) + MONTH(NASC) + DAY(NASC)", 2012.0},
+		{"DTOS(CRIADO)", "20240102"},
+		{"EMPTY(0) .AND. EMPTY('  ') .AND. !EMPTY(NOME)", true},
+		{"IIF(SALDO > 100, 'alto', 'baixo')", "alto"},
+		{"IIF(.F., 1/0, 2)", 2.0},
+		{"BETWEEN(SALDO, 100, 200)", true},
+		{"INLIST(NOME, 'Bia', 'Ana')", true},
+		{"INLIST(QTD, 1, 2)", false},
+		{"DELETED()", true},
+		{"RECNO()", 12.0},
+		{"NVL(.NULL., 5)", 5.0},
+		{"ISNULL(.NULL.)", true},
+		{"ISNULL(UPPER(.NULL.))", true},
+	}
+	for _, c := range cases {
+		if got := eval(t, c.src, testFields, rec); got != c.want {
+			t.Fatalf("%s: expected %#v, got %#v", c.src, c.want, got)
+		}
+	}
+}
+
+func TestFunctionLookup(t *testing.T) {
+	if _, _, err := lookupFunction("STR"); err != nil {
+		t.Fatalf("expected STR to be found: %v", err)
+	}
+	if _, name, err := lookupFunction("repl"); err != nil || name != "REPLICATE" {
+		t.Fatalf("expected REPL to resolve to REPLICATE, got %q/%v", name, err)
+	}
+	// DTOS/DTOC: abreviação ambígua
+	if _, _, err := lookupFunction("DTO"); err == nil {
+		t.Fatalf("expected error for short abbreviation")
+	}
+	if _, _, err := lookupFunction("STRZ"); err != nil {
+		t.Fatalf("expected STRZ to resolve: %v", err)
+	}
+}
@@ -0,0 +1,520 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --------------------------- Léxico ---------------------------
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokStr
+	tokDate
+	tokBool
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int // posição (base 1) no texto
+	val  any // literais já convertidos
+}
+
+// operadores de mais de um caractere vêm antes dos seus prefixos
+var operators = []string{"**", "==", "<>", "!=", "<=", ">=", "->", "^", "*", "/", "%", "+", "-", "=", "#", "<", ">", "$", "!"}
+
+// dotWords são as palavras entre pontos: .T., .AND. etc.
+var dotWords = map[string]string{
+	"T": "T", "Y": "T", "F": "F", "N": "F",
+	"AND": "AND", "OR": "OR", "NOT": "NOT", "NULL": "NULL",
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+			continue
+
+		case c == '.' && i+1 < len(s) && isLetter(s[i+1]):
+			j := strings.IndexByte(s[i+1:], '.')
+			if j < 0 {
+				return fmt.Errorf("posição %d: ponto sem fechamento", i+1)
+			}
+			word, ok := dotWords[strings.ToUpper(s[i+1:i+1+j])]
+			if !ok {
+				return fmt.Errorf("posição %d: operador desconhecido %q", i+1, s[i:i+j+2])
+			}
+			i += j + 2
+			switch word {
+			case "T", "F":
+				p.toks = append(p.toks, token{kind: tokBool, text: s[start:i], pos: start + 1, val: word == "T"})
+			case "NULL":
+				p.toks = append(p.toks, token{kind: tokBool, text: s[start:i], pos: start + 1})
+			default:
+				p.toks = append(p.toks, token{kind: tokOp, text: word, pos: start + 1})
+			}
+
+		case isDigit(c) || c == '.' && i+1 < len(s) && isDigit(s[i+1]):
+			for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			f, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return fmt.Errorf("posição %d: número inválido %q", start+1, s[start:i])
+			}
+			p.toks = append(p.toks, token{kind: tokNum, text: s[start:i], pos: start + 1, val: f})
+
+		case c == '"' || c == '\'' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			j := strings.IndexByte(s[i+1:], end)
+			if j < 0 {
+				return fmt.Errorf("posição %d: texto sem fechamento", i+1)
+			}
+			i += j + 2
+			p.toks = append(p.toks, token{kind: tokStr, text: s[start:i], pos: start + 1, val: s[start+1 : i-1]})
+
+		case c == '{':
+			j := strings.IndexByte(s[i+1:], '}')
+			if j < 0 {
+				return fmt.Errorf("posição %d: data sem fechamento", i+1)
+			}
+			i += j + 2
+			v, err := parseDateLiteral(strings.TrimSpace(s[start+1 : i-1]))
+			if err != nil {
+				return fmt.Errorf("posição %d: %w", start+1, err)
+			}
+			p.toks = append(p.toks, token{kind: tokDate, text: s[start:i], pos: start + 1, val: v})
+
+		case isLetter(c) || c == '_':
+			for i < len(s) && (isLetter(s[i]) || isDigit(s[i]) || s[i] == '_') {
+				i++
+			}
+			p.toks = append(p.toks, token{kind: tokIdent, text: s[start:i], pos: start + 1})
+
+		case c == '(':
+			i++
+			p.toks = append(p.toks, token{kind: tokLParen, text: "(", pos: start + 1})
+		case c == ')':
+			i++
+			p.toks = append(p.toks, token{kind: tokRParen, text: ")", pos: start + 1})
+		case c == ',':
+			i++
+			p.toks = append(p.toks, token{kind: tokComma, text: ",", pos: start + 1})
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(s[i:])
+				return fmt.Errorf("posição %d: caractere inesperado %q", i+1, r)
+			}
+			i += len(op)
+			if op == "!" {
+				op = "NOT"
+			}
+			p.toks = append(p.toks, token{kind: tokOp, text: op, pos: start + 1})
+		}
+	}
+	p.toks = append(p.toks, token{kind: tokEOF, pos: len(s) + 1})
+	return nil
+}
+
+// parseDateLiteral aceita {} (data vazia), {^AAAA-MM-DD [hh:mm[:ss]]} e
+// {MM/DD/AA[AA]}.
+func parseDateLiteral(s string) (any, error) {
+	if s == "" || s == "/  /" || s == "--" {
+		return time.Time{}, nil
+	}
+	if strings.HasPrefix(s, "^") {
+		s = strings.TrimSpace(s[1:])
+		date, clock, hasClock := strings.Cut(s, " ")
+		d, ok := ymd(date)
+		if !ok {
+			return nil, fmt.Errorf("data inválida: {^%s}", s)
+		}
+		if !hasClock {
+			return d, nil
+		}
+		for _, layout := range []string{"15:04:05", "15:04"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(clock)); err == nil {
+				return datetime{d.Add(time.Duration(t.Hour())*time.Hour +
+					time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)}, nil
+			}
+		}
+		return nil, fmt.Errorf("hora inválida: %s", clock)
+	}
+	d := ctod(s)
+	if d.IsZero() {
+		return nil, fmt.Errorf("data inválida: {%s}", s)
+	}
+	return d, nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// --------------------------- Árvore ---------------------------
+
+type nodeKind int
+
+const (
+	nodeLit nodeKind = iota
+	nodeField
+	nodeUnary
+	nodeBinary
+	nodeCall
+)
+
+// node é um nó da árvore, já com o tipo (0 se desconhecido) e, para
+// texto, o tamanho máximo do resultado.
+type node struct {
+	kind  nodeKind
+	op    string // operador ou nome da função
+	val   any    // literal
+	name  string // campo
+	field *Field // nil sem esquema
+	fn    *function
+	args  []*node
+	typ   byte
+	width int
+}
+
+func (n *node) walk(f func(*node)) {
+	f(n)
+	for _, a := range n.args {
+		a.walk(f)
+	}
+}
+
+// constInt devolve o valor de um argumento numérico literal.
+func (n *node) constInt() (int, bool) {
+	if n.kind == nodeUnary && n.op == "-" {
+		v, ok := n.args[0].constInt()
+		return -v, ok
+	}
+	f, ok := n.val.(float64)
+	if n.kind != nodeLit || !ok {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// --------------------------- Sintaxe ---------------------------
+
+type parser struct {
+	src    string
+	fields []Field
+	toks   []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("posição %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+// isWord indica se t é o operador lógico w, na forma .W. ou por extenso.
+func isWord(t token, w string) bool {
+	return t.kind == tokOp && t.text == w || t.kind == tokIdent && strings.EqualFold(t.text, w)
+}
+
+func (p *parser) parseOr() (*node, error) {
+	n, err := p.parseAnd()
+	for err == nil && isWord(p.peek(), "OR") {
+		t := p.next()
+		var r *node
+		if r, err = p.parseAnd(); err == nil {
+			n, err = p.binary(t, n, r)
+		}
+	}
+	return n, err
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	n, err := p.parseNot()
+	for err == nil && isWord(p.peek(), "AND") {
+		t := p.next()
+		var r *node
+		if r, err = p.parseNot(); err == nil {
+			n, err = p.binary(t, n, r)
+		}
+	}
+	return n, err
+}
+
+func (p *parser) parseNot() (*node, error) {
+	if !isWord(p.peek(), "NOT") {
+		return p.parseRel()
+	}
+	t := p.next()
+	n, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return p.unary(t, "NOT", n)
+}
+
+var relOps = map[string]bool{"=": true, "==": true, "<>": true, "#": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "$": true}
+
+func (p *parser) parseRel() (*node, error) {
+	n, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokOp && relOps[t.text] {
+		p.next()
+		r, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return p.binary(t, n, r)
+	}
+	return n, nil
+}
+
+func (p *parser) parseAdd() (*node, error) {
+	n, err := p.parseMul()
+	for err == nil && p.peek().kind == tokOp && (p.peek().text == "+" || p.peek().text == "-") {
+		t := p.next()
+		var r *node
+		if r, err = p.parseMul(); err == nil {
+			n, err = p.binary(t, n, r)
+		}
+	}
+	return n, err
+}
+
+func (p *parser) parseMul() (*node, error) {
+	n, err := p.parsePow()
+	for err == nil && p.peek().kind == tokOp && strings.Contains("*/%", p.peek().text) && p.peek().text != "**" {
+		t := p.next()
+		var r *node
+		if r, err = p.parsePow(); err == nil {
+			n, err = p.binary(t, n, r)
+		}
+	}
+	return n, err
+}
+
+func (p *parser) parsePow() (*node, error) {
+	n, err := p.parseUnary()
+	for err == nil && p.peek().kind == tokOp && (p.peek().text == "**" || p.peek().text == "^") {
+		t := p.next()
+		var r *node
+		if r, err = p.parseUnary(); err == nil {
+			n, err = p.binary(t, n, r)
+		}
+	}
+	return n, err
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	if t := p.peek(); t.kind == tokOp && (t.text == "-" || t.text == "+") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return p.unary(t, t.text, n)
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNum:
+		return &node{kind: nodeLit, val: t.val, typ: 'N'}, nil
+	case tokStr:
+		s := t.val.(string)
+		return &node{kind: nodeLit, val: s, typ: 'C', width: utf8.RuneCountInString(s)}, nil
+	case tokDate:
+		if _, ok := t.val.(datetime); ok {
+			return &node{kind: nodeLit, val: t.val, typ: 'T'}, nil
+		}
+		return &node{kind: nodeLit, val: t.val, typ: 'D'}, nil
+	case tokBool:
+		if t.val == nil {
+			return &node{kind: nodeLit}, nil
+		}
+		return &node{kind: nodeLit, val: t.val, typ: 'L'}, nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, p.errorf(c, "esperado ')'")
+		}
+		return n, nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		if nt := p.peek(); nt.kind == tokOp && nt.text == "->" {
+			// ALIAS->CAMPO: o alias é ignorado
+			p.next()
+			if t = p.next(); t.kind != tokIdent {
+				return nil, p.errorf(t, "esperado nome de campo após ->")
+			}
+		}
+		return p.fieldNode(t)
+	case tokEOF:
+		return nil, p.errorf(t, "expressão incompleta")
+	}
+	return nil, p.errorf(t, "símbolo inesperado %q", t.text)
+}
+
+func (p *parser) fieldNode(t token) (*node, error) {
+	n := &node{kind: nodeField, name: strings.ToUpper(t.text)}
+	if p.fields == nil {
+		return n, nil
+	}
+	f := lookupField(p.fields, t.text)
+	if f == nil {
+		return nil, p.errorf(t, "campo desconhecido: %s", t.text)
+	}
+	n.field, n.name, n.typ = f, f.Name, valueType(f.Type)
+	if n.typ == 'C' && !varWidth(f.Type) {
+		n.width = f.Size
+	}
+	return n, nil
+}
+
+func (p *parser) parseCall(name token) (*node, error) {
+	fn, fname, err := lookupFunction(name.text)
+	if err != nil {
+		return nil, p.errorf(name, "%v", err)
+	}
+	p.next() // (
+	n := &node{kind: nodeCall, op: fname, fn: fn}
+	if p.peek().kind != tokRParen {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, a)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if c := p.next(); c.kind != tokRParen {
+		return nil, p.errorf(c, "esperado ')' em %s()", fname)
+	}
+	if err := fn.check(n); err != nil {
+		return nil, p.errorf(name, "%s: %v", fname, err)
+	}
+	return n, nil
+}
+
+// --------------------------- Tipos ---------------------------
+
+func (p *parser) unary(t token, op string, a *node) (*node, error) {
+	n := &node{kind: nodeUnary, op: op, args: []*node{a}}
+	want := byte('N')
+	if op == "NOT" {
+		want = 'L'
+	}
+	if a.typ != 0 && a.typ != want {
+		return nil, p.errorf(t, "operador %s: esperado %s, recebido %s", t.text, typeLabel(want), typeLabel(a.typ))
+	}
+	n.typ = want
+	return n, nil
+}
+
+func (p *parser) binary(t token, a, b *node) (*node, error) {
+	n := &node{kind: nodeBinary, op: t.text, args: []*node{a, b}}
+	typ, width, ok := binaryType(n.op, a, b)
+	if !ok {
+		return nil, p.errorf(t, "operador %s: tipos incompatíveis (%s e %s)", t.text, typeLabel(a.typ), typeLabel(b.typ))
+	}
+	n.typ, n.width = typ, width
+	return n, nil
+}
+
+// binaryType deduz o tipo do resultado; tipos desconhecidos (0) são
+// aceitos e checados na avaliação.
+func binaryType(op string, a, b *node) (byte, int, bool) {
+	x, y := a.typ, b.typ
+	known := x != 0 && y != 0
+	isDate := func(t byte) bool { return t == 'D' || t == 'T' }
+	switch op {
+	case "+", "-":
+		switch {
+		case x == 'C' && y == 'C':
+			return 'C', a.width + b.width, true
+		case x == 'N' && y == 'N':
+			return 'N', 0, true
+		case isDate(x) && y == 'N':
+			return x, 0, true
+		case op == "+" && x == 'N' && isDate(y):
+			return y, 0, true
+		case op == "-" && isDate(x) && x == y:
+			return 'N', 0, true
+		case !known && (x == 0 || x == 'C' || x == 'N' || isDate(x)) && (y == 0 || y == 'C' || y == 'N' || isDate(y)):
+			return 0, 0, true
+		}
+	case "*", "/", "%", "**", "^":
+		return 'N', 0, (x == 0 || x == 'N') && (y == 0 || y == 'N')
+	case "$":
+		return 'L', 0, (x == 0 || x == 'C') && (y == 0 || y == 'C')
+	case "AND", "OR":
+		return 'L', 0, (x == 0 || x == 'L') && (y == 0 || y == 'L')
+	default: // comparações
+		return 'L', 0, !known || x == y || isDate(x) && isDate(y)
+	}
+	return 0, 0, false
+}
+
+func typeLabel(t byte) string {
+	switch t {
+	case 'C':
+		return "texto"
+	case 'N':
+		return "número"
+	case 'L':
+		return "lógico"
+	case 'D':
+		return "data"
+	case 'T':
+		return "data e hora"
+	}
+	return "desconhecido"
+}
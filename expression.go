@@ -0,0 +1,23 @@
+package dbfmini
+
+import "github.com/alberto255345/dbfmini/expr"
+
+// --------------------------- Expressões ---------------------------
+
+// CompileExpr compila uma expressão xBase (filtro ou chave) com o esquema
+// da tabela, de modo que nomes e tipos são checados antes da leitura. O
+// resultado avalia os registros devolvidos por Next e ReadRecords; use
+// IncludeDeleted e IncludeRecno para DELETED() e RECNO().
+func (d *DBF) CompileExpr(src string) (*expr.Expr, error) {
+	return expr.Compile(src, exprFields(d.Fields))
+}
+
+// exprFields converte o esquema para o pacote expr.
+func exprFields(fields []Field) []expr.Field {
+	out := make([]expr.Field, len(fields))
+	for i, f := range fields {
+		out[i] = expr.Field{Name: f.Name, Type: f.Type, Size: int(f.Size), Dec: int(f.DecimalPlaces),
+			Nullable: f.Flags&FieldNullable != 0}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package dbfmini
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileExprFiltersRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41},
+		Record{"NAME": "Carla", "AGE": 25, "_deleted": true},
+	)
+	db, err := Open(path, &OpenOptions{IncludeDeleted: true, IncludeRecno: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if _, err := db.CompileExpr("CITY = 'SP'"); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+	e, err := db.CompileExpr("AGE > 26 .OR. DELETED() .AND. RECNO() = 3")
+	if err != nil {
+		t.Fatalf("CompileExpr returned error: %v", err)
+	}
+	var names []string
+	for db.Next() {
+		ok, err := e.Bool(db.Record())
+		if err != nil {
+			t.Fatalf("Bool returned error: %v", err)
+		}
+		if ok {
+			names = append(names, db.Record()["NAME"].(string))
+		}
+	}
+	if len(names) != 3 {
+		t.Fatalf("expected 3 matches, got %v", names)
+	}
+
+	key, err := db.CompileExpr("UPPER(NAME) + STR(AGE, 3)")
+	if err != nil {
+		t.Fatalf("CompileExpr returned error: %v", err)
+	}
+	if key.Type() != 'C' || key.Width() != 13 {
+		t.Fatalf("expected C/13, got %c/%d", key.Type(), key.Width())
+	}
+}
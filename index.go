@@ -0,0 +1,441 @@
+package dbfmini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alberto255345/dbfmini/expr"
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- Índices ---------------------------
+
+// IndexTag descreve uma ordem de índice: uma tag de um .CDX/.MDX ou o
+// arquivo inteiro, nos formatos de chave única (.NDX/.NTX).
+type IndexTag struct {
+	Name       string
+	Expr       string // expressão da chave, ex.: "UPPER(NOME)"
+	For        string // filtro FOR ("" se não houver)
+	Unique     bool
+	Descending bool
+	KeyLen     int
+
+	keyType byte // 'C', 'N', 'D', 'T' ou 'I'
+	tree    *btree
+	seekKey func(v any) ([]byte, error)
+}
+
+// Index é um arquivo de índice aberto com OpenIndex. As chaves de Seek são
+// valores Go (string, números, time.Time) convertidos para o formato da
+// tag; []byte é usado como chave bruta.
+type Index struct {
+	Path string
+	Tags []*IndexTag
+
+	r io.ReaderAt
+	c io.Closer
+}
+
+// ErrTagNotFound indica tag inexistente no índice.
+var ErrTagNotFound = errors.New("tag de índice não encontrada")
+
+// OpenIndex abre o arquivo de índice em path para esta tabela. O formato
+// é escolhido pela extensão: .cdx, .mdx, .ndx ou .ntx. Nos dois últimos,
+// de chave única, a tag tem o nome do arquivo e pode ser referida por "".
+func (d *DBF) OpenIndex(path string) (*Index, error) {
+	return d.openIndex(fileOpener(path), path)
+}
+
+// HasProductionIndex informa se o header (byte 28) marca um índice de
+// produção: o .MDX do dBase IV ou o .CDX estrutural do FoxPro, abertos
+// automaticamente por essas aplicações junto com a tabela.
+func (d *DBF) HasProductionIndex() bool { return d.flags&0x01 != 0 }
+
+// OpenProductionIndex abre o índice de produção ao lado da tabela (mesmo
+// nome, extensão .mdx ou .cdx).
+func (d *DBF) OpenProductionIndex() (*Index, error) {
+	if !d.HasProductionIndex() {
+		return nil, errors.New("tabela sem índice de produção")
+	}
+	if d.sibling == nil {
+		return nil, errors.New("índice de produção indisponível para tabelas abertas com OpenReader")
+	}
+	exts := []string{".mdx", ".MDX", ".cdx", ".CDX"}
+	if usesFPT(d.version) {
+		exts = exts[2:]
+	}
+	open, path := findSibling(d.Path, d.sibling, exts...)
+	if open == nil {
+		return nil, errors.New("índice de produção não encontrado")
+	}
+	return d.openIndex(open, path)
+}
+
+func (d *DBF) openIndex(open opener, path string) (*Index, error) {
+	r, c, err := open()
+	if err != nil {
+		return nil, err
+	}
+	x := &Index{Path: path, r: r, c: c}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cdx":
+		x.Tags, err = d.readCDX(r)
+	case ".mdx":
+		x.Tags, err = d.readMDX(r)
+	case ".ndx":
+		x.Tags, err = d.readNDX(r, path)
+	case ".ntx":
+		x.Tags, err = d.readNTX(r, path)
+	default:
+		err = fmt.Errorf("formato de índice não suportado: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		x.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return x, nil
+}
+
+// Close fecha o arquivo de índice.
+func (x *Index) Close() error {
+	if x.c == nil {
+		return nil
+	}
+	err := x.c.Close()
+	x.c = nil
+	return err
+}
+
+// Tag devolve a tag pelo nome, sem diferenciar maiúsculas. Em índices de
+// chave única, "" devolve a única tag.
+func (x *Index) Tag(name string) (*IndexTag, error) {
+	if name == "" && len(x.Tags) == 1 {
+		return x.Tags[0], nil
+	}
+	for _, t := range x.Tags {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTagNotFound, name)
+}
+
+// Seek procura key na tag e devolve o número do primeiro registro (base 1)
+// cuja chave começa por key, como SEEK com SET EXACT OFF. found é false se
+// não houver chave correspondente.
+func (x *Index) Seek(tag string, key any) (recno uint32, found bool, err error) {
+	recno, found, err = x.SeekSoft(tag, key)
+	if !found {
+		recno = 0
+	}
+	return recno, found, err
+}
+
+// SeekSoft é Seek com SET SOFTSEEK ON: sem correspondência, devolve o
+// registro da primeira chave maior que key (found=false), ou 0 se key for
+// maior que todas.
+func (x *Index) SeekSoft(tag string, key any) (recno uint32, found bool, err error) {
+	t, err := x.Tag(tag)
+	if err != nil {
+		return 0, false, err
+	}
+	k, err := t.seekKey(key)
+	if err != nil {
+		return 0, false, err
+	}
+	c := t.tree.seek(k)
+	if !c.Next() {
+		return 0, false, c.Err()
+	}
+	return c.Recno(), t.tree.hasPrefix(c.Key(), k), nil
+}
+
+// Scan percorre a tag na ordem do índice (decrescente se Descending).
+//
+//	c, err := idx.Scan("CODIGO")
+//	for c.Next() {
+//		rec, err := db.ReadAt(c.Recno())
+//	}
+func (x *Index) Scan(tag string) (*IndexCursor, error) {
+	t, err := x.Tag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return t.tree.scan(t.Descending && !t.tree.descStored), nil
+}
+
+// ScanFrom percorre a tag em ordem crescente de chave a partir da primeira
+// chave >= key.
+func (x *Index) ScanFrom(tag string, key any) (*IndexCursor, error) {
+	t, err := x.Tag(tag)
+	if err != nil {
+		return nil, err
+	}
+	k, err := t.seekKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return t.tree.seek(k), nil
+}
+
+// ScanRange percorre a tag em ordem crescente de chave, da primeira chave
+// >= from até a última cujo prefixo seja <= to.
+func (x *Index) ScanRange(tag string, from, to any) (*IndexCursor, error) {
+	t, err := x.Tag(tag)
+	if err != nil {
+		return nil, err
+	}
+	lo, err := t.seekKey(from)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := t.seekKey(to)
+	if err != nil {
+		return nil, err
+	}
+	c := t.tree.seek(lo)
+	c.stop = hi
+	return c, nil
+}
+
+// --------------------------- B-tree ---------------------------
+
+// indexNode é um nó já decodificado, comum a todos os formatos. Cada
+// chave i tem à esquerda o filho children[i]; last é o filho à direita da
+// última chave. 0 indica ausência de filho.
+type indexNode struct {
+	leaf     bool
+	keys     [][]byte
+	recnos   []uint32
+	children []int64
+	last     int64
+}
+
+func (n *indexNode) child(i int) int64 {
+	if i < len(n.children) {
+		return n.children[i]
+	}
+	return n.last
+}
+
+// btree percorre a árvore de um índice. Em formatos B+ (CDX, NDX, MDX) as
+// chaves internas só separam os filhos; com interiorData (NTX) elas também
+// apontam para registros. descStored indica árvore gravada já em ordem
+// decrescente (compare invertido).
+type btree struct {
+	root         int64
+	load         func(off int64) (*indexNode, error)
+	compare      func(a, b []byte) int
+	interiorData bool
+	descStored   bool
+}
+
+func (t *btree) emits(n *indexNode) bool { return n.leaf || t.interiorData }
+
+// comparePrefix compara os len(k) primeiros bytes de key com a chave de
+// busca k.
+func (t *btree) comparePrefix(key, k []byte) int {
+	if len(k) < len(key) {
+		key = key[:len(k)]
+	}
+	return t.compare(key, k)
+}
+
+// hasPrefix informa se a chave key começa pela chave de busca k.
+func (t *btree) hasPrefix(key, k []byte) bool { return t.comparePrefix(key, k) == 0 }
+
+func (t *btree) scan(desc bool) *IndexCursor {
+	c := &IndexCursor{tree: t, desc: desc}
+	root, err := t.load(t.root)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	start := 0
+	if desc {
+		start = len(root.keys)
+	}
+	c.stack = []cursorFrame{{n: root, i: start}}
+	return c
+}
+
+// seek posiciona um cursor crescente na primeira chave >= k (comparando
+// apenas o prefixo len(k) das chaves).
+func (t *btree) seek(k []byte) *IndexCursor {
+	c := &IndexCursor{tree: t}
+	off := t.root
+	for off != 0 {
+		n, err := t.load(off)
+		if err != nil {
+			c.err = err
+			return c
+		}
+		i := 0
+		for i < len(n.keys) && t.comparePrefix(n.keys[i], k) < 0 {
+			i++
+		}
+		c.stack = append(c.stack, cursorFrame{n: n, i: i, down: true})
+		off = n.child(i)
+	}
+	return c
+}
+
+// IndexCursor percorre as chaves de uma tag. Use como Next de DBF:
+//
+//	for c.Next() {
+//		fmt.Println(c.Recno(), c.Key())
+//	}
+//	if err := c.Err(); err != nil { ... }
+type IndexCursor struct {
+	tree    *btree
+	desc    bool
+	stack   []cursorFrame
+	started bool
+	stop    []byte // limite superior de ScanRange
+	key     []byte
+	recno   uint32
+	err     error
+}
+
+// cursorFrame é a posição em um nó: i é o filho (e a chave à sua direita,
+// em ordem decrescente, ou à sua esquerda, em ordem crescente) da vez;
+// down indica que o filho i já foi visitado.
+type cursorFrame struct {
+	n    *indexNode
+	i    int
+	down bool
+}
+
+// Next avança para a próxima chave. Devolve false no fim ou em erro.
+func (c *IndexCursor) Next() bool {
+	c.key, c.recno = nil, 0
+	if c.err != nil || len(c.stack) == 0 {
+		return false
+	}
+	if c.started {
+		f := &c.stack[len(c.stack)-1]
+		f.i += c.step()
+		f.down = false
+	}
+	c.started = true
+
+	for len(c.stack) > 0 {
+		f := &c.stack[len(c.stack)-1]
+		if !f.down {
+			f.down = true
+			if off := f.n.child(f.i); off != 0 {
+				n, err := c.tree.load(off)
+				if err != nil {
+					c.err = err
+					c.stack = nil
+					return false
+				}
+				start := 0
+				if c.desc {
+					start = len(n.keys)
+				}
+				c.stack = append(c.stack, cursorFrame{n: n, i: start})
+				continue
+			}
+		}
+		k := f.i
+		if c.desc {
+			k--
+		}
+		if k >= 0 && k < len(f.n.keys) {
+			if c.tree.emits(f.n) {
+				if c.stop != nil && c.tree.comparePrefix(f.n.keys[k], c.stop) > 0 {
+					c.stack = nil
+					return false
+				}
+				c.key, c.recno = f.n.keys[k], f.n.recnos[k]
+				return true
+			}
+			f.i += c.step()
+			f.down = false
+			continue
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+func (c *IndexCursor) step() int {
+	if c.desc {
+		return -1
+	}
+	return 1
+}
+
+// Recno devolve o número do registro (base 1) da chave atual.
+func (c *IndexCursor) Recno() uint32 { return c.recno }
+
+// Key devolve a chave atual no formato bruto do índice.
+func (c *IndexCursor) Key() []byte { return c.key }
+
+// Err devolve o erro que interrompeu Next, se houver.
+func (c *IndexCursor) Err() error { return c.err }
+
+// --------------------------- Chaves ---------------------------
+
+// keyTypeFor deduz o tipo da chave pelo tipo do resultado da expressão
+// ('I' para um campo inteiro isolado); expressões que não compilam são
+// tratadas como caractere.
+func keyTypeFor(fields []Field, src string) byte {
+	if i := fieldIndex(fields, strings.TrimSpace(src)); i >= 0 && fields[i].Type == 'I' {
+		return 'I'
+	}
+	e, err := expr.Compile(src, exprFields(fields))
+	if err != nil {
+		return 'C'
+	}
+	switch t := e.Type(); t {
+	case 'N', 'D', 'T':
+		return t
+	}
+	return 'C'
+}
+
+// textKey converte uma string de busca para os bytes da página de códigos
+// da tabela.
+func textKey(v any, enc encoding.Encoding) ([]byte, error) {
+	switch k := v.(type) {
+	case string:
+		return encodeBytes(k, enc)
+	case []byte:
+		return k, nil
+	}
+	return nil, fmt.Errorf("chave de índice: esperado string, recebido %T", v)
+}
+
+// julianFloat devolve a data (e a fração do dia) como dia juliano.
+func julianFloat(t time.Time, withTime bool) float64 {
+	jd, ms := utcToVFPDateTime(t)
+	if !withTime {
+		return float64(jd)
+	}
+	return float64(jd) + float64(ms)/86400000
+}
+
+// keyCodec devolve a codificação de chave de texto da tabela: a do campo,
+// quando a expressão é um único campo, ou a página de códigos padrão.
+func (d *DBF) keyCodec(expr string) encoding.Encoding {
+	if i := fieldIndex(d.Fields, strings.TrimSpace(expr)); i >= 0 {
+		return d.fieldCodec(i)
+	}
+	enc, err := d.opt.resolveCodec("")
+	if err != nil {
+		return nil
+	}
+	return enc
+}
+
+func trimKey(b []byte) string {
+	return string(bytes.TrimRight(b, " \x00"))
+}
@@ -0,0 +1,475 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alberto255345/dbfmini/expr"
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- Criação e manutenção de índices ---------------------------
+
+// IndexDef descreve uma tag de índice, como INDEX ON Expr TAG Tag FOR For
+// [UNIQUE] [DESCENDING] no xBase.
+type IndexDef struct {
+	Tag        string
+	Expr       string
+	For        string // opcional
+	Unique     bool
+	Descending bool
+}
+
+// indexKey calcula a chave e o filtro de uma tag a partir de um registro.
+type indexKey struct {
+	keyType byte
+	keyLen  int
+	key     func(Record) ([]byte, error)
+	filter  func(Record) (bool, error) // nil: todos os registros
+}
+
+// compileIndexKey compila a expressão e o filtro FOR de def. Chaves de
+// texto são convertidas com enc e completadas com espaços até o tamanho
+// da expressão; números e datas viram doubles ordenáveis e um campo I
+// isolado, um inteiro de 4 bytes, como no VFP. Um resultado .NULL. vira a
+// chave de bytes zero, que ordena antes de qualquer valor, como no FoxPro.
+func compileIndexKey(fields []Field, enc encoding.Encoding, def IndexDef) (*indexKey, error) {
+	if strings.TrimSpace(def.Tag) == "" {
+		return nil, errors.New("nome de tag obrigatório")
+	}
+	if len(def.Tag) > 10 {
+		return nil, fmt.Errorf("nome de tag com mais de 10 caracteres: %s", def.Tag)
+	}
+	schema := exprFields(fields)
+	e, err := expr.Compile(def.Expr, schema)
+	if err != nil {
+		return nil, fmt.Errorf("chave %q: %w", def.Expr, err)
+	}
+	k := &indexKey{}
+	if strings.TrimSpace(def.For) != "" {
+		f, err := expr.Compile(def.For, schema)
+		if err != nil {
+			return nil, fmt.Errorf("filtro %q: %w", def.For, err)
+		}
+		if f.Type() != 'L' {
+			return nil, fmt.Errorf("filtro %q não é lógico", def.For)
+		}
+		k.filter = func(rec Record) (bool, error) { return f.Bool(rec) }
+	}
+	eval := func(rec Record) (any, error) { return e.Eval(rec) }
+
+	if i := lookupField(fields, def.Expr); i >= 0 && fields[i].Type == 'I' {
+		k.keyType, k.keyLen = 'I', 4
+		k.key = func(rec Record) ([]byte, error) {
+			v, err := eval(rec)
+			if err != nil {
+				return nil, err
+			}
+			switch x := v.(type) {
+			case float64:
+				return cdxInt(int32(x)), nil
+			case nil:
+				return make([]byte, 4), nil
+			}
+			return nil, fmt.Errorf("chave %q: valor inesperado %T", def.Expr, v)
+		}
+		return k, nil
+	}
+
+	switch t := e.Type(); t {
+	case 'C', 'L':
+		k.keyType, k.keyLen = 'C', e.Width()
+		if k.keyLen <= 0 || k.keyLen > 240 {
+			return nil, fmt.Errorf("chave %q: tamanho inválido (%d)", def.Expr, k.keyLen)
+		}
+		k.key = func(rec Record) ([]byte, error) {
+			v, err := eval(rec)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return make([]byte, k.keyLen), nil
+			}
+			return textIndexKey(v, k.keyLen, enc)
+		}
+	case 'N', 'D', 'T':
+		k.keyType, k.keyLen = t, 8
+		k.key = func(rec Record) ([]byte, error) {
+			v, err := eval(rec)
+			if err != nil {
+				return nil, err
+			}
+			switch x := v.(type) {
+			case float64:
+				return cdxNumber(x), nil
+			case time.Time:
+				if x.IsZero() {
+					return cdxNumber(0), nil
+				}
+				return cdxNumber(julianFloat(x, t == 'T')), nil
+			case nil:
+				return make([]byte, 8), nil
+			}
+			return nil, fmt.Errorf("chave %q: valor inesperado %T", def.Expr, v)
+		}
+	default:
+		return nil, fmt.Errorf("chave %q: tipo do resultado indeterminado", def.Expr)
+	}
+	return k, nil
+}
+
+// textIndexKey converte o resultado de uma chave de texto (ou lógica) para
+// width bytes na página de códigos da tabela.
+func textIndexKey(v any, width int, enc encoding.Encoding) ([]byte, error) {
+	out := make([]byte, width)
+	fillSpaces(out)
+	switch x := v.(type) {
+	case string:
+		b, err := encodeBytes(x, enc)
+		if err != nil {
+			return nil, err
+		}
+		copy(out, b)
+	case bool:
+		out[0] = 'F'
+		if x {
+			out[0] = 'T'
+		}
+	default:
+		return nil, fmt.Errorf("valor inesperado %T em chave de texto", v)
+	}
+	return out, nil
+}
+
+// lookupField procura o campo name sem diferenciar maiúsculas.
+func lookupField(fields []Field, name string) int {
+	name = strings.TrimSpace(name)
+	for i, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// --------------------------- Writer ---------------------------
+
+// AddIndex inclui uma tag no índice estrutural (.cdx de mesmo nome) que
+// Close grava ao final, já com todos os registros.
+func (w *Writer) AddIndex(def IndexDef) error {
+	if w.closed {
+		return errors.New("writer fechado")
+	}
+	if _, err := compileIndexKey(w.fields, w.enc, def); err != nil {
+		return fmt.Errorf("tag %s: %w", def.Tag, err)
+	}
+	w.indexes = setIndexDef(w.indexes, def)
+	return nil
+}
+
+func (w *Writer) fieldCodec(int) encoding.Encoding { return w.enc }
+
+// writeIndex grava o .cdx estrutural das tags de AddIndex.
+func (w *Writer) writeIndex() error {
+	opts := OpenOptions{Encoding: Encoding{Codec: w.enc}}
+	_, err := rebuildCDX(w.path, opts, w.indexes, structuralPath(w.path))
+	return err
+}
+
+// --------------------------- Atualização ---------------------------
+
+// CreateIndex cria (ou substitui) a tag def no índice estrutural da tabela
+// aberta por OpenForUpdate e marca o índice de produção no header. O
+// índice é reconstruído na hora e, daí em diante, mantido a cada Append,
+// UpdateRecord, Delete e Recall: as chaves do registro alterado são
+// trocadas em memória e o .cdx é regravado antes de a chamada retornar.
+func (d *DBF) CreateIndex(def IndexDef) error {
+	if d.rw == nil {
+		return ErrReadOnly
+	}
+	if _, err := compileIndexKey(d.Fields, d.keyCodec(def.Expr), def); err != nil {
+		return fmt.Errorf("tag %s: %w", def.Tag, err)
+	}
+	if d.indexPath == "" {
+		d.indexPath = structuralPath(d.Path)
+	}
+	d.indexes = setIndexDef(d.indexes, def)
+	if d.flags&0x01 == 0 {
+		if _, err := d.rw.WriteAt([]byte{d.flags | 0x01}, 28); err != nil {
+			return fmt.Errorf("atualizando header: %w", err)
+		}
+		d.flags |= 0x01
+	}
+	return d.Reindex()
+}
+
+// Reindex reconstrói o índice estrutural a partir dos registros atuais
+// (lendo a tabela inteira).
+func (d *DBF) Reindex() error {
+	if d.rw == nil {
+		return ErrReadOnly
+	}
+	if len(d.indexes) == 0 {
+		return nil
+	}
+	tags, err := rebuildCDX(d.Path, d.opt, d.indexes, d.indexPath)
+	if err != nil {
+		return fmt.Errorf("reindexando %s: %w", d.indexPath, err)
+	}
+	d.tags = tags
+	return nil
+}
+
+// loadStructuralIndex lê as tags do .cdx estrutural, se o header o
+// indicar, para mantê-lo nas alterações. Tags com expressões que não
+// sabemos calcular impedem a atualização: gravar sem elas deixaria o
+// índice desatualizado.
+func (d *DBF) loadStructuralIndex() error {
+	if !d.HasProductionIndex() {
+		return nil
+	}
+	open, path := findSibling(d.Path, d.sibling, ".cdx", ".CDX")
+	if open == nil {
+		return nil
+	}
+	idx, err := d.openIndex(open, path)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+	var defs []IndexDef
+	for _, t := range idx.Tags {
+		def := IndexDef{Tag: t.Name, Expr: t.Expr, For: t.For, Unique: t.Unique, Descending: t.Descending}
+		if _, err := compileIndexKey(d.Fields, d.keyCodec(def.Expr), def); err != nil {
+			return fmt.Errorf("%s: tag %s: %w", path, t.Name, err)
+		}
+		defs = append(defs, def)
+	}
+	d.indexes, d.indexPath = defs, path
+	return nil
+}
+
+// updateIndex troca as chaves do registro recno (base 1), cujo conteúdo
+// atual é buf, em todas as tags e regrava o .cdx. Na primeira alteração da
+// sessão as chaves são carregadas da tabela.
+func (d *DBF) updateIndex(recno uint32, buf []byte) error {
+	if len(d.indexes) == 0 {
+		return nil
+	}
+	if d.tags == nil {
+		return d.Reindex() // a tabela já contém a alteração
+	}
+	release, err := d.attachMemo()
+	if err != nil {
+		return err
+	}
+	rec, _, err := d.decodeRecord(buf, true)
+	release()
+	if err != nil {
+		return fmt.Errorf("registro %d: %w", recno, err)
+	}
+	rec["_recno"] = recno
+	for i := range d.tags {
+		t := &d.tags[i]
+		t.data.entries = removeRecno(t.data.entries, recno)
+		key, ok, err := t.key.eval(rec)
+		if err != nil {
+			return fmt.Errorf("tag %s: registro %d: %w", t.data.def.Tag, recno, err)
+		}
+		if ok {
+			t.data.entries = insertEntry(t.data.entries, cdxEntry{key: key, recno: recno})
+		}
+	}
+	if err := writeCDX(d.indexPath, d.tags); err != nil {
+		return fmt.Errorf("gravando %s: %w", d.indexPath, err)
+	}
+	return nil
+}
+
+// Append grava rec como novo registro no fim da tabela aberta por
+// OpenForUpdate. Campos ausentes ficam vazios (memo sem conteúdo); valores
+// para memo, V e Q ainda não são suportados na escrita.
+func (d *DBF) Append(rec Record) error {
+	if d.rw == nil {
+		return ErrReadOnly
+	}
+	buf := make([]byte, int(d.recordLen)+1)
+	if err := encodeRecord(buf[:d.recordLen], d.Fields, d.offsets, rec, d.fieldCodec); err != nil {
+		return err
+	}
+	for i, f := range d.Fields {
+		// campo ausente fica vazio, como no APPEND BLANK; só nil explícito
+		// grava .NULL.
+		v, ok := rec[f.Name]
+		d.setNull(buf, i, ok && v == nil)
+		if isVarType(f.Type) {
+			d.setVarLen(buf, i)
+		}
+	}
+	buf[d.recordLen] = 0x1A
+	if _, err := d.rw.WriteAt(buf, d.recordOffset(int(d.RecordCount))); err != nil {
+		return fmt.Errorf("gravando registro %d: %w", d.RecordCount, err)
+	}
+	cnt := make([]byte, 4)
+	binary.LittleEndian.PutUint32(cnt, d.RecordCount+1)
+	if _, err := d.rw.WriteAt(cnt, 4); err != nil {
+		return fmt.Errorf("atualizando header: %w", err)
+	}
+	d.RecordCount++
+	if err := d.touch(); err != nil {
+		return err
+	}
+	return d.updateIndex(d.RecordCount, buf[:d.recordLen])
+}
+
+// --------------------------- Montagem do .CDX ---------------------------
+
+// indexTag é uma tag mantida em memória: todas as chaves que passam no
+// filtro, inclusive as repetidas de uma tag UNIQUE, ordenadas por chave e
+// registro.
+type indexTag struct {
+	key  *indexKey
+	data cdxTagData
+}
+
+// eval devolve a chave do registro e se ele entra na tag (filtro FOR).
+func (k *indexKey) eval(rec Record) ([]byte, bool, error) {
+	if k.filter != nil {
+		ok, err := k.filter(rec)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	key, err := k.key(rec)
+	return key, err == nil, err
+}
+
+// rebuildCDX lê todos os registros de table (inclusive excluídos), monta
+// as tags defs e as grava em path.
+func rebuildCDX(table string, opts OpenOptions, defs []IndexDef, path string) ([]indexTag, error) {
+	opts.IncludeDeleted = true
+	opts.IncludeRecno = true // para RECNO() nas expressões
+	db, err := Open(table, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tags := make([]indexTag, len(defs))
+	for i, def := range defs {
+		k, err := compileIndexKey(db.Fields, db.keyCodec(def.Expr), def)
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", def.Tag, err)
+		}
+		tags[i] = indexTag{key: k, data: cdxTagData{def: def, keyLen: k.keyLen, fill: ' '}}
+		if k.keyType != 'C' {
+			tags[i].data.fill = 0
+		}
+	}
+	for db.Next() {
+		rec := db.Record()
+		for i := range tags {
+			t := &tags[i]
+			key, ok, err := t.key.eval(rec)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: registro %d: %w", t.data.def.Tag, db.Recno(), err)
+			}
+			if ok {
+				t.data.entries = append(t.data.entries, cdxEntry{key: key, recno: db.Recno()})
+			}
+		}
+	}
+	if err := db.Err(); err != nil {
+		return nil, err
+	}
+	for i := range tags {
+		es := tags[i].data.entries
+		sort.SliceStable(es, func(a, b int) bool { return bytes.Compare(es[a].key, es[b].key) < 0 })
+	}
+	return tags, writeCDX(path, tags)
+}
+
+// writeCDX grava as tags em path. O arquivo é montado em um temporário e
+// renomeado sobre path ao final. Como no xBase, uma tag UNIQUE guarda só o
+// primeiro registro de cada chave.
+func writeCDX(path string, tags []indexTag) error {
+	data := make([]cdxTagData, len(tags))
+	for i, t := range tags {
+		data[i] = t.data
+		if t.data.def.Unique {
+			var out []cdxEntry
+			for j, e := range t.data.entries {
+				if j == 0 || !bytes.Equal(e.key, out[len(out)-1].key) {
+					out = append(out, e)
+				}
+			}
+			data[i].entries = out
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".idx-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // sem efeito após o rename
+	_, err = tmp.Write(encodeCDX(data))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return replaceFile(tmp.Name(), path)
+}
+
+// insertEntry insere e na posição dada por chave e número de registro.
+func insertEntry(es []cdxEntry, e cdxEntry) []cdxEntry {
+	i := sort.Search(len(es), func(j int) bool {
+		c := bytes.Compare(es[j].key, e.key)
+		return c > 0 || c == 0 && es[j].recno > e.recno
+	})
+	return slices.Insert(es, i, e)
+}
+
+// removeRecno retira a chave do registro recno, se houver.
+func removeRecno(es []cdxEntry, recno uint32) []cdxEntry {
+	for i, e := range es {
+		if e.recno == recno {
+			return slices.Delete(es, i, i+1)
+		}
+	}
+	return es
+}
+
+// setIndexDef inclui def em defs, substituindo uma tag de mesmo nome.
+func setIndexDef(defs []IndexDef, def IndexDef) []IndexDef {
+	for i, ex := range defs {
+		if strings.EqualFold(ex.Tag, def.Tag) {
+			defs[i] = def
+			return defs
+		}
+	}
+	return append(defs, def)
+}
+
+// structuralPath devolve o caminho do .cdx de mesmo nome da tabela,
+// mantendo a caixa da extensão.
+func structuralPath(table string) string {
+	ext := filepath.Ext(table)
+	cdx := ".cdx"
+	if ext != "" && ext == strings.ToUpper(ext) {
+		cdx = ".CDX"
+	}
+	return strings.TrimSuffix(table, ext) + cdx
+}
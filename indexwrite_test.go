@@ -0,0 +1,275 @@
+package dbfmini
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// scanRecnos devolve os registros da tag na ordem do índice.
+func scanRecnos(t *testing.T, idx *Index, tag string) []uint32 {
+	t.Helper()
+	c, err := idx.Scan(tag)
+	if err != nil {
+		t.Fatalf("Scan(%s) returned error: %v", tag, err)
+	}
+	var out []uint32
+	for c.Next() {
+		out = append(out, c.Recno())
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("cursor error: %v", err)
+	}
+	return out
+}
+
+func expectRecnos(t *testing.T, got []uint32, want ...uint32) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func openProduction(t *testing.T, path string) (*DBF, *Index) {
+	t.Helper()
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if !db.HasProductionIndex() {
+		t.Fatalf("expected production index flag")
+	}
+	idx, err := db.OpenProductionIndex()
+	if err != nil {
+		t.Fatalf("OpenProductionIndex returned error: %v", err)
+	}
+	t.Cleanup(func() { idx.Close() })
+	return db, idx
+}
+
+func TestWriterAddIndexBuildsCDX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	w, err := Create(path, peopleFields, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	for _, def := range []IndexDef{
+		{Tag: "NAME", Expr: "UPPER(NAME)"},
+		{Tag: "AGE", Expr: "AGE", For: "!DELETED()", Descending: true},
+		{Tag: "UAGE", Expr: "AGE", Unique: true},
+		{Tag: "AGENAME", Expr: "STR(AGE, 3) + UPPER(NAME)", For: "AGE >= 10 .AND. !DELETED()"},
+	} {
+		if err := w.AddIndex(def); err != nil {
+			t.Fatalf("AddIndex(%s) returned error: %v", def.Tag, err)
+		}
+	}
+	if err := w.AddIndex(IndexDef{Tag: "BAD", Expr: "AGE + NAME"}); err == nil {
+		t.Fatalf("AddIndex with unsupported expression should fail")
+	}
+	// o suficiente para várias folhas e um nível interno
+	for i := 0; i < 300; i++ {
+		rec := Record{"NAME": fmt.Sprintf("p%03d", 299-i), "AGE": i % 50}
+		if i == 7 {
+			rec["_deleted"] = true
+		}
+		if err := w.Append(rec); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	_, idx := openProduction(t, path)
+	if len(idx.Tags) != 4 {
+		t.Fatalf("expected 4 tags, got %d", len(idx.Tags))
+	}
+	age, _ := idx.Tag("AGE")
+	if age.For != "!DELETED()" || !age.Descending || age.KeyLen != 8 {
+		t.Fatalf("unexpected AGE tag: %+v", age)
+	}
+
+	names := scanRecnos(t, idx, "NAME")
+	if len(names) != 300 || names[0] != 300 || names[299] != 1 {
+		t.Fatalf("unexpected NAME order: %v", names)
+	}
+	if recno, ok, err := idx.Seek("NAME", "P150"); err != nil || !ok || recno != 150 {
+		t.Fatalf("Seek(NAME, P150): expected 150, got %d/%v/%v", recno, ok, err)
+	}
+	ages := scanRecnos(t, idx, "AGE")
+	if len(ages) != 299 || ages[0] != 300 {
+		t.Fatalf("unexpected AGE order: %v", ages[:5])
+	}
+	if recno, ok, err := idx.Seek("AGE", 7); err != nil || !ok || recno != 58 {
+		t.Fatalf("Seek(AGE, 7) should skip the deleted record, got %d/%v/%v", recno, ok, err)
+	}
+	if recno, ok, err := idx.Seek("AGENAME", " 12P"); err != nil || !ok || recno != 263 {
+		t.Fatalf("Seek(AGENAME, ' 12P'): expected 263, got %d/%v/%v", recno, ok, err)
+	}
+	if got := scanRecnos(t, idx, "AGENAME"); len(got) != 240 || got[0] != 261 {
+		t.Fatalf("unexpected AGENAME order: %d keys starting at %v", len(got), got[:1])
+	}
+	if got := scanRecnos(t, idx, "UAGE"); len(got) != 50 || got[0] != 1 || got[49] != 50 {
+		t.Fatalf("unexpected UAGE order: %v", got)
+	}
+}
+
+func TestUpdateMaintainsStructuralCDX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41},
+		Record{"NAME": "Carla", "AGE": 25},
+	)
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.CreateIndex(IndexDef{Tag: "AGE", Expr: "AGE", For: "NOT DELETED()"}); err != nil {
+		t.Fatalf("CreateIndex returned error: %v", err)
+	}
+	if err := db.CreateIndex(IndexDef{Tag: "NAME", Expr: "NAME"}); err != nil {
+		t.Fatalf("CreateIndex returned error: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	_, idx := openProduction(t, path)
+	expectRecnos(t, scanRecnos(t, idx, "AGE"), 3, 1, 2)
+	idx.Close()
+
+	db, err = OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.Append(Record{"NAME": "Davi", "AGE": 19}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	if err := db.UpdateRecord(0, Record{"NAME": "Zoe", "AGE": 50}); err != nil {
+		t.Fatalf("UpdateRecord returned error: %v", err)
+	}
+	if err := db.Delete(1); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, idx = openProduction(t, path)
+	if db.RecordCount != 4 {
+		t.Fatalf("expected 4 records, got %d", db.RecordCount)
+	}
+	expectRecnos(t, scanRecnos(t, idx, "AGE"), 4, 3, 1)
+	expectRecnos(t, scanRecnos(t, idx, "NAME"), 2, 3, 4, 1)
+	if recno, ok, _ := idx.Seek("NAME", "Davi"); !ok || recno != 4 {
+		t.Fatalf("Seek(NAME, Davi): expected 4, got %d/%v", recno, ok)
+	}
+	idx.Close()
+
+	if err := Pack(path, nil); err != nil {
+		t.Fatalf("Pack returned error: %v", err)
+	}
+	_, idx = openProduction(t, path)
+	expectRecnos(t, scanRecnos(t, idx, "NAME"), 2, 3, 1)
+}
+
+func TestOpenForUpdateRejectsUnsupportedCDX(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "people.dbf")
+	writePeople(t, path, Record{"NAME": "Ana", "AGE": 30})
+	writeFixture(t, dir, "people.cdx", buildCDX([]cdxTestTag{
+		{name: "AGESTR", expr: "KEYOF(AGE)", keyLen: 3, fill: ' ',
+			keys: [][]byte{[]byte(" 30")}, recnos: []uint32{1}},
+	}, 3))
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read table: %v", err)
+	}
+	raw[28] = 0x01
+	writeFixture(t, dir, "people.dbf", raw)
+
+	if db, err := OpenForUpdate(path, nil); err == nil {
+		db.Close()
+		t.Fatalf("expected error for index that cannot be maintained")
+	}
+}
+
+func TestCreateIndexNullKeys(t *testing.T) {
+	fields := []Field{
+		{Name: "NOME", Type: 'C', Size: 6, Flags: FieldNullable},
+		{Name: "QTD", Type: 'I', Size: 4, Flags: FieldNullable},
+		{Name: "_NullFlags", Type: '0', Size: 1, Flags: FieldSystem | FieldBinary},
+	}
+	path := vfpFixture(t, fields,
+		[]byte(" Bia   \x05\x00\x00\x00\x00"),
+		[]byte("       \x00\x00\x00\x00\x02"),
+		[]byte("       \xfd\xff\xff\xff\x01"),
+	)
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	for _, def := range []IndexDef{{Tag: "QTD", Expr: "QTD"}, {Tag: "NOME", Expr: "NOME"}} {
+		if err := db.CreateIndex(def); err != nil {
+			t.Fatalf("CreateIndex(%s) returned error: %v", def.Tag, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// .NULL. ordena antes de qualquer valor, inclusive do vazio
+	_, idx := openProduction(t, path)
+	expectRecnos(t, scanRecnos(t, idx, "QTD"), 2, 3, 1)
+	expectRecnos(t, scanRecnos(t, idx, "NOME"), 3, 2, 1)
+}
+
+func TestUpdateKeepsCDXCurrentBeforeClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 30},
+	)
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	defer db.Close()
+	for _, def := range []IndexDef{
+		{Tag: "AGE", Expr: "AGE", For: "!DELETED()"},
+		{Tag: "UAGE", Expr: "AGE", Unique: true},
+	} {
+		if err := db.CreateIndex(def); err != nil {
+			t.Fatalf("CreateIndex(%s) returned error: %v", def.Tag, err)
+		}
+	}
+
+	// o .cdx em disco reflete cada chamada, sem esperar Close
+	check := func(tag string, want ...uint32) {
+		t.Helper()
+		_, idx := openProduction(t, path)
+		expectRecnos(t, scanRecnos(t, idx, tag), want...)
+		idx.Close()
+	}
+	if err := db.Append(Record{"NAME": "Carla", "AGE": 19}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	check("AGE", 3, 1, 2)
+	check("UAGE", 3, 1)
+	if err := db.UpdateRecord(0, Record{"AGE": 50}); err != nil {
+		t.Fatalf("UpdateRecord returned error: %v", err)
+	}
+	check("AGE", 3, 2, 1)
+	check("UAGE", 3, 2, 1)
+	if err := db.Delete(1); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	check("AGE", 3, 1)
+	if err := db.Recall(1); err != nil {
+		t.Fatalf("Recall returned error: %v", err)
+	}
+	check("AGE", 3, 2, 1)
+}
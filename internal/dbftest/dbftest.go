@@ -0,0 +1,32 @@
+// Package dbftest reúne o que os testes dos subpacotes usam para montar
+// tabelas DBF de exemplo.
+package dbftest
+
+import (
+	"testing"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// Table cria path com fields, grava rows e as tags de índice estrutural em
+// indexes e fecha o arquivo. Qualquer erro encerra o teste.
+func Table(t testing.TB, path string, fields []dbfmini.Field, opts *dbfmini.CreateOptions, rows []dbfmini.Record, indexes ...dbfmini.IndexDef) {
+	t.Helper()
+	w, err := dbfmini.Create(path, fields, opts)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	for _, def := range indexes {
+		if err := w.AddIndex(def); err != nil {
+			t.Fatalf("AddIndex(%s) returned error: %v", def.Tag, err)
+		}
+	}
+	for _, r := range rows {
+		if err := w.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build go1.23
+
+package dbfmini
+
+import "iter"
+
+// Records devolve um iterador sobre os registros restantes, a partir do
+// cursor atual, com o índice físico (base 0) de cada um. Usa o mesmo
+// mecanismo de Next: erros ficam disponíveis em Err após o laço.
+//
+//	for i, rec := range db.Records() {
+//		...
+//	}
+func (d *DBF) Records() iter.Seq2[int, Record] {
+	return func(yield func(int, Record) bool) {
+		for d.Next() {
+			if !yield(int(d.recordsRead)-1, d.Record()) {
+				return
+			}
+		}
+	}
+}
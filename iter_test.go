@@ -0,0 +1,37 @@
+//go:build go1.23
+
+package dbfmini
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordsIterator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41, "_deleted": true},
+		Record{"NAME": "Carla", "AGE": 25},
+	)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	var idx []int
+	for i, rec := range db.Records() {
+		idx = append(idx, i)
+		if i == 2 && rec["NAME"] != "Carla" {
+			t.Fatalf("record 2 = %#v, want Carla", rec)
+		}
+	}
+	if err := db.Err(); err != nil {
+		t.Fatalf("Err returned %v", err)
+	}
+	if len(idx) != 2 || idx[0] != 0 || idx[1] != 2 {
+		t.Fatalf("indexes = %v, want [0 2]", idx)
+	}
+}
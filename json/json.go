@@ -0,0 +1,237 @@
+// Package json grava registros DBF como JSON, em NDJSON (um objeto por
+// linha) ou em um array, mantendo a ordem das colunas da tabela e os tipos
+// do xBase: datas D como "AAAA-MM-DD", datas e horas T em RFC 3339 (UTC) e
+// números N, F e Y com as casas decimais do campo, como literais ou como
+// strings.
+//
+//	db, _ := dbfmini.Open("clientes.dbf", nil)
+//	n, err := json.Export(os.Stdout, db, &json.Options{Recno: true})
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// Format é o formato da saída.
+type Format int
+
+const (
+	// NDJSON grava um objeto por linha (padrão).
+	NDJSON Format = iota
+	// Array grava um único array JSON, um objeto por linha.
+	Array
+)
+
+// Options controla a gravação.
+type Options struct {
+	Format Format
+	// NumbersAsStrings grava N, F e Y como strings ("1234.50"), para
+	// consumidores que convertem números JSON em float64. Por padrão são
+	// literais com as casas decimais do campo (1234.50).
+	NumbersAsStrings bool
+	// Recno inclui "_recno" (base 1). Em Encode, vem de rec["_recno"]
+	// (OpenOptions.IncludeRecno).
+	Recno bool
+	// Deleted inclui "_deleted" (true/false). Sem ele, os registros
+	// excluídos são omitidos; eles só chegam ao Encoder se a tabela for
+	// aberta com OpenOptions.IncludeDeleted.
+	Deleted bool
+}
+
+// Encoder grava registros de uma tabela como objetos JSON, na ordem de
+// fields.
+type Encoder struct {
+	w      io.Writer
+	fields []dbfmini.Field
+	opt    Options
+	buf    []byte
+	str    bytes.Buffer
+	enc    *stdjson.Encoder
+	count  int
+	closed bool
+}
+
+// NewEncoder cria um Encoder para registros com os campos fields
+// (normalmente db.Fields).
+func NewEncoder(w io.Writer, fields []dbfmini.Field, opts *Options) *Encoder {
+	e := &Encoder{w: w, fields: fields}
+	if opts != nil {
+		e.opt = *opts
+	}
+	e.enc = stdjson.NewEncoder(&e.str)
+	e.enc.SetEscapeHTML(false)
+	return e
+}
+
+// Encode grava rec, ou nada se ele estiver excluído e Deleted for falso.
+func (e *Encoder) Encode(rec dbfmini.Record) error {
+	if e.closed {
+		return errors.New("encoder fechado")
+	}
+	deleted := rec["_deleted"] == true
+	if deleted && !e.opt.Deleted {
+		return nil
+	}
+	b := e.buf[:0]
+	if e.opt.Format == Array {
+		if e.count == 0 {
+			b = append(b, "[\n"...)
+		} else {
+			b = append(b, ",\n"...)
+		}
+	}
+	b = append(b, '{')
+	n := 0
+	key := func(name string) {
+		if n > 0 {
+			b = append(b, ',')
+		}
+		b = e.appendString(b, name)
+		b = append(b, ':')
+		n++
+	}
+	if e.opt.Recno {
+		key("_recno")
+		if r, ok := rec["_recno"].(uint32); ok {
+			b = strconv.AppendUint(b, uint64(r), 10)
+		} else {
+			b = append(b, "null"...)
+		}
+	}
+	for _, f := range e.fields {
+		key(f.Name)
+		var err error
+		if b, err = e.appendValue(b, f, rec[f.Name]); err != nil {
+			return err
+		}
+	}
+	if e.opt.Deleted {
+		key("_deleted")
+		b = strconv.AppendBool(b, deleted)
+	}
+	b = append(b, '}')
+	if e.opt.Format == NDJSON {
+		b = append(b, '\n')
+	}
+	e.buf = b
+	if _, err := e.w.Write(b); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close termina a saída (fecha o array em Format Array; em NDJSON não
+// grava nada). Chamadas repetidas não gravam de novo.
+func (e *Encoder) Close() error {
+	if e.closed || e.opt.Format != Array {
+		e.closed = true
+		return nil
+	}
+	e.closed = true
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// Export grava todos os registros de db em w e devolve quantos foram
+// gravados. Com Options.Recno, "_recno" vem do número físico do registro,
+// mesmo que db não tenha sido aberto com IncludeRecno.
+func Export(w io.Writer, db *dbfmini.DBF, opts *Options) (int, error) {
+	e := NewEncoder(w, db.Fields, opts)
+	rows, err := db.Query().Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rec := rows.Record()
+		if e.opt.Recno {
+			rec["_recno"] = rows.Recno()
+		}
+		if err := e.Encode(rec); err != nil {
+			return e.count, fmt.Errorf("registro %d: %w", rows.Recno(), err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return e.count, err
+	}
+	return e.count, e.Close()
+}
+
+// --------------------------- Valores ---------------------------
+
+// appendValue grava o valor decodificado de f.
+func (e *Encoder) appendValue(b []byte, f dbfmini.Field, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case string:
+		return e.appendString(b, v), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int64: // campo N sem casas decimais (NumericDecimal)
+		if !e.opt.NumbersAsStrings {
+			return strconv.AppendInt(b, v, 10), nil
+		}
+		b = append(b, '"')
+		b = strconv.AppendInt(b, v, 10)
+		return append(b, '"'), nil
+	case dbfmini.Decimal:
+		if !e.opt.NumbersAsStrings {
+			return append(b, v.String()...), nil
+		}
+		return append(append(append(b, '"'), v.String()...), '"'), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return b, fmt.Errorf("%s: valor %v não representável em JSON", f.Name, v)
+		}
+		prec := -1
+		switch f.Type {
+		case 'N', 'F':
+			prec = int(f.DecimalPlaces)
+		case 'Y':
+			prec = 4
+		}
+		if prec < 0 || !e.opt.NumbersAsStrings {
+			return strconv.AppendFloat(b, v, 'f', prec, 64), nil
+		}
+		b = append(b, '"')
+		b = strconv.AppendFloat(b, v, 'f', prec, 64)
+		return append(b, '"'), nil
+	case time.Time:
+		b = append(b, '"')
+		if f.Type == 'D' {
+			b = v.AppendFormat(b, "2006-01-02")
+		} else {
+			b = v.UTC().AppendFormat(b, time.RFC3339Nano)
+		}
+		return append(b, '"'), nil
+	}
+	// []byte (G/W) em base64 e demais tipos pelo encoding/json
+	e.str.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		return b, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return append(b, bytes.TrimRight(e.str.Bytes(), "\n")...), nil
+}
+
+func (e *Encoder) appendString(b []byte, s string) []byte {
+	e.str.Reset()
+	e.enc.Encode(s) // strings sempre são codificáveis
+	return append(b, bytes.TrimRight(e.str.Bytes(), "\n")...)
+}
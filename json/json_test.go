@@ -0,0 +1,119 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+	"github.com/alberto255345/dbfmini/internal/dbftest"
+)
+
+func writeTable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clientes.dbf")
+	fields := []dbfmini.Field{
+		{Name: "NOME", Type: 'C', Size: 12},
+		{Name: "SALDO", Type: 'N', Size: 12, DecimalPlaces: 2},
+		{Name: "CURR", Type: 'Y'},
+		{Name: "QTD", Type: 'I'},
+		{Name: "ATIVO", Type: 'L'},
+		{Name: "NASC", Type: 'D'},
+		{Name: "STAMP", Type: 'T'},
+	}
+	dbftest.Table(t, path, fields, nil, []dbfmini.Record{
+		{"NOME": "Zé <\"A\">", "SALDO": 1234567.1, "CURR": 0.1, "QTD": int32(-3), "ATIVO": true,
+			"NASC":  time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC),
+			"STAMP": time.Date(2024, time.January, 2, 10, 30, 15, 0, time.UTC)},
+		{"NOME": "Vazio"},
+		{"NOME": "Excluído", "_deleted": true},
+	})
+	return path
+}
+
+func TestExportNDJSON(t *testing.T) {
+	db, err := dbfmini.Open(writeTable(t), &dbfmini.OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	n, err := Export(&buf, db, nil)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 records, got %d/%v", n, err)
+	}
+	want := `{"NOME":"Zé <\"A\">","SALDO":1234567.10,"CURR":0.1000,"QTD":-3,"ATIVO":true,"NASC":"1990-05-17","STAMP":"2024-01-02T10:30:15Z"}` + "\n" +
+		`{"NOME":"Vazio","SALDO":null,"CURR":0.0000,"QTD":0,"ATIVO":null,"NASC":null,"STAMP":null}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	opts := &Options{NumbersAsStrings: true, Recno: true, Deleted: true}
+	if n, err = Export(&buf, db, opts); err != nil || n != 3 {
+		t.Fatalf("expected 3 records, got %d/%v", n, err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if !bytes.HasPrefix(lines[0], []byte(`{"_recno":1,"NOME":`)) ||
+		!bytes.Contains(lines[0], []byte(`"SALDO":"1234567.10","CURR":"0.1000","QTD":-3`)) ||
+		!bytes.HasSuffix(lines[2], []byte(`"_deleted":true}`)) {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestExportArray(t *testing.T) {
+	db, err := dbfmini.Open(writeTable(t), nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := Export(&buf, db, &Options{Format: Array, Recno: true}); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	var got []map[string]any
+	if err := stdjson.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON array: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 || got[1]["_recno"] != 2.0 || got[0]["NASC"] != "1990-05-17" {
+		t.Fatalf("unexpected array: %v", got)
+	}
+
+	buf.Reset()
+	e := NewEncoder(&buf, db.Fields, &Options{Format: Array})
+	if err := e.Close(); err != nil || buf.String() != "[]\n" {
+		t.Fatalf("expected empty array, got %q/%v", buf.String(), err)
+	}
+	if err := e.Encode(dbfmini.Record{}); err == nil {
+		t.Fatalf("expected error after Close")
+	}
+}
+
+func TestExportNumericDecimal(t *testing.T) {
+	db, err := dbfmini.Open(writeTable(t), &dbfmini.OpenOptions{Numeric: dbfmini.NumericDecimal})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, db.Fields, &Options{NumbersAsStrings: true})
+	rec := dbfmini.Record{"NOME": "X", "SALDO": dbfmini.Decimal{Unscaled: 12345678901234567, Scale: 2},
+		"CURR": dbfmini.Decimal{Unscaled: -1, Scale: 4}, "QTD": int32(1)}
+	if err := e.Encode(rec); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	want := `{"NOME":"X","SALDO":"123456789012345.67","CURR":"-0.0001","QTD":1,"ATIVO":null,"NASC":null,"STAMP":null}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if _, err := Export(&buf, db, nil); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	if want := `"SALDO":1234567.10,"CURR":0.1000`; !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Fatalf("output %s does not contain %s", buf.String(), want)
+	}
+}
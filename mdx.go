@@ -0,0 +1,253 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- MDX (dBase IV) ---------------------------
+
+// Um .MDX tem um header com a tabela de tags (até 47 entradas de 32 bytes
+// a partir do byte 544). Cada tag aponta para um bloco de header com a
+// expressão e a raiz da sua B-tree. Posições são números de página de 512
+// bytes; os nós ocupam um bloco inteiro (header, bytes 20-21, em páginas).
+// Nos nós, cada item é um ponteiro LE de 4 bytes seguido da chave: número
+// de registro nas folhas e página do filho nos nós internos, que têm um
+// ponteiro a mais após a última chave (nas folhas esse ponteiro é 0).
+const (
+	mdxPageSize   = 512
+	mdxHeaderSize = 2048
+	mdxTagTable   = 544
+	mdxTagEntry   = 32
+	mdxMaxTags    = 47
+	mdxNodeHeader = 8
+
+	mdxDescending = 0x08
+	mdxUnique     = 0x40
+)
+
+// readMDX lê a tabela de tags de um .MDX.
+func (d *DBF) readMDX(r io.ReaderAt) ([]*IndexTag, error) {
+	hdr := make([]byte, mdxHeaderSize)
+	if n, err := r.ReadAt(hdr, 0); err != nil && n < mdxTagTable {
+		return nil, fmt.Errorf("lendo header: %w", err)
+	}
+	pages := int(binary.LittleEndian.Uint16(hdr[20:22]))
+	if pages == 0 {
+		pages = 2
+	}
+	blockSize := pages * mdxPageSize
+	count := int(binary.LittleEndian.Uint16(hdr[28:30]))
+	if count > mdxMaxTags {
+		return nil, fmt.Errorf("número de tags inválido: %d", count)
+	}
+
+	var tags []*IndexTag
+	for i := 0; i < count; i++ {
+		e := hdr[mdxTagTable+i*mdxTagEntry:]
+		name, _, _ := bytes.Cut(e[4:15], []byte{0})
+		t, err := readMDXTag(r, int64(binary.LittleEndian.Uint32(e[0:4])), blockSize)
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", name, err)
+		}
+		t.Name = string(bytes.TrimSpace(name))
+		enc := d.keyCodec(t.Expr)
+		t.seekKey = func(v any) ([]byte, error) { return mdxSeekKey(t, v, enc) }
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+func readMDXTag(r io.ReaderAt, page int64, blockSize int) (*IndexTag, error) {
+	hdr := make([]byte, blockSize)
+	if _, err := r.ReadAt(hdr, page*mdxPageSize); err != nil {
+		return nil, fmt.Errorf("lendo header da tag: %w", err)
+	}
+	keyFmt := hdr[8]
+	keyType := hdr[9]
+	keyLen := int(binary.LittleEndian.Uint16(hdr[12:14]))
+	itemLen := int(binary.LittleEndian.Uint16(hdr[18:20]))
+	if keyLen <= 0 || itemLen < keyLen+4 || mdxNodeHeader+itemLen > blockSize {
+		return nil, fmt.Errorf("tamanho de chave inválido: %d/%d", keyLen, itemLen)
+	}
+	expr, _, _ := bytes.Cut(hdr[24:244], []byte{0})
+
+	t := &IndexTag{
+		Expr:       string(bytes.TrimSpace(expr)),
+		Unique:     hdr[23] != 0 || keyFmt&mdxUnique != 0,
+		Descending: keyFmt&mdxDescending != 0,
+		KeyLen:     keyLen,
+		keyType:    keyType,
+	}
+	if hdr[245] != 0 && blockSize > 580 {
+		forExpr, _, _ := bytes.Cut(hdr[580:], []byte{0})
+		t.For = string(bytes.TrimSpace(forExpr))
+	}
+
+	var compare func(a, b []byte) int
+	switch keyType {
+	case 'C':
+		compare = bytes.Compare
+	case 'N':
+		compare = func(a, b []byte) int { return compareFloat(mdxNumber(a), mdxNumber(b)) }
+	case 'D':
+		compare = func(a, b []byte) int { return compareFloat(leFloat(a), leFloat(b)) }
+	default:
+		return nil, fmt.Errorf("tipo de chave não suportado: %q", string(keyType))
+	}
+	t.tree = &btree{
+		root:    int64(binary.LittleEndian.Uint32(hdr[0:4])) * mdxPageSize,
+		compare: compare,
+		load: func(off int64) (*indexNode, error) {
+			return readMDXNode(r, off, blockSize, keyLen, itemLen)
+		},
+	}
+	return t, nil
+}
+
+func readMDXNode(r io.ReaderAt, off int64, blockSize, keyLen, itemLen int) (*indexNode, error) {
+	buf := make([]byte, blockSize)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("lendo nó em %d: %w", off, err)
+	}
+	count := int(binary.LittleEndian.Uint32(buf[0:4]))
+	if mdxNodeHeader+count*itemLen > blockSize {
+		return nil, fmt.Errorf("nó em %d: %d chaves não cabem no bloco", off, count)
+	}
+	n := &indexNode{leaf: true}
+	if p := mdxNodeHeader + count*itemLen; p+4 <= blockSize {
+		if last := binary.LittleEndian.Uint32(buf[p:]); last != 0 {
+			n.leaf = false
+			n.last = int64(last) * mdxPageSize
+		}
+	}
+	for i := 0; i < count; i++ {
+		item := buf[mdxNodeHeader+i*itemLen:]
+		ptr := binary.LittleEndian.Uint32(item[0:4])
+		n.keys = append(n.keys, append([]byte(nil), item[4:4+keyLen]...))
+		if n.leaf {
+			n.recnos = append(n.recnos, ptr)
+			n.children = append(n.children, 0)
+		} else {
+			n.recnos = append(n.recnos, 0)
+			n.children = append(n.children, int64(ptr)*mdxPageSize)
+		}
+	}
+	return n, nil
+}
+
+// mdxSeekKey converte v para uma chave de busca da tag t.
+func mdxSeekKey(t *IndexTag, v any, enc encoding.Encoding) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	switch t.keyType {
+	case 'C':
+		return textKey(v, enc)
+	case 'D':
+		tm, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("chave de índice: esperado time.Time, recebido %T", v)
+		}
+		return putLEFloat(julianFloat(tm, false)), nil
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, fmt.Errorf("chave de índice: %w", err)
+	}
+	return mdxBCD(f)
+}
+
+// mdxBCD codifica f no formato numérico de chave do dBase IV: expoente
+// (dígitos antes da vírgula + 52), quantidade de dígitos significativos
+// (bits 2-6) com o sinal no bit 7 e até 20 dígitos BCD.
+func mdxBCD(f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("chave de índice: valor numérico inválido: %v", f)
+	}
+	out := make([]byte, 12)
+	out[0] = 52
+	if f == 0 {
+		return out, nil
+	}
+	s := strconv.FormatFloat(math.Abs(f), 'e', -1, 64) // d.ddde±XX
+	mant, e, _ := bytes.Cut([]byte(s), []byte{'e'})
+	exp, err := strconv.Atoi(string(e))
+	if err != nil {
+		return nil, err
+	}
+	if exp < -53 || exp > 200 {
+		return nil, fmt.Errorf("chave de índice: %v fora da faixa", f)
+	}
+	digits := bytes.Replace(mant, []byte{'.'}, nil, 1)
+	if len(digits) > 20 {
+		digits = digits[:20]
+	}
+	out[0] = byte(52 + exp + 1)
+	out[1] = byte(len(digits) << 2)
+	if f < 0 {
+		out[1] |= 0x80
+	}
+	for i, c := range digits {
+		v := c - '0'
+		if i%2 == 0 {
+			v <<= 4
+		}
+		out[2+i/2] |= v
+	}
+	return out, nil
+}
+
+// mdxNumber decodifica uma chave numérica do dBase IV.
+func mdxNumber(b []byte) float64 {
+	if len(b) < 12 {
+		return 0
+	}
+	n := int(b[1]>>2) & 0x1F
+	if n > 20 {
+		n = 20
+	}
+	var v float64
+	for i := 0; i < n; i++ {
+		d := b[2+i/2]
+		if i%2 == 0 {
+			d >>= 4
+		}
+		v = v*10 + float64(d&0x0F)
+	}
+	v *= math.Pow10(int(b[0]) - 52 - n)
+	if b[1]&0x80 != 0 {
+		v = -v
+	}
+	return v
+}
+
+func leFloat(b []byte) float64 {
+	if len(b) < 8 {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func putLEFloat(f float64) []byte {
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint64(out, math.Float64bits(f))
+	return out
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
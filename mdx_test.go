@@ -0,0 +1,274 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mdxTestTag struct {
+	name, expr, forExpr string
+	keyType             byte
+	keyLen              int
+	descending          bool
+	keys                [][]byte
+	recnos              []uint32
+}
+
+// buildMDX monta um .MDX com blocos de 1024 bytes e folhas de no máximo
+// perLeaf chaves sob uma raiz interna.
+func buildMDX(tags []mdxTestTag, perLeaf int) []byte {
+	const block = 1024
+	out := make([]byte, mdxHeaderSize)
+	out[0] = 2
+	binary.LittleEndian.PutUint16(out[20:22], block/mdxPageSize)
+	binary.LittleEndian.PutUint16(out[28:30], uint16(len(tags)))
+
+	page := uint32(mdxHeaderSize / mdxPageSize)
+	for i, t := range tags {
+		itemLen := (t.keyLen + 4 + 3) / 4 * 4
+		e := out[mdxTagTable+i*mdxTagEntry:]
+		binary.LittleEndian.PutUint32(e[0:4], page)
+		copy(e[4:15], t.name)
+		e[20] = t.keyType
+
+		nLeaves := (len(t.keys) + perLeaf - 1) / perLeaf
+		leafPage := func(j int) uint32 { return page + 2 + uint32(2*j) }
+		root := leafPage(0)
+		if nLeaves > 1 {
+			root = leafPage(nLeaves)
+		}
+
+		hdr := make([]byte, block)
+		binary.LittleEndian.PutUint32(hdr[0:4], root)
+		if t.descending {
+			hdr[8] = mdxDescending
+		}
+		hdr[9] = t.keyType
+		binary.LittleEndian.PutUint16(hdr[12:14], uint16(t.keyLen))
+		binary.LittleEndian.PutUint16(hdr[18:20], uint16(itemLen))
+		copy(hdr[24:], t.expr)
+		if t.forExpr != "" {
+			hdr[245] = 1
+			copy(hdr[580:], t.forExpr)
+		}
+		out = append(out, hdr...)
+
+		var lastKeys [][]byte
+		for j := 0; j < nLeaves; j++ {
+			lo, hi := j*perLeaf, (j+1)*perLeaf
+			if hi > len(t.keys) {
+				hi = len(t.keys)
+			}
+			node := make([]byte, block)
+			binary.LittleEndian.PutUint32(node[0:4], uint32(hi-lo))
+			for k := lo; k < hi; k++ {
+				item := node[mdxNodeHeader+(k-lo)*itemLen:]
+				binary.LittleEndian.PutUint32(item[0:4], t.recnos[k])
+				copy(item[4:], t.keys[k])
+			}
+			out = append(out, node...)
+			lastKeys = append(lastKeys, t.keys[hi-1])
+		}
+		if nLeaves > 1 {
+			node := make([]byte, block)
+			binary.LittleEndian.PutUint32(node[0:4], uint32(nLeaves-1))
+			for j := 0; j < nLeaves; j++ {
+				item := node[mdxNodeHeader+j*itemLen:]
+				binary.LittleEndian.PutUint32(item[0:4], leafPage(j))
+				if j < nLeaves-1 {
+					copy(item[4:], lastKeys[j])
+				}
+			}
+			out = append(out, node...)
+			nLeaves++
+		}
+		page += 2 + uint32(2*nLeaves)
+	}
+	return out
+}
+
+// writeMDXFixture grava ledger.dbf (marcada com índice de produção) e
+// ledger.mdx com tags de texto, número e data.
+func writeMDXFixture(t *testing.T) *DBF {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ledger.dbf")
+	fields := []Field{
+		{Name: "NAME", Type: 'C', Size: 10},
+		{Name: "AMOUNT", Type: 'N', Size: 8, DecimalPlaces: 2},
+		{Name: "BOOKED", Type: 'D'},
+	}
+	w, err := Create(path, fields, &CreateOptions{Version: 0x03})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	rows := []struct {
+		name   string
+		amount float64
+		day    int
+	}{
+		{"rent", 1200, 5}, {"food", -35.5, 2}, {"bonus", 500, 20},
+		{"fuel", -80.25, 2}, {"refund", 0.75, 11}, {"tax", -1200, 30},
+	}
+	type entry struct {
+		key   []byte
+		recno uint32
+		n     float64
+	}
+	var byName, byAmount, byDate []entry
+	for i, r := range rows {
+		booked := time.Date(2024, time.March, r.day, 0, 0, 0, 0, time.UTC)
+		if err := w.Append(Record{"NAME": r.name, "AMOUNT": r.amount, "BOOKED": booked}); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+		bcd, err := mdxBCD(r.amount)
+		if err != nil {
+			t.Fatalf("mdxBCD returned error: %v", err)
+		}
+		recno := uint32(i + 1)
+		byName = append(byName, entry{padKey(strings.ToUpper(r.name), 10, ' '), recno, 0})
+		byAmount = append(byAmount, entry{bcd, recno, r.amount})
+		byDate = append(byDate, entry{putLEFloat(julianFloat(booked, false)), recno, float64(r.day)})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	split := func(es []entry, less func(a, b entry) bool) ([][]byte, []uint32) {
+		sort.SliceStable(es, func(i, j int) bool { return less(es[i], es[j]) })
+		var keys [][]byte
+		var recnos []uint32
+		for _, e := range es {
+			keys = append(keys, e.key)
+			recnos = append(recnos, e.recno)
+		}
+		return keys, recnos
+	}
+	byNum := func(a, b entry) bool { return a.n < b.n }
+	nameKeys, nameRecnos := split(byName, func(a, b entry) bool { return bytes.Compare(a.key, b.key) < 0 })
+	amountKeys, amountRecnos := split(byAmount, byNum)
+	dateKeys, dateRecnos := split(byDate, byNum)
+
+	data := buildMDX([]mdxTestTag{
+		{name: "NAME", expr: "UPPER(NAME)", keyType: 'C', keyLen: 10, keys: nameKeys, recnos: nameRecnos},
+		{name: "AMOUNT", expr: "AMOUNT", forExpr: "AMOUNT<>0", keyType: 'N', keyLen: 12, keys: amountKeys, recnos: amountRecnos},
+		{name: "BOOKED", expr: "BOOKED", keyType: 'D', keyLen: 8, descending: true, keys: dateKeys, recnos: dateRecnos},
+	}, 2)
+	writeFixture(t, dir, "ledger.mdx", data)
+
+	// marca o índice de produção no header
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read table: %v", err)
+	}
+	raw[28] = 0x01
+	writeFixture(t, dir, "ledger.dbf", raw)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	return db
+}
+
+func TestMDXBCDRoundTrip(t *testing.T) {
+	for _, v := range []float64{0, 1, 41, -35.5, 0.75, 1200, -0.001, 123456789.25} {
+		b, err := mdxBCD(v)
+		if err != nil {
+			t.Fatalf("mdxBCD(%v) returned error: %v", v, err)
+		}
+		if got := mdxNumber(b); got != v {
+			t.Fatalf("expected %v, got %v", v, got)
+		}
+	}
+}
+
+func TestOpenProductionIndexMDX(t *testing.T) {
+	db := writeMDXFixture(t)
+	if !db.HasProductionIndex() {
+		t.Fatalf("expected production index flag")
+	}
+	idx, err := db.OpenProductionIndex()
+	if err != nil {
+		t.Fatalf("OpenProductionIndex returned error: %v", err)
+	}
+	defer idx.Close()
+
+	if len(idx.Tags) != 3 {
+		t.Fatalf("expected 3 tags, got %d", len(idx.Tags))
+	}
+	amount, _ := idx.Tag("AMOUNT")
+	if amount.Expr != "AMOUNT" || amount.For != "AMOUNT<>0" || amount.KeyLen != 12 {
+		t.Fatalf("unexpected AMOUNT tag: %+v", amount)
+	}
+	if booked, _ := idx.Tag("BOOKED"); !booked.Descending {
+		t.Fatalf("expected BOOKED to be descending")
+	}
+}
+
+func TestMDXSeekAndScan(t *testing.T) {
+	db := writeMDXFixture(t)
+	idx, err := db.OpenProductionIndex()
+	if err != nil {
+		t.Fatalf("OpenProductionIndex returned error: %v", err)
+	}
+	defer idx.Close()
+
+	seek := func(tag string, key any, want uint32, wantFound bool) {
+		t.Helper()
+		recno, found, err := idx.Seek(tag, key)
+		if err != nil {
+			t.Fatalf("Seek(%s, %v) returned error: %v", tag, key, err)
+		}
+		if recno != want || found != wantFound {
+			t.Fatalf("Seek(%s, %v): expected %d/%v, got %d/%v", tag, key, want, wantFound, recno, found)
+		}
+	}
+	seek("NAME", "REFUND", 5, true)
+	seek("NAME", "F", 2, true)
+	seek("NAME", "ZZZ", 0, false)
+	seek("AMOUNT", -80.25, 4, true)
+	seek("AMOUNT", 500, 3, true)
+	seek("AMOUNT", 1, 0, false)
+	seek("BOOKED", time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), 5, true)
+
+	collect := func(c *IndexCursor, err error) []uint32 {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("scan returned error: %v", err)
+		}
+		var out []uint32
+		for c.Next() {
+			out = append(out, c.Recno())
+		}
+		if err := c.Err(); err != nil {
+			t.Fatalf("cursor error: %v", err)
+		}
+		return out
+	}
+	equal := func(got, want []uint32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	}
+
+	equal(collect(idx.Scan("AMOUNT")), []uint32{6, 4, 2, 5, 3, 1})
+	equal(collect(idx.Scan("BOOKED")), []uint32{6, 3, 5, 1, 4, 2})
+	equal(collect(idx.ScanRange("AMOUNT", -100, 1)), []uint32{4, 2, 5})
+	equal(collect(idx.ScanRange("NAME", "B", "FOOD")), []uint32{3, 2})
+	equal(collect(idx.ScanRange("BOOKED",
+		time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC))), []uint32{2, 4, 1})
+}
@@ -0,0 +1,308 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Memo (.DBT/.FPT) ---------------------------
+
+type memoKind int
+
+const (
+	memoDBT3 memoKind = iota // dBase III: texto terminado por 0x1A 0x1A
+	memoDBT4                 // dBase IV: bloco com prefixo FF FF 08 00 + tamanho
+	memoFPT                  // FoxPro/VFP: bloco com tipo e tamanho (big endian)
+)
+
+const (
+	dbt3BlockSize  = 512
+	memoHeaderSize = 512
+)
+
+// Tipos de bloco do .FPT.
+const (
+	fptPicture = 0 // imagem/binário
+	fptText    = 1 // texto (memo)
+)
+
+// memoFile lê blocos de um arquivo de memo já aberto.
+type memoFile struct {
+	r         io.ReaderAt
+	c         io.Closer
+	kind      memoKind
+	blockSize int64
+	size      int64 // tamanho do arquivo; -1 se desconhecido
+}
+
+func openMemo(open opener, version byte) (*memoFile, error) {
+	r, c, err := open()
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMemoFile(r, version)
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	m.c = c
+	return m, nil
+}
+
+func newMemoFile(r io.ReaderAt, version byte) (*memoFile, error) {
+	hdr := make([]byte, 32)
+	if _, err := r.ReadAt(hdr, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("lendo header do memo: %w", err)
+	}
+	m := &memoFile{r: r, kind: memoDBT3, blockSize: dbt3BlockSize, size: readerSize(r)}
+	if usesFPT(version) {
+		m.kind = memoFPT
+		m.blockSize = int64(binary.BigEndian.Uint16(hdr[6:8]))
+		if m.blockSize == 0 {
+			return nil, fmt.Errorf("memo .FPT com tamanho de bloco zero")
+		}
+		return m, nil
+	}
+	if version == 0x8b {
+		m.kind = memoDBT4
+		if bs := binary.LittleEndian.Uint16(hdr[20:22]); bs != 0 {
+			m.blockSize = int64(bs)
+		}
+	}
+	return m, nil
+}
+
+func (m *memoFile) close() error {
+	if m == nil || m.c == nil {
+		return nil
+	}
+	return m.c.Close()
+}
+
+// read devolve o conteúdo do memo que começa no bloco informado e se ele
+// deve ser tratado como texto (DBT sempre; FPT conforme o tipo do bloco).
+func (m *memoFile) read(block uint32) ([]byte, bool, error) {
+	if m.kind == memoFPT {
+		return m.readFPT(block)
+	}
+	data, err := m.readDBT(block)
+	return data, true, err
+}
+
+func (m *memoFile) readFPT(block uint32) ([]byte, bool, error) {
+	pos := int64(block) * m.blockSize
+	hdr := make([]byte, 8)
+	if _, err := m.r.ReadAt(hdr, pos); err != nil {
+		return nil, false, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+	}
+	typ := binary.BigEndian.Uint32(hdr[0:4])
+	data, err := m.readData(pos+8, int64(binary.BigEndian.Uint32(hdr[4:8])), block)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, typ == fptText, nil
+}
+
+func (m *memoFile) readDBT(block uint32) ([]byte, error) {
+	pos := int64(block) * m.blockSize
+	if m.kind == memoDBT4 {
+		hdr := make([]byte, 8)
+		if _, err := m.r.ReadAt(hdr, pos); err != nil {
+			return nil, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+		}
+		if bytes.Equal(hdr[:4], []byte{0xff, 0xff, 0x08, 0x00}) {
+			n := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+			if n < 8 {
+				return nil, fmt.Errorf("bloco de memo %d: tamanho inválido %d", block, n)
+			}
+			return m.readData(pos+8, n-8, block)
+		}
+		// sem assinatura: alguns gravadores usam o layout do dBase III
+	}
+	return m.readTerminated(pos, block)
+}
+
+// readData lê os n bytes de um memo a partir de pos. O tamanho vem do
+// próprio arquivo e não é confiável: com o tamanho do arquivo conhecido, n
+// é limitado pelo que resta dele; sem ele, o buffer cresce conforme os
+// dados lidos em vez de ser alocado de uma vez.
+func (m *memoFile) readData(pos, n int64, block uint32) ([]byte, error) {
+	if m.size >= 0 {
+		if n > m.size-pos {
+			return nil, fmt.Errorf("bloco de memo %d: tamanho %d excede o arquivo", block, n)
+		}
+		data := make([]byte, n)
+		if _, err := m.r.ReadAt(data, pos); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+		}
+		return data, nil
+	}
+	data, err := io.ReadAll(io.NewSectionReader(m.r, pos, n))
+	if err != nil {
+		return nil, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+	}
+	if int64(len(data)) < n {
+		return nil, fmt.Errorf("bloco de memo %d: tamanho %d excede o arquivo", block, n)
+	}
+	return data, nil
+}
+
+// readTerminated lê blocos consecutivos até o terminador 0x1A.
+func (m *memoFile) readTerminated(pos int64, block uint32) ([]byte, error) {
+	var out []byte
+	chunk := make([]byte, m.blockSize)
+	for {
+		n, err := m.r.ReadAt(chunk, pos)
+		if i := bytes.IndexByte(chunk[:n], 0x1a); i >= 0 {
+			return append(out, chunk[:i]...), nil
+		}
+		out = append(out, chunk[:n]...)
+		if err != nil {
+			if err == io.EOF {
+				return out, nil
+			}
+			return nil, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+		}
+		pos += int64(n)
+	}
+}
+
+// readRaw devolve o memo no formato gravado em disco (cabeçalho do bloco
+// ou terminador incluídos), pronto para ser copiado por memoWriter.
+func (m *memoFile) readRaw(block uint32) ([]byte, error) {
+	if m.kind == memoFPT {
+		pos := int64(block) * m.blockSize
+		hdr := make([]byte, 8)
+		if _, err := m.r.ReadAt(hdr, pos); err != nil {
+			return nil, fmt.Errorf("lendo bloco de memo %d: %w", block, err)
+		}
+		data, err := m.readData(pos+8, int64(binary.BigEndian.Uint32(hdr[4:8])), block)
+		if err != nil {
+			return nil, err
+		}
+		return append(hdr, data...), nil
+	}
+	data, err := m.readDBT(block)
+	if err != nil {
+		return nil, err
+	}
+	if m.kind == memoDBT4 {
+		raw := make([]byte, 8, 8+len(data))
+		copy(raw, []byte{0xff, 0xff, 0x08, 0x00})
+		binary.LittleEndian.PutUint32(raw[4:8], uint32(len(data)+8))
+		return append(raw, data...), nil
+	}
+	return append(data, 0x1a, 0x1a), nil
+}
+
+// --------------------------- Gravação de memo ---------------------------
+
+// memoWriter grava blocos brutos sequencialmente em um novo arquivo de memo.
+type memoWriter struct {
+	w         io.WriterAt
+	kind      memoKind
+	blockSize int64
+	next      uint32 // próximo bloco livre
+}
+
+// newMemoWriter copia o header de src (512 bytes) e posiciona o primeiro
+// bloco livre logo após ele.
+func newMemoWriter(w io.WriterAt, src *memoFile) (*memoWriter, error) {
+	hdr := make([]byte, memoHeaderSize)
+	if _, err := src.r.ReadAt(hdr, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("lendo header do memo: %w", err)
+	}
+	mw := &memoWriter{
+		w:         w,
+		kind:      src.kind,
+		blockSize: src.blockSize,
+		next:      uint32((memoHeaderSize + src.blockSize - 1) / src.blockSize),
+	}
+	if _, err := w.WriteAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("gravando header do memo: %w", err)
+	}
+	return mw, nil
+}
+
+// write grava raw a partir do próximo bloco livre e devolve esse bloco.
+func (mw *memoWriter) write(raw []byte) (uint32, error) {
+	block := mw.next
+	blocks := (int64(len(raw)) + mw.blockSize - 1) / mw.blockSize
+	if blocks == 0 {
+		blocks = 1
+	}
+	padded := make([]byte, blocks*mw.blockSize)
+	copy(padded, raw)
+	if _, err := mw.w.WriteAt(padded, int64(block)*mw.blockSize); err != nil {
+		return 0, fmt.Errorf("gravando bloco de memo: %w", err)
+	}
+	mw.next += uint32(blocks)
+	return block, nil
+}
+
+// finish atualiza o ponteiro de próximo bloco livre no header.
+func (mw *memoWriter) finish() error {
+	next := make([]byte, 4)
+	if mw.kind == memoFPT {
+		binary.BigEndian.PutUint32(next, mw.next)
+	} else {
+		binary.LittleEndian.PutUint32(next, mw.next)
+	}
+	if _, err := mw.w.WriteAt(next, 0); err != nil {
+		return fmt.Errorf("atualizando header do memo: %w", err)
+	}
+	return nil
+}
+
+// usesFPT indica as versões que guardam memos em .FPT.
+func usesFPT(version byte) bool {
+	return isVFP(version) || version == 0xf5
+}
+
+// isVFP indica as versões do Visual FoxPro: 0x30, 0x31 (autoincremento) e
+// 0x32 (Varchar/Varbinary).
+func isVFP(version byte) bool {
+	return version == 0x30 || version == 0x31 || version == 0x32
+}
+
+// isMemoType indica os tipos cujo conteúdo mora no arquivo de memo.
+func isMemoType(t byte) bool {
+	return t == 'M' || t == 'G' || t == 'W'
+}
+
+// putMemoBlock grava o ponteiro no mesmo formato lido por memoBlock.
+func putMemoBlock(dst []byte, block uint32) {
+	if len(dst) == 4 {
+		binary.LittleEndian.PutUint32(dst, block)
+		return
+	}
+	fillSpaces(dst)
+	if block == 0 {
+		return
+	}
+	s := strconv.FormatUint(uint64(block), 10)
+	copy(dst[len(dst)-len(s):], s)
+}
+
+// memoBlock interpreta o ponteiro gravado no registro: 4 bytes binários
+// (VFP) ou número ASCII de 10 posições (dBase/FoxPro 2).
+func memoBlock(b []byte) (uint32, error) {
+	if len(b) == 4 {
+		return binary.LittleEndian.Uint32(b), nil
+	}
+	s := strings.Trim(string(b), " \x00")
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ponteiro de memo inválido: %q", s)
+	}
+	return uint32(n), nil
+}
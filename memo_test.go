@@ -0,0 +1,266 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildDBF monta um DBF mínimo; cada linha traz o conteúdo bruto de cada
+// campo, completado com espaços até o tamanho declarado.
+func buildDBF(version byte, fields []Field, rows [][]string) []byte {
+	headerLen := 32 + 32*len(fields) + 1
+	recordLen := calcRecordLen(fields)
+
+	hdr := make([]byte, 32)
+	hdr[0] = version
+	hdr[1], hdr[2], hdr[3] = 124, 1, 1
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(len(rows)))
+	binary.LittleEndian.PutUint16(hdr[8:10], uint16(headerLen))
+	binary.LittleEndian.PutUint16(hdr[10:12], uint16(recordLen))
+
+	out := append([]byte{}, hdr...)
+	for _, f := range fields {
+		des := make([]byte, 32)
+		copy(des[0:11], f.Name)
+		des[11] = f.Type
+		des[16] = f.Size
+		des[17] = f.DecimalPlaces
+		out = append(out, des...)
+	}
+	out = append(out, 0x0d)
+
+	for _, row := range rows {
+		out = append(out, ' ')
+		for i, f := range fields {
+			v := row[i]
+			if len(v) < int(f.Size) {
+				v += strings.Repeat(" ", int(f.Size)-len(v))
+			}
+			out = append(out, v[:f.Size]...)
+		}
+	}
+	return append(out, 0x1a)
+}
+
+func writeFixture(t testing.TB, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+var memoFields = []Field{
+	{Name: "ID", Type: 'N', Size: 3},
+	{Name: "NOTES", Type: 'M', Size: 10},
+}
+
+func TestReadRecordsDBaseIIIMemo(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", ""},
+		{"  3", "         2"},
+	}))
+
+	memo := make([]byte, 3*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 3)
+	copy(memo[512:], "Cliente pontual\x1a\x1a")
+	copy(memo[1024:], "Cobrar em dobro\x1a\x1a")
+	writeFixture(t, dir, "notes.dbt", memo)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("len(records) = %d, want 3", len(records))
+	}
+
+	want := []any{"Cliente pontual", nil, "Cobrar em dobro"}
+	for i, w := range want {
+		if got := records[i]["NOTES"]; got != w {
+			t.Fatalf("record %d NOTES = %#v, want %#v", i, got, w)
+		}
+	}
+}
+
+func TestReadRecordsDBaseIVMemo(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x8b, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", "         2"},
+	}))
+
+	long := strings.Repeat("x", 70)
+	memo := make([]byte, 5*64)
+	binary.LittleEndian.PutUint32(memo[0:4], 5)
+	binary.LittleEndian.PutUint16(memo[20:22], 64)
+	block := func(n int, text string) {
+		copy(memo[n*64:], []byte{0xff, 0xff, 0x08, 0x00})
+		binary.LittleEndian.PutUint32(memo[n*64+4:], uint32(len(text)+8))
+		copy(memo[n*64+8:], text)
+	}
+	block(1, "Observa\xe7\xe3o")
+	block(2, long)
+	writeFixture(t, dir, "notes.dbt", memo)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if got := records[0]["NOTES"]; got != "Observação" {
+		t.Fatalf("record 0 NOTES = %#v, want %q", got, "Observação")
+	}
+	if got := records[1]["NOTES"]; got != long {
+		t.Fatalf("record 1 NOTES = %#v, want %q", got, long)
+	}
+}
+
+func TestReadRecordsMissingMemoFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+	}))
+
+	if _, err := Open(path, nil); err == nil {
+		t.Fatalf("Open without .dbt in strict mode should fail")
+	}
+
+	db, err := Open(path, &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if got, ok := records[0]["NOTES"]; !ok || got != nil {
+		t.Fatalf("NOTES = %#v, want nil", got)
+	}
+}
+
+func TestReadRecordsVisualFoxProMemo(t *testing.T) {
+	dir := t.TempDir()
+	fields := []Field{
+		{Name: "ID", Type: 'N', Size: 3},
+		{Name: "NOTES", Type: 'M', Size: 4},
+		{Name: "PHOTO", Type: 'G', Size: 4},
+		{Name: "DATA", Type: 'W', Size: 4},
+	}
+	ptr := func(n uint32) string {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, n)
+		return string(b)
+	}
+	path := writeFixture(t, dir, "vfp.dbf", buildDBF(0x30, fields, [][]string{
+		{"  1", ptr(8), ptr(9), ptr(10)},
+		{"  2", ptr(0), ptr(0), ptr(0)},
+	}))
+
+	const blockSize = 64
+	memo := make([]byte, 11*blockSize)
+	binary.BigEndian.PutUint32(memo[0:4], 11)
+	binary.BigEndian.PutUint16(memo[6:8], blockSize)
+	block := func(n int, typ uint32, data []byte) {
+		binary.BigEndian.PutUint32(memo[n*blockSize:], typ)
+		binary.BigEndian.PutUint32(memo[n*blockSize+4:], uint32(len(data)))
+		copy(memo[n*blockSize+8:], data)
+	}
+	block(8, 1, []byte("Pedido urgente"))
+	block(9, 0, []byte{0x89, 'P', 'N', 'G'})
+	block(10, 0, []byte{0x00, 0x01, 0x02})
+	writeFixture(t, dir, "vfp.fpt", memo)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+
+	rec := records[0]
+	if got := rec["NOTES"]; got != "Pedido urgente" {
+		t.Fatalf("NOTES = %#v, want %q", got, "Pedido urgente")
+	}
+	if got, ok := rec["PHOTO"].([]byte); !ok || string(got) != "\x89PNG" {
+		t.Fatalf("PHOTO = %#v, want PNG signature bytes", rec["PHOTO"])
+	}
+	if got, ok := rec["DATA"].([]byte); !ok || len(got) != 3 || got[2] != 0x02 {
+		t.Fatalf("DATA = %#v, want []byte{0, 1, 2}", rec["DATA"])
+	}
+
+	for _, name := range []string{"NOTES", "PHOTO", "DATA"} {
+		if got, ok := records[1][name]; !ok || got != nil {
+			t.Fatalf("empty %s = %#v, want nil", name, got)
+		}
+	}
+}
+
+func TestOpenVisualFoxProMissingMemoFile(t *testing.T) {
+	dir := t.TempDir()
+	fields := []Field{{Name: "NOTES", Type: 'M', Size: 4}}
+	path := writeFixture(t, dir, "vfp.dbf", buildDBF(0x30, fields, [][]string{{"\x00\x00\x00\x00"}}))
+
+	if _, err := Open(path, nil); err == nil {
+		t.Fatalf("Open without .fpt in strict mode should fail")
+	}
+	if _, err := Open(path, &OpenOptions{ReadMode: ReadLoose}); err != nil {
+		t.Fatalf("Open in loose mode returned error: %v", err)
+	}
+}
+
+// readerAtOnly esconde o tamanho do leitor, como um fs.File sem Stat.
+type readerAtOnly struct{ r io.ReaderAt }
+
+func (r readerAtOnly) ReadAt(p []byte, off int64) (int, error) { return r.r.ReadAt(p, off) }
+
+func TestMemoRejectsLengthBeyondFile(t *testing.T) {
+	fpt := make([]byte, 3*64)
+	binary.BigEndian.PutUint16(fpt[6:8], 64)
+	binary.BigEndian.PutUint32(fpt[2*64:], 1)
+	binary.BigEndian.PutUint32(fpt[2*64+4:], 0xfffffff0)
+
+	dbt := make([]byte, 2*512)
+	copy(dbt[512:], []byte{0xff, 0xff, 0x08, 0x00})
+	binary.LittleEndian.PutUint32(dbt[516:], 0xfffffff0)
+
+	for _, c := range []struct {
+		name    string
+		data    []byte
+		version byte
+		block   uint32
+	}{
+		{"FPT", fpt, 0x30, 2},
+		{"DBT", dbt, 0x8b, 1},
+	} {
+		for _, r := range []io.ReaderAt{bytes.NewReader(c.data), readerAtOnly{bytes.NewReader(c.data)}} {
+			m, err := newMemoFile(r, c.version)
+			if err != nil {
+				t.Fatalf("%s: newMemoFile returned error: %v", c.name, err)
+			}
+			if _, _, err := m.read(c.block); err == nil || !strings.Contains(err.Error(), "excede o arquivo") {
+				t.Fatalf("%s (%T): expected length error, got %v", c.name, r, err)
+			}
+			if _, err := m.readRaw(c.block); err == nil {
+				t.Fatalf("%s (%T): readRaw should fail", c.name, r)
+			}
+		}
+	}
+}
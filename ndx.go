@@ -0,0 +1,123 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- NDX (dBase III) ---------------------------
+
+// Um .NDX tem uma única chave e blocos de 512 bytes endereçados por número
+// de bloco. Cada nó traz a quantidade de chaves e itens com ponteiro para
+// o filho à esquerda, número de registro e chave; nós internos têm um
+// item a mais, só com o ponteiro do último filho. Chaves numéricas e de
+// data são doubles LE (datas como dia juliano).
+const (
+	ndxBlockSize  = 512
+	ndxNodeHeader = 4
+)
+
+func (d *DBF) readNDX(r io.ReaderAt, path string) ([]*IndexTag, error) {
+	hdr := make([]byte, ndxBlockSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("lendo header: %w", err)
+	}
+	keyLen := int(binary.LittleEndian.Uint16(hdr[12:14]))
+	numeric := binary.LittleEndian.Uint16(hdr[16:18]) != 0
+	itemLen := int(binary.LittleEndian.Uint16(hdr[18:20]))
+	if keyLen <= 0 || itemLen < keyLen+8 || ndxNodeHeader+itemLen > ndxBlockSize {
+		return nil, fmt.Errorf("tamanho de chave inválido: %d/%d", keyLen, itemLen)
+	}
+	expr, _, _ := bytes.Cut(hdr[24:], []byte{0})
+
+	t := &IndexTag{
+		Name:    singleTagName(path),
+		Expr:    string(bytes.TrimSpace(expr)),
+		Unique:  hdr[23] != 0,
+		KeyLen:  keyLen,
+		keyType: 'C',
+	}
+	compare := bytes.Compare
+	if numeric {
+		t.keyType = 'N'
+		if keyTypeFor(d.Fields, t.Expr) == 'D' {
+			t.keyType = 'D'
+		}
+		compare = func(a, b []byte) int { return compareFloat(leFloat(a), leFloat(b)) }
+	}
+	t.tree = &btree{
+		root:    int64(binary.LittleEndian.Uint32(hdr[0:4])) * ndxBlockSize,
+		compare: compare,
+		load: func(off int64) (*indexNode, error) {
+			return readNDXNode(r, off, keyLen, itemLen)
+		},
+	}
+	enc := d.keyCodec(t.Expr)
+	t.seekKey = func(v any) ([]byte, error) { return ndxSeekKey(t, v, enc) }
+	return []*IndexTag{t}, nil
+}
+
+func readNDXNode(r io.ReaderAt, off int64, keyLen, itemLen int) (*indexNode, error) {
+	buf := make([]byte, ndxBlockSize)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("lendo nó em %d: %w", off, err)
+	}
+	count := int(binary.LittleEndian.Uint32(buf[0:4]))
+	if ndxNodeHeader+count*itemLen > ndxBlockSize {
+		return nil, fmt.Errorf("nó em %d: %d chaves não cabem no bloco", off, count)
+	}
+	n := &indexNode{leaf: true}
+	for i := 0; i < count; i++ {
+		item := buf[ndxNodeHeader+i*itemLen:]
+		child := binary.LittleEndian.Uint32(item[0:4])
+		if child != 0 {
+			n.leaf = false
+		}
+		n.children = append(n.children, int64(child)*ndxBlockSize)
+		n.recnos = append(n.recnos, binary.LittleEndian.Uint32(item[4:8]))
+		n.keys = append(n.keys, append([]byte(nil), item[8:8+keyLen]...))
+	}
+	if p := ndxNodeHeader + count*itemLen; p+4 <= ndxBlockSize {
+		if last := binary.LittleEndian.Uint32(buf[p:]); last != 0 {
+			n.leaf = false
+			n.last = int64(last) * ndxBlockSize
+		}
+	}
+	return n, nil
+}
+
+// ndxSeekKey converte v para uma chave de busca da tag t.
+func ndxSeekKey(t *IndexTag, v any, enc encoding.Encoding) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	switch t.keyType {
+	case 'C':
+		return textKey(v, enc)
+	case 'D':
+		tm, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("chave de índice: esperado time.Time, recebido %T", v)
+		}
+		return putLEFloat(julianFloat(tm, false)), nil
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, fmt.Errorf("chave de índice: %w", err)
+	}
+	return putLEFloat(f), nil
+}
+
+// singleTagName devolve o nome da tag de um índice de chave única: o nome
+// do arquivo sem extensão, em maiúsculas.
+func singleTagName(path string) string {
+	base := filepath.Base(path)
+	return strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base)))
+}
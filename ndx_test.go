@@ -0,0 +1,159 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// buildNDX monta um .NDX com folhas de no máximo perLeaf chaves sob uma
+// raiz interna.
+func buildNDX(expr string, numeric bool, keyLen int, keys [][]byte, recnos []uint32, perLeaf int) []byte {
+	itemLen := (keyLen + 8 + 3) / 4 * 4
+	nLeaves := (len(keys) + perLeaf - 1) / perLeaf
+	root := uint32(1)
+	if nLeaves > 1 {
+		root = uint32(nLeaves + 1)
+	}
+
+	hdr := make([]byte, ndxBlockSize)
+	binary.LittleEndian.PutUint32(hdr[0:4], root)
+	binary.LittleEndian.PutUint16(hdr[12:14], uint16(keyLen))
+	if numeric {
+		binary.LittleEndian.PutUint16(hdr[16:18], 1)
+	}
+	binary.LittleEndian.PutUint16(hdr[18:20], uint16(itemLen))
+	copy(hdr[24:], expr)
+	out := hdr
+
+	var lastKeys [][]byte
+	for j := 0; j < nLeaves; j++ {
+		lo, hi := j*perLeaf, (j+1)*perLeaf
+		if hi > len(keys) {
+			hi = len(keys)
+		}
+		node := make([]byte, ndxBlockSize)
+		binary.LittleEndian.PutUint32(node[0:4], uint32(hi-lo))
+		for k := lo; k < hi; k++ {
+			item := node[ndxNodeHeader+(k-lo)*itemLen:]
+			binary.LittleEndian.PutUint32(item[4:8], recnos[k])
+			copy(item[8:], keys[k])
+		}
+		out = append(out, node...)
+		lastKeys = append(lastKeys, keys[hi-1])
+	}
+	if nLeaves > 1 {
+		node := make([]byte, ndxBlockSize)
+		binary.LittleEndian.PutUint32(node[0:4], uint32(nLeaves-1))
+		for j := 0; j < nLeaves; j++ {
+			item := node[ndxNodeHeader+j*itemLen:]
+			binary.LittleEndian.PutUint32(item[0:4], uint32(j+1))
+			if j < nLeaves-1 {
+				copy(item[8:], lastKeys[j])
+			}
+		}
+		out = append(out, node...)
+	}
+	return out
+}
+
+func TestNDXSeekAndScan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "people.dbf")
+	names := []string{"Davi", "ana", "Bruno", "Carla", "Eva", "Caio", "Fabio"}
+	ages := []int{52, 30, 41, 25, 19, 41, 67}
+	var rows []Record
+	for i := range names {
+		rows = append(rows, Record{"NAME": names[i], "AGE": ages[i]})
+	}
+	writePeople(t, path, rows...)
+
+	type entry struct {
+		key   []byte
+		recno uint32
+		age   int
+	}
+	var byName, byAge []entry
+	for i := range names {
+		byName = append(byName, entry{padKey(strings.ToUpper(names[i]), 10, ' '), uint32(i + 1), 0})
+		byAge = append(byAge, entry{putLEFloat(float64(ages[i])), uint32(i + 1), ages[i]})
+	}
+	sort.SliceStable(byName, func(i, j int) bool { return bytes.Compare(byName[i].key, byName[j].key) < 0 })
+	sort.SliceStable(byAge, func(i, j int) bool { return byAge[i].age < byAge[j].age })
+	split := func(es []entry) ([][]byte, []uint32) {
+		var keys [][]byte
+		var recnos []uint32
+		for _, e := range es {
+			keys = append(keys, e.key)
+			recnos = append(recnos, e.recno)
+		}
+		return keys, recnos
+	}
+	nameKeys, nameRecnos := split(byName)
+	ageKeys, ageRecnos := split(byAge)
+	writeFixture(t, dir, "names.ndx", buildNDX("UPPER(NAME)", false, 10, nameKeys, nameRecnos, 3))
+	writeFixture(t, dir, "ages.ndx", buildNDX("AGE", true, 8, ageKeys, ageRecnos, 2))
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	byNames, err := db.OpenIndex(filepath.Join(dir, "names.ndx"))
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer byNames.Close()
+	byAges, err := db.OpenIndex(filepath.Join(dir, "ages.ndx"))
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer byAges.Close()
+
+	tag, err := byNames.Tag("")
+	if err != nil {
+		t.Fatalf("Tag returned error: %v", err)
+	}
+	if tag.Name != "NAMES" || tag.Expr != "UPPER(NAME)" || tag.KeyLen != 10 || tag.Unique {
+		t.Fatalf("unexpected tag: %+v", tag)
+	}
+
+	if recno, found, _ := byNames.Seek("", "CARLA"); !found || recno != 4 {
+		t.Fatalf("expected CARLA at 4, got %d/%v", recno, found)
+	}
+	if recno, found, _ := byNames.Seek("", "CB"); found || recno != 0 {
+		t.Fatalf("expected no match for CB, got %d/%v", recno, found)
+	}
+	if recno, found, _ := byNames.SeekSoft("", "CB"); found || recno != 1 {
+		t.Fatalf("expected SeekSoft(CB) to land on DAVI (1), got %d/%v", recno, found)
+	}
+	if recno, found, _ := byNames.SeekSoft("", "ZZ"); found || recno != 0 {
+		t.Fatalf("expected SeekSoft past the end to return 0, got %d/%v", recno, found)
+	}
+	if recno, found, _ := byAges.Seek("", 41); !found || recno != 3 {
+		t.Fatalf("expected age 41 at 3, got %d/%v", recno, found)
+	}
+	if recno, found, _ := byAges.SeekSoft("", 42); found || recno != 1 {
+		t.Fatalf("expected SeekSoft(42) to land on 52 (1), got %d/%v", recno, found)
+	}
+
+	c, err := byAges.Scan("")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	var got []uint32
+	for c.Next() {
+		got = append(got, c.Recno())
+	}
+	want := []uint32{5, 4, 2, 3, 6, 1, 7}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- NTX (Clipper) ---------------------------
+
+// Um .NTX tem uma única chave em páginas de 1024 bytes endereçadas por
+// posição no arquivo. Cada página começa com a quantidade de itens e uma
+// tabela de deslocamentos; cada item tem filho à esquerda, número de
+// registro e chave. É uma B-tree clássica: chaves de nós internos também
+// apontam para registros. Todas as chaves são texto: datas como
+// AAAAMMDD e números formatados de modo que a ordem de bytes seja a
+// numérica. Tags DESCEND são gravadas já em ordem decrescente.
+const (
+	ntxPageSize = 1024
+
+	ntxExprOffset = 22
+	ntxUnique     = 278
+	ntxDescend    = 280
+	ntxForOffset  = 282
+	ntxTagOffset  = 538
+	ntxMaxExpr    = 256
+)
+
+func (d *DBF) readNTX(r io.ReaderAt, path string) ([]*IndexTag, error) {
+	hdr := make([]byte, ntxPageSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("lendo header: %w", err)
+	}
+	itemLen := int(binary.LittleEndian.Uint16(hdr[12:14]))
+	keyLen := int(binary.LittleEndian.Uint16(hdr[14:16]))
+	keyDec := int(binary.LittleEndian.Uint16(hdr[16:18]))
+	if keyLen <= 0 || itemLen < keyLen+8 || itemLen > ntxPageSize/2 {
+		return nil, fmt.Errorf("tamanho de chave inválido: %d/%d", keyLen, itemLen)
+	}
+	expr, _, _ := bytes.Cut(hdr[ntxExprOffset:ntxExprOffset+ntxMaxExpr], []byte{0})
+	forExpr, _, _ := bytes.Cut(hdr[ntxForOffset:ntxForOffset+ntxMaxExpr], []byte{0})
+	tagName, _, _ := bytes.Cut(hdr[ntxTagOffset:ntxTagOffset+11], []byte{0})
+
+	t := &IndexTag{
+		Name:       strings.TrimSpace(string(tagName)),
+		Expr:       string(bytes.TrimSpace(expr)),
+		For:        string(bytes.TrimSpace(forExpr)),
+		Unique:     hdr[ntxUnique] != 0,
+		Descending: hdr[ntxDescend] != 0,
+		KeyLen:     keyLen,
+	}
+	if t.Name == "" {
+		t.Name = singleTagName(path)
+	}
+	t.keyType = keyTypeFor(d.Fields, t.Expr)
+	if t.keyType == 'T' || t.keyType == 'I' {
+		t.keyType = 'N'
+	}
+
+	compare := bytes.Compare
+	if t.Descending {
+		compare = func(a, b []byte) int { return bytes.Compare(b, a) }
+	}
+	t.tree = &btree{
+		root:         int64(binary.LittleEndian.Uint32(hdr[4:8])),
+		compare:      compare,
+		interiorData: true,
+		descStored:   t.Descending,
+		load: func(off int64) (*indexNode, error) {
+			return readNTXPage(r, off, keyLen)
+		},
+	}
+	enc := d.keyCodec(t.Expr)
+	t.seekKey = func(v any) ([]byte, error) { return ntxSeekKey(t, v, keyDec, enc) }
+	return []*IndexTag{t}, nil
+}
+
+func readNTXPage(r io.ReaderAt, off int64, keyLen int) (*indexNode, error) {
+	buf := make([]byte, ntxPageSize)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, fmt.Errorf("lendo página em %d: %w", off, err)
+	}
+	count := int(binary.LittleEndian.Uint16(buf[0:2]))
+	if 2+2*(count+1) > ntxPageSize {
+		return nil, fmt.Errorf("página em %d: %d chaves não cabem", off, count)
+	}
+	item := func(i int) ([]byte, error) {
+		p := int(binary.LittleEndian.Uint16(buf[2+2*i:]))
+		if p+8 > ntxPageSize || (i < count && p+8+keyLen > ntxPageSize) {
+			return nil, fmt.Errorf("página em %d: item %d fora da página", off, i)
+		}
+		return buf[p:], nil
+	}
+
+	n := &indexNode{leaf: true}
+	for i := 0; i < count; i++ {
+		it, err := item(i)
+		if err != nil {
+			return nil, err
+		}
+		child := binary.LittleEndian.Uint32(it[0:4])
+		if child != 0 {
+			n.leaf = false
+		}
+		n.children = append(n.children, int64(child))
+		n.recnos = append(n.recnos, binary.LittleEndian.Uint32(it[4:8]))
+		n.keys = append(n.keys, append([]byte(nil), it[8:8+keyLen]...))
+	}
+	it, err := item(count)
+	if err != nil {
+		return nil, err
+	}
+	n.last = int64(binary.LittleEndian.Uint32(it[0:4]))
+	if n.last != 0 {
+		n.leaf = false
+	}
+	return n, nil
+}
+
+// ntxSeekKey converte v para uma chave de busca da tag t.
+func ntxSeekKey(t *IndexTag, v any, dec int, enc encoding.Encoding) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	switch t.keyType {
+	case 'C':
+		return textKey(v, enc)
+	case 'D':
+		tm, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("chave de índice: esperado time.Time, recebido %T", v)
+		}
+		return []byte(tm.Format("20060102")), nil
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, fmt.Errorf("chave de índice: %w", err)
+	}
+	return ntxNumber(f, t.KeyLen, dec)
+}
+
+// ntxNumber formata f como chave numérica do Clipper: STR(f, size, dec)
+// com os espaços à esquerda trocados por zeros; nos negativos o sinal
+// vira zero e cada dígito d vira o byte '0'-d-4, abaixo de '0', para que
+// valores mais negativos fiquem antes.
+func ntxNumber(f float64, size, dec int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("chave de índice: valor numérico inválido: %v", f)
+	}
+	s := strconv.FormatFloat(f, 'f', dec, 64)
+	if len(s) > size {
+		return nil, fmt.Errorf("chave de índice: %s não cabe em %d posições", s, size)
+	}
+	out := []byte(strings.Repeat(" ", size-len(s)) + s)
+	i := 0
+	for i < len(out) && out[i] == ' ' {
+		out[i] = '0'
+		i++
+	}
+	if i < len(out) && out[i] == '-' {
+		out[i] = '0'
+		for j, c := range out {
+			if c >= '0' && c <= '9' {
+				out[j] = '0' - (c - '0') - 4
+			}
+		}
+	}
+	return out, nil
+}
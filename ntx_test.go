@@ -0,0 +1,236 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type ntxTestIndex struct {
+	expr, forExpr, tag string
+	keyLen, dec        int
+	descending         bool
+	keys               [][]byte // já na ordem física
+	recnos             []uint32
+}
+
+// buildNTX monta um .NTX com uma raiz cujas chaves separam folhas de no
+// máximo perLeaf chaves (B-tree: as chaves da raiz também apontam para
+// registros).
+func buildNTX(x ntxTestIndex, perLeaf int) []byte {
+	const maxItems = 8
+	itemLen := x.keyLen + 8
+
+	type page struct {
+		keys     [][]byte
+		recnos   []uint32
+		children []uint32
+		last     uint32
+	}
+	var leaves []page
+	var root page
+	for i := 0; i < len(x.keys); {
+		hi := i + perLeaf
+		if hi > len(x.keys) {
+			hi = len(x.keys)
+		}
+		leaves = append(leaves, page{keys: x.keys[i:hi], recnos: x.recnos[i:hi]})
+		if hi < len(x.keys) {
+			root.keys = append(root.keys, x.keys[hi])
+			root.recnos = append(root.recnos, x.recnos[hi])
+		}
+		i = hi + 1
+	}
+	pages := leaves
+	rootOff := uint32(ntxPageSize)
+	if len(leaves) > 1 {
+		for i := range leaves {
+			off := uint32(ntxPageSize * (i + 1))
+			if i < len(root.keys) {
+				root.children = append(root.children, off)
+			} else {
+				root.last = off
+			}
+		}
+		rootOff = uint32(ntxPageSize * (len(leaves) + 1))
+		pages = append(pages, root)
+	}
+
+	hdr := make([]byte, ntxPageSize)
+	binary.LittleEndian.PutUint16(hdr[0:2], 6)
+	binary.LittleEndian.PutUint32(hdr[4:8], rootOff)
+	binary.LittleEndian.PutUint16(hdr[12:14], uint16(itemLen))
+	binary.LittleEndian.PutUint16(hdr[14:16], uint16(x.keyLen))
+	binary.LittleEndian.PutUint16(hdr[16:18], uint16(x.dec))
+	binary.LittleEndian.PutUint16(hdr[18:20], maxItems)
+	copy(hdr[ntxExprOffset:], x.expr)
+	if x.descending {
+		hdr[ntxDescend] = 1
+	}
+	copy(hdr[ntxForOffset:], x.forExpr)
+	copy(hdr[ntxTagOffset:], x.tag)
+	out := hdr
+
+	for _, p := range pages {
+		buf := make([]byte, ntxPageSize)
+		binary.LittleEndian.PutUint16(buf[0:2], uint16(len(p.keys)))
+		for i := 0; i <= maxItems; i++ {
+			off := 2 + 2*(maxItems+1) + i*itemLen
+			binary.LittleEndian.PutUint16(buf[2+2*i:], uint16(off))
+			item := buf[off:]
+			switch {
+			case i < len(p.keys):
+				if p.children != nil {
+					binary.LittleEndian.PutUint32(item[0:4], p.children[i])
+				}
+				binary.LittleEndian.PutUint32(item[4:8], p.recnos[i])
+				copy(item[8:], p.keys[i])
+			case i == len(p.keys):
+				binary.LittleEndian.PutUint32(item[0:4], p.last)
+			}
+		}
+		out = append(out, buf...)
+	}
+	return out
+}
+
+func TestNTXNumberOrdering(t *testing.T) {
+	values := []float64{-120.5, -50, -5, -0.25, 0, 3, 41.75, 300}
+	var prev []byte
+	for _, v := range values {
+		k, err := ntxNumber(v, 8, 2)
+		if err != nil {
+			t.Fatalf("ntxNumber(%v) returned error: %v", v, err)
+		}
+		if len(k) != 8 {
+			t.Fatalf("expected 8-byte key, got %q", k)
+		}
+		if prev != nil && bytes.Compare(prev, k) >= 0 {
+			t.Fatalf("expected %q < %q (value %v)", prev, k, v)
+		}
+		prev = k
+	}
+	if _, err := ntxNumber(123456, 4, 0); err == nil {
+		t.Fatalf("expected error for value wider than the key")
+	}
+}
+
+func TestNTXSeekAndScan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "people.dbf")
+	names := []string{"Davi", "ana", "Bruno", "Carla", "Eva", "Caio", "Fabio"}
+	ages := []int{52, 30, 41, 25, 19, 41, 67}
+	var rows []Record
+	for i := range names {
+		rows = append(rows, Record{"NAME": names[i], "AGE": ages[i]})
+	}
+	writePeople(t, path, rows...)
+
+	type entry struct {
+		key   []byte
+		recno uint32
+	}
+	var byName, byAge []entry
+	for i := range names {
+		byName = append(byName, entry{padKey(strings.ToUpper(names[i]), 10, ' '), uint32(i + 1)})
+		k, err := ntxNumber(float64(ages[i]), 3, 0)
+		if err != nil {
+			t.Fatalf("ntxNumber returned error: %v", err)
+		}
+		byAge = append(byAge, entry{k, uint32(i + 1)})
+	}
+	split := func(es []entry, desc bool) ([][]byte, []uint32) {
+		sort.SliceStable(es, func(i, j int) bool {
+			c := bytes.Compare(es[i].key, es[j].key)
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		})
+		var keys [][]byte
+		var recnos []uint32
+		for _, e := range es {
+			keys = append(keys, e.key)
+			recnos = append(recnos, e.recno)
+		}
+		return keys, recnos
+	}
+	nameKeys, nameRecnos := split(byName, false)
+	ageKeys, ageRecnos := split(byAge, true)
+	writeFixture(t, dir, "names.ntx", buildNTX(ntxTestIndex{
+		expr: "UPPER(NAME)", tag: "BYNAME", keyLen: 10, keys: nameKeys, recnos: nameRecnos,
+	}, 2))
+	writeFixture(t, dir, "ages.ntx", buildNTX(ntxTestIndex{
+		expr: "AGE", forExpr: "AGE > 20", keyLen: 3, descending: true, keys: ageKeys, recnos: ageRecnos,
+	}, 2))
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	byNames, err := db.OpenIndex(filepath.Join(dir, "names.ntx"))
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer byNames.Close()
+	byAges, err := db.OpenIndex(filepath.Join(dir, "ages.ntx"))
+	if err != nil {
+		t.Fatalf("OpenIndex returned error: %v", err)
+	}
+	defer byAges.Close()
+
+	if tag, _ := byNames.Tag("BYNAME"); tag == nil || tag.Expr != "UPPER(NAME)" || tag.KeyLen != 10 {
+		t.Fatalf("unexpected name tag: %+v", tag)
+	}
+	ageTag, _ := byAges.Tag("")
+	if ageTag.Name != "AGES" || !ageTag.Descending || ageTag.For != "AGE > 20" {
+		t.Fatalf("unexpected age tag: %+v", ageTag)
+	}
+
+	for _, c := range []struct {
+		key   string
+		recno uint32
+	}{{"ANA", 2}, {"CARLA", 4}, {"DAVI", 1}, {"FABIO", 7}, {"C", 6}} {
+		if recno, found, _ := byNames.Seek("", c.key); !found || recno != c.recno {
+			t.Fatalf("Seek(%s): expected %d, got %d/%v", c.key, c.recno, recno, found)
+		}
+	}
+	if recno, found, _ := byNames.SeekSoft("", "CB"); found || recno != 1 {
+		t.Fatalf("expected SeekSoft(CB) to land on DAVI (1), got %d/%v", recno, found)
+	}
+	if recno, found, _ := byAges.Seek("", 41); !found || recno != 3 {
+		t.Fatalf("expected age 41 at 3, got %d/%v", recno, found)
+	}
+
+	collect := func(x *Index) []uint32 {
+		t.Helper()
+		c, err := x.Scan("")
+		if err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+		var out []uint32
+		for c.Next() {
+			out = append(out, c.Recno())
+		}
+		if err := c.Err(); err != nil {
+			t.Fatalf("cursor error: %v", err)
+		}
+		return out
+	}
+	equal := func(got, want []uint32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	}
+	equal(collect(byNames), []uint32{2, 3, 6, 4, 1, 5, 7})
+	equal(collect(byAges), []uint32{7, 1, 3, 6, 2, 4, 5})
+}
@@ -0,0 +1,261 @@
+package dbfmini
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --------------------------- PACK ---------------------------
+
+// Pack regrava o DBF em path sem os registros marcados como excluídos,
+// corrige RecordCount no header e compacta o .DBT/.FPT associado,
+// descartando os blocos que só eram referenciados por registros removidos.
+// Um índice estrutural .cdx mantido pela biblioteca é reconstruído.
+//
+// Tabela e memo novos são gravados em temporários no mesmo diretório e
+// preparados com o sufixo .pack (memo primeiro, tabela depois); a
+// preparação da tabela é o ponto de confirmação, e só então os dois são
+// renomeados sobre os originais. Como duas renomeações não são atômicas em
+// conjunto, uma queda entre elas deixa a tabela antiga ao lado do memo
+// compactado: nesse caso Open devolve ErrPackPending e uma nova chamada a
+// Pack conclui a troca antes de prosseguir. Uma queda antes do ponto de
+// confirmação deixa os originais intactos.
+func Pack(path string, opts *OpenOptions) error {
+	if err := finishPack(path); err != nil {
+		return err
+	}
+	db, err := Open(path, opts)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	hdr := make([]byte, db.headerLen)
+	if _, err := src.ReadAt(hdr, 0); err != nil {
+		return fmt.Errorf("lendo header: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".pack-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // sem efeito após o rename
+
+	var (
+		memo    *memoFile
+		mw      *memoWriter
+		memoTmp *os.File
+	)
+	if db.memoPath != "" {
+		memo, err = openMemo(db.memoSrc, db.version)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("abrindo memo: %w", err)
+		}
+		defer memo.close()
+
+		memoTmp, err = os.CreateTemp(filepath.Dir(db.memoPath), filepath.Base(db.memoPath)+".pack-*")
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		defer os.Remove(memoTmp.Name())
+		if mw, err = newMemoWriter(memoTmp, memo); err != nil {
+			tmp.Close()
+			memoTmp.Close()
+			return err
+		}
+	}
+
+	kept, err := db.packRecords(src, tmp, len(hdr), memo, mw)
+	if err == nil {
+		err = finishPackedHeader(tmp, hdr, kept)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if memoTmp != nil {
+		if err == nil {
+			err = mw.finish()
+		}
+		if err == nil {
+			err = memoTmp.Sync()
+		}
+		if cerr := memoTmp.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if memoTmp != nil {
+		if err := replaceFile(memoTmp.Name(), db.memoPath+packSuffix); err != nil {
+			return err
+		}
+	}
+	if err := replaceFile(tmpPath, path+packSuffix); err != nil {
+		return err
+	}
+	if err := finishPack(path); err != nil {
+		return err
+	}
+	return reindexPacked(path, opts)
+}
+
+// ErrPackPending indica um Pack interrompido entre a troca do memo e a da
+// tabela; chame Pack para concluí-lo.
+var ErrPackPending = errors.New("Pack interrompido: chame Pack para concluir")
+
+// packSuffix marca os arquivos preparados por Pack para a troca final.
+const packSuffix = ".pack"
+
+// memoExts são as extensões de memo procuradas ao lado da tabela.
+var memoExts = []string{".dbt", ".DBT", ".fpt", ".FPT"}
+
+// finishPack conclui a troca preparada por Pack. Com a tabela preparada,
+// os arquivos .pack substituem os originais (memo primeiro); sem ela, um
+// memo preparado é descartado, pois os originais continuam consistentes.
+func finishPack(path string) error {
+	base := path[:len(path)-len(filepath.Ext(path))]
+	_, err := os.Stat(path + packSuffix)
+	committed := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, ext := range memoExts {
+		staged := base + ext + packSuffix
+		if _, err := os.Stat(staged); err != nil {
+			continue
+		}
+		if !committed {
+			if err := os.Remove(staged); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := replaceFile(staged, base+ext); err != nil {
+			return err
+		}
+	}
+	if !committed {
+		return nil
+	}
+	return replaceFile(path+packSuffix, path)
+}
+
+// reindexPacked reconstrói o índice estrutural, se houver: os números de
+// registro mudaram com a compactação.
+func reindexPacked(path string, opts *OpenOptions) error {
+	db, err := OpenForUpdate(path, opts)
+	if err != nil {
+		return err
+	}
+	err = db.Reindex()
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// packRecords copia os registros não excluídos para dst (após o header) e
+// devolve quantos foram mantidos. Memos são copiados para mw e os
+// ponteiros do registro, reescritos.
+func (d *DBF) packRecords(src io.ReaderAt, dst *os.File, headerLen int, memo *memoFile, mw *memoWriter) (uint32, error) {
+	total := int64(d.RecordCount) * int64(d.recordLen)
+	r := bufio.NewReader(io.NewSectionReader(src, int64(d.headerLen), total))
+	w := bufio.NewWriter(dst)
+	if _, err := dst.Seek(int64(headerLen), io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, d.recordLen)
+	var kept uint32
+	for i := uint32(0); i < d.RecordCount; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break // arquivo menor que o header indica
+			}
+			return 0, fmt.Errorf("lendo registro %d: %w", i, err)
+		}
+		if buf[0] == 0x2A {
+			continue
+		}
+		if mw != nil {
+			if err := d.copyMemos(buf, memo, mw); err != nil {
+				return 0, fmt.Errorf("registro %d: %w", i, err)
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return 0, fmt.Errorf("gravando registro: %w", err)
+		}
+		kept++
+	}
+	if err := w.WriteByte(0x1A); err != nil {
+		return 0, err
+	}
+	return kept, w.Flush()
+}
+
+func (d *DBF) copyMemos(buf []byte, memo *memoFile, mw *memoWriter) error {
+	for i, f := range d.Fields {
+		off := d.fieldOffset(i)
+		field := buf[off : off+int(f.Size)]
+		if !isMemoType(f.Type) {
+			continue
+		}
+		block, err := memoBlock(field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if block == 0 {
+			continue
+		}
+		raw, err := memo.readRaw(block)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		nb, err := mw.write(raw)
+		if err != nil {
+			return err
+		}
+		putMemoBlock(field, nb)
+	}
+	return nil
+}
+
+func finishPackedHeader(f *os.File, hdr []byte, count uint32) error {
+	now := time.Now()
+	putHeaderDate(hdr, now)
+	binary.LittleEndian.PutUint32(hdr[4:8], count)
+	if _, err := f.WriteAt(hdr, 0); err != nil {
+		return fmt.Errorf("gravando header: %w", err)
+	}
+	return nil
+}
+
+// replaceFile renomeia tmp sobre dst preservando as permissões de dst.
+func replaceFile(tmp, dst string) error {
+	if st, err := os.Stat(dst); err == nil {
+		if err := os.Chmod(tmp, st.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, dst)
+}
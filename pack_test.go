@@ -0,0 +1,179 @@
+package dbfmini
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackRemovesDeletedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41, "_deleted": true},
+		Record{"NAME": "Carla", "AGE": 25},
+		Record{"NAME": "Davi", "AGE": 19, "_deleted": true},
+	)
+	before, _ := os.Stat(path)
+
+	if err := Pack(path, nil); err != nil {
+		t.Fatalf("Pack returned error: %v", err)
+	}
+
+	db, err := Open(path, &OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.RecordCount != 2 {
+		t.Fatalf("RecordCount = %d, want 2", db.RecordCount)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 2 || records[0]["NAME"] != "Ana" || records[1]["NAME"] != "Carla" {
+		t.Fatalf("records = %#v, want Ana and Carla", records)
+	}
+
+	after, _ := os.Stat(path)
+	if want := before.Size() - 2*int64(db.recordLen); after.Size() != want {
+		t.Fatalf("file size = %d, want %d", after.Size(), want)
+	}
+	if leftovers, _ := filepath.Glob(path + ".pack-*"); len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestPackCompactsMemoFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", "         2"},
+		{"  3", "         4"},
+	}))
+
+	memo := make([]byte, 5*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 5)
+	copy(memo[512:], "primeiro\x1a\x1a")
+	for i := 1024; i < 1024+600; i++ {
+		memo[i] = 'x'
+	}
+	copy(memo[1024+600:], "\x1a\x1a") // memo de dois blocos
+	copy(memo[2048:], "terceiro\x1a\x1a")
+	memoPath := writeFixture(t, dir, "notes.dbt", memo)
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.Delete(1); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	db.Close()
+
+	if err := Pack(path, nil); err != nil {
+		t.Fatalf("Pack returned error: %v", err)
+	}
+
+	db, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 2 || records[0]["NOTES"] != "primeiro" || records[1]["NOTES"] != "terceiro" {
+		t.Fatalf("records = %#v, want primeiro and terceiro", records)
+	}
+
+	st, err := os.Stat(memoPath)
+	if err != nil {
+		t.Fatalf("stat memo: %v", err)
+	}
+	if st.Size() != 3*512 {
+		t.Fatalf("memo size = %d, want %d", st.Size(), 3*512)
+	}
+	data, _ := os.ReadFile(memoPath)
+	if next := binary.LittleEndian.Uint32(data[0:4]); next != 3 {
+		t.Fatalf("next free block = %d, want 3", next)
+	}
+}
+
+func TestPackFinishesInterruptedSwap(t *testing.T) {
+	// tabela e memo compactados, gerados em outro diretório
+	packed := t.TempDir()
+	path := writeFixture(t, packed, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", "         2"},
+	}))
+	memo := make([]byte, 3*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 3)
+	copy(memo[512:], "primeiro\x1a\x1a")
+	copy(memo[1024:], "segundo\x1a\x1a")
+	writeFixture(t, packed, "notes.dbt", memo)
+	orig, _ := os.ReadFile(path)
+	origMemo, _ := os.ReadFile(filepath.Join(packed, "notes.dbt"))
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.Delete(0); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	db.Close()
+	if err := Pack(path, nil); err != nil {
+		t.Fatalf("Pack returned error: %v", err)
+	}
+	newTable, _ := os.ReadFile(path)
+	newMemo, _ := os.ReadFile(filepath.Join(packed, "notes.dbt"))
+
+	check := func(t *testing.T, path string, want ...string) {
+		t.Helper()
+		db, err := Open(path, nil)
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		records, err := db.ReadRecords(0)
+		if err != nil {
+			t.Fatalf("ReadRecords returned error: %v", err)
+		}
+		if len(records) != len(want) {
+			t.Fatalf("records = %#v, want %v", records, want)
+		}
+		for i, w := range want {
+			if records[i]["NOTES"] != w {
+				t.Fatalf("records = %#v, want %v", records, want)
+			}
+		}
+	}
+
+	// queda após trocar o memo e antes de trocar a tabela
+	dir := t.TempDir()
+	path = writeFixture(t, dir, "notes.dbf", orig)
+	writeFixture(t, dir, "notes.dbt", newMemo)
+	writeFixture(t, dir, "notes.dbf.pack", newTable)
+	if _, err := Open(path, nil); !errors.Is(err, ErrPackPending) {
+		t.Fatalf("Open: expected ErrPackPending, got %v", err)
+	}
+	if err := Pack(path, nil); err != nil {
+		t.Fatalf("Pack returned error: %v", err)
+	}
+	check(t, path, "segundo")
+
+	// queda antes do ponto de confirmação: o memo preparado é descartado
+	dir = t.TempDir()
+	path = writeFixture(t, dir, "notes.dbf", orig)
+	writeFixture(t, dir, "notes.dbt", origMemo)
+	writeFixture(t, dir, "notes.dbt.pack", newMemo)
+	if err := finishPack(path); err != nil {
+		t.Fatalf("finishPack returned error: %v", err)
+	}
+	check(t, path, "primeiro", "segundo")
+	if _, err := os.Stat(filepath.Join(dir, "notes.dbt.pack")); err == nil {
+		t.Fatalf("staged memo should have been removed")
+	}
+}
@@ -0,0 +1,575 @@
+package dbfmini
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------- Consultas ---------------------------
+
+// Query descreve uma leitura filtrada da tabela. Os predicados de Where
+// são testados sobre os bytes brutos de cada registro, antes de qualquer
+// decodificação; só os registros aceitos têm decodificadas as colunas de
+// Select (todas, se Select não for chamado).
+//
+//	rows, err := db.Query().
+//		Where(dbfmini.Col("UF").Eq("SP"), dbfmini.Col("SALDO").Gt(0)).
+//		Select("NOME", "SALDO").
+//		Limit(100).
+//		Rows()
+//
+// Registros excluídos seguem OpenOptions.IncludeDeleted; "_recno" é
+// incluído com OpenOptions.IncludeRecno. A consulta tem cursor próprio e
+// não altera o de Next/ReadRecords.
+type Query struct {
+	d     *DBF
+	where []Predicate
+	cols  []string
+	limit int
+}
+
+// Query inicia uma consulta sobre a tabela.
+func (d *DBF) Query() *Query { return &Query{d: d} }
+
+// Where acrescenta predicados; todos precisam ser verdadeiros.
+func (q *Query) Where(ps ...Predicate) *Query {
+	q.where = append(q.where, ps...)
+	return q
+}
+
+// Select limita as colunas decodificadas em cada registro.
+func (q *Query) Select(cols ...string) *Query {
+	q.cols = append(q.cols, cols...)
+	return q
+}
+
+// Limit interrompe a consulta após n registros (n <= 0: sem limite).
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Rows executa a consulta. Chame Close se abandonar o laço antes do fim.
+func (q *Query) Rows() (*Rows, error) {
+	d := q.d
+	test, err := And(q.where...).compile(d)
+	if err != nil {
+		return nil, err
+	}
+	var cols []int
+	if len(q.cols) == 0 {
+		for i := range d.Fields {
+			cols = append(cols, i)
+		}
+	}
+	for _, name := range q.cols {
+		i := lookupField(d.Fields, name)
+		if i < 0 {
+			return nil, fmt.Errorf("coluna desconhecida: %s", name)
+		}
+		cols = append(cols, i)
+	}
+	r := &Rows{d: d, test: test, cols: cols, limit: q.limit}
+	if d.RecordCount > 0 {
+		if r.scan, err = d.openScan(0); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// All executa a consulta e devolve todos os registros aceitos.
+func (q *Query) All() ([]Record, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Record
+	for rows.Next() {
+		out = append(out, rows.Record())
+	}
+	return out, rows.Err()
+}
+
+// Count conta os registros aceitos sem decodificar nenhuma coluna.
+func (q *Query) Count() (int, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n := 0
+	for rows.advance() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// Rows percorre o resultado de uma consulta.
+type Rows struct {
+	d     *DBF
+	scan  *scanState
+	test  rowTest
+	cols  []int
+	limit int
+	read  uint32 // registros lidos
+	found int
+	recno uint32
+	cur   Record
+	err   error
+}
+
+// Next avança para o próximo registro aceito. Em ReadLoose, registros que
+// não decodificam são pulados, como em DBF.Next.
+func (r *Rows) Next() bool {
+	r.cur = nil
+	for r.advance() {
+		rec, err := r.decode(r.scan.buf)
+		if err != nil {
+			if r.d.opt.ReadMode == ReadStrict {
+				r.fail(fmt.Errorf("registro %d: %w", r.recno, err))
+				return false
+			}
+			r.found-- // pulado: não conta para Limit
+			continue
+		}
+		r.cur = rec
+		return true
+	}
+	return false
+}
+
+// advance avança até o próximo registro aceito, sem decodificá-lo.
+func (r *Rows) advance() bool {
+	if r.scan == nil || r.err != nil {
+		return false
+	}
+	d := r.d
+	for r.read < d.RecordCount && (r.limit <= 0 || r.found < r.limit) {
+		if _, err := io.ReadFull(r.scan.r, r.scan.buf); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break // arquivo menor que o header indica
+			}
+			r.fail(fmt.Errorf("lendo registro: %w", err))
+			return false
+		}
+		r.read++
+		row := r.scan.buf
+		if row[0] == 0x2A && !d.opt.IncludeDeleted {
+			continue
+		}
+		ok, err := r.test(row, r.read)
+		if err != nil {
+			if d.opt.ReadMode == ReadStrict {
+				r.fail(fmt.Errorf("registro %d: %w", r.read, err))
+				return false
+			}
+			continue
+		}
+		if ok {
+			r.found++
+			r.recno = r.read
+			return true
+		}
+	}
+	r.Close()
+	return false
+}
+
+// decode converte só as colunas selecionadas do registro bruto.
+func (r *Rows) decode(row []byte) (Record, error) {
+	d := r.d
+	rec := make(Record, len(r.cols)+2)
+	for _, i := range r.cols {
+		v, ok, err := d.decodeCell(i, row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rec[d.Fields[i].Name] = v
+		}
+	}
+	if row[0] == 0x2A {
+		rec["_deleted"] = true
+	}
+	d.tagRecno(rec, r.recno)
+	return rec, nil
+}
+
+func (r *Rows) fail(err error) {
+	r.err = err
+	r.Close()
+}
+
+// Record devolve o registro carregado pelo último Next.
+func (r *Rows) Record() Record { return r.cur }
+
+// Recno devolve o número (base 1) do registro atual.
+func (r *Rows) Recno() uint32 { return r.recno }
+
+// Err devolve o erro que interrompeu Next, se houver.
+func (r *Rows) Err() error { return r.err }
+
+// Close libera o arquivo; Next passa a devolver false.
+func (r *Rows) Close() error {
+	if r.scan != nil {
+		r.scan.close()
+		r.scan = nil
+	}
+	return nil
+}
+
+// --------------------------- Predicados ---------------------------
+
+// rowTest testa um registro bruto (com o byte de exclusão).
+type rowTest func(row []byte, recno uint32) (bool, error)
+
+// Predicate é uma condição para Query.Where, compilada contra o esquema
+// da tabela quando a consulta é executada.
+type Predicate struct {
+	compile func(d *DBF) (rowTest, error)
+}
+
+// Column referencia uma coluna em predicados; veja Col.
+type Column struct{ name string }
+
+// Col referencia a coluna name (sem diferenciar maiúsculas).
+func Col(name string) Column { return Column{name: name} }
+
+// Eq aceita registros cujo valor é igual a v; Eq(nil) equivale a IsEmpty.
+// Textos são comparados sem os espaços à direita do campo.
+func (c Column) Eq(v any) Predicate {
+	if v == nil {
+		return c.IsEmpty()
+	}
+	return c.compare(v, func(n int) bool { return n == 0 })
+}
+
+// Ne aceita registros com valor diferente de v. Como nos demais
+// comparadores, campos vazios que não sejam texto nunca são aceitos.
+func (c Column) Ne(v any) Predicate {
+	if v == nil {
+		return Not(c.IsEmpty())
+	}
+	return c.compare(v, func(n int) bool { return n != 0 })
+}
+
+// Lt aceita valores menores que v.
+func (c Column) Lt(v any) Predicate { return c.compare(v, func(n int) bool { return n < 0 }) }
+
+// Le aceita valores menores ou iguais a v.
+func (c Column) Le(v any) Predicate { return c.compare(v, func(n int) bool { return n <= 0 }) }
+
+// Gt aceita valores maiores que v.
+func (c Column) Gt(v any) Predicate { return c.compare(v, func(n int) bool { return n > 0 }) }
+
+// Ge aceita valores maiores ou iguais a v.
+func (c Column) Ge(v any) Predicate { return c.compare(v, func(n int) bool { return n >= 0 }) }
+
+// Between aceita valores entre lo e hi, inclusive.
+func (c Column) Between(lo, hi any) Predicate { return And(c.Ge(lo), c.Le(hi)) }
+
+// In aceita valores iguais a algum de vs.
+func (c Column) In(vs ...any) Predicate {
+	ps := make([]Predicate, len(vs))
+	for i, v := range vs {
+		ps[i] = c.Eq(v)
+	}
+	return Or(ps...)
+}
+
+// IsEmpty aceita campos vazios: os que decodificam para nil e, para
+// texto, os só com espaços.
+func (c Column) IsEmpty() Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		i, cell, err := c.cell(d)
+		if err != nil {
+			return nil, err
+		}
+		off, size := d.fieldOffset(i), int(d.Fields[i].Size)
+		return func(row []byte, _ uint32) (bool, error) {
+			return d.isNull(i, row) || cell(row[off:off+size]).empty, nil
+		}, nil
+	}}
+}
+
+// HasPrefix aceita campos texto que começam por s.
+func (c Column) HasPrefix(s string) Predicate {
+	return c.text(s, func(field, s []byte) bool { return bytes.HasPrefix(field, s) })
+}
+
+// Contains aceita campos texto que contêm s.
+func (c Column) Contains(s string) Predicate {
+	return c.text(s, func(field, s []byte) bool { return bytes.Contains(bytes.TrimRight(field, " "), s) })
+}
+
+func (c Column) text(s string, match func(field, s []byte) bool) Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		i := lookupField(d.Fields, c.name)
+		if i < 0 {
+			return nil, fmt.Errorf("coluna desconhecida: %s", c.name)
+		}
+		if d.Fields[i].Type != 'C' {
+			return nil, fmt.Errorf("%s: busca de texto em campo %q", d.Fields[i].Name, string(d.Fields[i].Type))
+		}
+		key, err := encodeBytes(s, d.fieldCodec(i))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Fields[i].Name, err)
+		}
+		off, size := d.fieldOffset(i), int(d.Fields[i].Size)
+		return func(row []byte, _ uint32) (bool, error) {
+			return !d.isNull(i, row) && match(row[off:off+size], key), nil
+		}, nil
+	}}
+}
+
+// compare monta um predicado que compara o campo com v e aplica ok ao
+// resultado (-1, 0 ou 1); campos vazios (exceto texto, que vale "") e
+// .NULL. nunca são aceitos.
+func (c Column) compare(v any, ok func(int) bool) Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		i := lookupField(d.Fields, c.name)
+		if i < 0 {
+			return nil, fmt.Errorf("coluna desconhecida: %s", c.name)
+		}
+		cmp, err := d.rawComparer(i, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Fields[i].Name, err)
+		}
+		off, size := d.fieldOffset(i), int(d.Fields[i].Size)
+		return func(row []byte, _ uint32) (bool, error) {
+			if d.isNull(i, row) {
+				return false, nil
+			}
+			n, empty := cmp(row[off : off+size])
+			return !empty && ok(n), nil
+		}, nil
+	}}
+}
+
+// cell devolve o campo da coluna e a função que lê seu valor bruto.
+func (c Column) cell(d *DBF) (int, func([]byte) rawValue, error) {
+	i := lookupField(d.Fields, c.name)
+	if i < 0 {
+		return 0, nil, fmt.Errorf("coluna desconhecida: %s", c.name)
+	}
+	f := d.Fields[i]
+	switch f.Type {
+	case 'M', 'G', 'W':
+		return 0, nil, fmt.Errorf("%s: campo memo não pode ser filtrado (use Cond)", f.Name)
+	case 'V', 'Q':
+		return 0, nil, fmt.Errorf("%s: campo de tamanho variável não pode ser filtrado (use Cond)", f.Name)
+	}
+	return i, func(b []byte) rawValue { return readRawValue(f, b) }, nil
+}
+
+// And aceita registros que satisfazem todos os predicados.
+func And(ps ...Predicate) Predicate {
+	return combine(ps, true)
+}
+
+// Or aceita registros que satisfazem algum dos predicados.
+func Or(ps ...Predicate) Predicate {
+	return combine(ps, false)
+}
+
+func combine(ps []Predicate, all bool) Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		tests := make([]rowTest, len(ps))
+		for i, p := range ps {
+			t, err := p.compile(d)
+			if err != nil {
+				return nil, err
+			}
+			tests[i] = t
+		}
+		return func(row []byte, recno uint32) (bool, error) {
+			for _, t := range tests {
+				ok, err := t(row, recno)
+				if err != nil || ok != all {
+					return ok, err
+				}
+			}
+			return all, nil
+		}, nil
+	}}
+}
+
+// Not inverte o predicado.
+func Not(p Predicate) Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		t, err := p.compile(d)
+		if err != nil {
+			return nil, err
+		}
+		return func(row []byte, recno uint32) (bool, error) {
+			ok, err := t(row, recno)
+			return !ok, err
+		}, nil
+	}}
+}
+
+// Deleted aceita registros marcados como excluídos (só aparecem com
+// OpenOptions.IncludeDeleted).
+func Deleted() Predicate {
+	return Predicate{compile: func(*DBF) (rowTest, error) {
+		return func(row []byte, _ uint32) (bool, error) { return row[0] == 0x2A, nil }, nil
+	}}
+}
+
+// Cond aceita registros para os quais a expressão xBase src é verdadeira.
+// Só os campos citados na expressão são decodificados para testá-la.
+func Cond(src string) Predicate {
+	return Predicate{compile: func(d *DBF) (rowTest, error) {
+		e, err := d.CompileExpr(src)
+		if err != nil {
+			return nil, err
+		}
+		if t := e.Type(); t != 'L' {
+			return nil, fmt.Errorf("%s: expressão não lógica", src)
+		}
+		var cols []int
+		for _, name := range e.Fields() {
+			cols = append(cols, lookupField(d.Fields, name))
+		}
+		return func(row []byte, recno uint32) (bool, error) {
+			rec := make(Record, len(cols)+2)
+			for _, i := range cols {
+				v, _, err := d.decodeCell(i, row)
+				if err != nil {
+					return false, err
+				}
+				rec[d.Fields[i].Name] = v
+			}
+			rec["_deleted"] = row[0] == 0x2A
+			rec["_recno"] = recno
+			return e.Bool(rec)
+		}, nil
+	}}
+}
+
+// --------------------------- Valores brutos ---------------------------
+
+// rawValue é o valor de um campo lido direto dos bytes do registro, em uma
+// forma comparável: texto (sem espaços à direita), número ou bytes
+// ordenáveis (datas).
+type rawValue struct {
+	empty bool
+	text  []byte
+	num   float64
+	isNum bool
+}
+
+// readRawValue lê o campo f sem decodificar a página de códigos.
+func readRawValue(f Field, b []byte) rawValue {
+	switch f.Type {
+	case 'C':
+		t := bytes.TrimRight(b, " ")
+		return rawValue{text: t, empty: len(t) == 0}
+	case 'N', 'F':
+		t := bytes.TrimSpace(b)
+		if len(t) == 0 {
+			return rawValue{empty: true}
+		}
+		s := string(t)
+		if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+			s = strings.ReplaceAll(s, ",", ".")
+		}
+		x, err := strconv.ParseFloat(s, 64)
+		return rawValue{num: x, isNum: true, empty: err != nil}
+	case 'Y':
+		return rawValue{num: float64(int64(binary.LittleEndian.Uint64(b))) / 10000, isNum: true}
+	case 'B':
+		return rawValue{num: math.Float64frombits(binary.LittleEndian.Uint64(b)), isNum: true}
+	case 'I':
+		return rawValue{num: float64(int32(binary.LittleEndian.Uint32(b))), isNum: true}
+	case 'L':
+		switch b[0] {
+		case 'T', 't', 'Y', 'y':
+			return rawValue{num: 1, isNum: true}
+		case 'F', 'f', 'N', 'n':
+			return rawValue{num: 0, isNum: true}
+		}
+		return rawValue{empty: true}
+	case 'D':
+		t := bytes.TrimSpace(b)
+		if len(t) != 8 || bytes.Equal(t, []byte("00000000")) {
+			return rawValue{empty: true}
+		}
+		return rawValue{text: t}
+	case 'T':
+		jd := int32(binary.LittleEndian.Uint32(b[:4]))
+		ms := int32(binary.LittleEndian.Uint32(b[4:8]))
+		if jd == 0 && ms == 0 {
+			return rawValue{empty: true}
+		}
+		return rawValue{num: float64(jd)*86400000 + float64(ms), isNum: true}
+	}
+	return rawValue{empty: true}
+}
+
+// rawComparer converte v para a forma bruta do campo i e devolve a função
+// que o compara com os bytes do campo.
+func (d *DBF) rawComparer(i int, v any) (func(b []byte) (int, bool), error) {
+	f := d.Fields[i]
+	var want rawValue
+	switch f.Type {
+	case 'C':
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("esperado string, recebido %T", v)
+		}
+		b, err := encodeBytes(s, d.fieldCodec(i))
+		if err != nil {
+			return nil, err
+		}
+		want.text = bytes.TrimRight(b, " ")
+	case 'N', 'F', 'Y', 'B', 'I':
+		x, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		want.num = x
+	case 'L':
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("esperado bool, recebido %T", v)
+		}
+		if b {
+			want.num = 1
+		}
+	case 'D':
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("esperado time.Time, recebido %T", v)
+		}
+		want.text = []byte(t.Format("20060102"))
+	case 'T':
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("esperado time.Time, recebido %T", v)
+		}
+		jd, ms := utcToVFPDateTime(t.UTC())
+		want.num = float64(jd)*86400000 + float64(ms)
+	default:
+		return nil, fmt.Errorf("tipo %q não pode ser filtrado (use Cond)", string(f.Type))
+	}
+	return func(b []byte) (int, bool) {
+		got := readRawValue(f, b)
+		if got.empty && f.Type != 'C' {
+			return 0, true
+		}
+		if got.isNum {
+			return compareFloat(got.num, want.num), false
+		}
+		return bytes.Compare(got.text, want.text), false
+	}, nil
+}
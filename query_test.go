@@ -0,0 +1,226 @@
+package dbfmini
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var queryFields = []Field{
+	{Name: "NOME", Type: 'C', Size: 10},
+	{Name: "UF", Type: 'C', Size: 2},
+	{Name: "SALDO", Type: 'N', Size: 10, DecimalPlaces: 2},
+	{Name: "QTD", Type: 'I'},
+	{Name: "ATIVO", Type: 'L'},
+	{Name: "NASC", Type: 'D'},
+	{Name: "STAMP", Type: 'T'},
+	{Name: "CURR", Type: 'Y'},
+}
+
+func writeQueryTable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clientes.dbf")
+	w, err := Create(path, queryFields, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	day := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+	rows := []Record{
+		{"NOME": "Ana", "UF": "SP", "SALDO": 150.5, "QTD": int32(3), "ATIVO": true,
+			"NASC": day(1990, 5, 17), "STAMP": day(2024, 1, 2).Add(10 * time.Hour), "CURR": 1.5},
+		{"NOME": "Bruno", "UF": "RJ", "SALDO": -20, "QTD": int32(7), "ATIVO": false,
+			"NASC": day(1985, 1, 1), "STAMP": day(2023, 6, 1), "CURR": 2.25},
+		{"NOME": "Carla", "UF": "SP", "SALDO": 0, "QTD": int32(1), "ATIVO": true,
+			"NASC": day(2001, 12, 31), "_deleted": true},
+		{"NOME": "Davi", "UF": "MG", "QTD": int32(5)},
+		{"NOME": "Élida", "UF": "SP", "SALDO": 99.99, "QTD": int32(-2), "ATIVO": false,
+			"NASC": day(1999, 9, 9), "STAMP": day(2024, 1, 2).Add(9 * time.Hour), "CURR": -4},
+	}
+	for _, r := range rows {
+		if err := w.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	return path
+}
+
+func queryNames(t *testing.T, q *Query) []string {
+	t.Helper()
+	recs, err := q.All()
+	if err != nil {
+		t.Fatalf("All returned error: %v", err)
+	}
+	var names []string
+	for _, r := range recs {
+		names = append(names, r["NOME"].(string))
+	}
+	return names
+}
+
+func TestQueryPredicates(t *testing.T) {
+	path := writeQueryTable(t)
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	ref := time.Date(2024, time.January, 2, 9, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		p    Predicate
+		want string
+	}{
+		{"eq text", Col("uf").Eq("SP"), "Ana,Élida"},
+		{"eq padded text", Col("NOME").Eq("Ana  "), "Ana"},
+		{"ne text", Col("UF").Ne("SP"), "Bruno,Davi"},
+		{"in", Col("UF").In("RJ", "MG"), "Bruno,Davi"},
+		{"prefix", Col("NOME").HasPrefix("B"), "Bruno"},
+		{"contains", Col("NOME").Contains("li"), "Élida"},
+		{"gt number", Col("SALDO").Gt(100), "Ana"},
+		{"le number", Col("SALDO").Le(99.99), "Bruno,Élida"},
+		{"empty number", Col("SALDO").Eq(nil), "Davi"},
+		{"not empty", Col("SALDO").Ne(nil), "Ana,Bruno,Élida"},
+		{"between int", Col("QTD").Between(3, 7), "Ana,Bruno,Davi"},
+		{"negative int", Col("QTD").Lt(0), "Élida"},
+		{"logical", Col("ATIVO").Eq(false), "Bruno,Élida"},
+		{"date", Col("NASC").Ge(time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)), "Ana,Élida"},
+		{"datetime", Col("STAMP").Gt(ref), "Ana"},
+		{"currency", Col("CURR").Ge(1.5), "Ana,Bruno"},
+		{"or/not", Or(Col("UF").Eq("MG"), Not(Col("ATIVO").Eq(false))), "Ana,Davi"},
+		{"cond", Cond("UPPER(NOME) = 'B' .OR. QTD * 2 = 10"), "Bruno,Davi"},
+	}
+	for _, c := range cases {
+		got := queryNames(t, db.Query().Where(c.p))
+		if joined := strings.Join(got, ","); joined != c.want {
+			t.Fatalf("%s: expected %s, got %s", c.name, c.want, joined)
+		}
+	}
+
+	n, err := db.Query().Where(Col("UF").Eq("SP"), Col("SALDO").Gt(100)).Count()
+	if err != nil || n != 1 {
+		t.Fatalf("expected count 1, got %d/%v", n, err)
+	}
+}
+
+func TestQuerySelectLimitAndRows(t *testing.T) {
+	path := writeQueryTable(t)
+	db, err := Open(path, &OpenOptions{IncludeDeleted: true, IncludeRecno: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	rows, err := db.Query().Where(Col("UF").Eq("SP")).Select("nome", "SALDO").Limit(2).Rows()
+	if err != nil {
+		t.Fatalf("Rows returned error: %v", err)
+	}
+	var recnos []uint32
+	for rows.Next() {
+		rec := rows.Record()
+		if _, ok := rec["UF"]; ok || len(rec) > 4 {
+			t.Fatalf("unexpected columns: %v", rec)
+		}
+		if rec["_recno"] != rows.Recno() {
+			t.Fatalf("expected _recno %d, got %v", rows.Recno(), rec["_recno"])
+		}
+		recnos = append(recnos, rows.Recno())
+	}
+	if rows.Err() != nil {
+		t.Fatalf("Rows returned error: %v", rows.Err())
+	}
+	if len(recnos) != 2 || recnos[0] != 1 || recnos[1] != 3 {
+		t.Fatalf("expected recnos [1 3], got %v", recnos)
+	}
+
+	recs, err := db.Query().Where(Deleted()).All()
+	if err != nil || len(recs) != 1 || recs[0]["_deleted"] != true || recs[0]["NOME"] != "Carla" {
+		t.Fatalf("expected deleted Carla, got %v/%v", recs, err)
+	}
+
+	// a consulta não move o cursor de Next
+	if !db.Next() || db.Record()["NOME"] != "Ana" {
+		t.Fatalf("expected Next to start at the first record")
+	}
+	db.Close()
+}
+
+func TestQueryRowsLooseSkipsUnreadableRecords(t *testing.T) {
+	db, err := Open(writeTruncatedFixture(t), &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	for name, q := range map[string]*Query{
+		"all columns":  db.Query(),
+		"where on bad": db.Query().Select("A").Where(Cond("B = 'Bia'")),
+	} {
+		recs, err := q.All()
+		if err != nil || len(recs) != 0 {
+			t.Fatalf("%s: All() = %v, %v; want no records and no error", name, recs, err)
+		}
+	}
+	recs, err := db.Query().Select("A").All()
+	if err != nil || len(recs) != 1 || recs[0]["A"] != "Ana" {
+		t.Fatalf("Select(A).All() = %v, %v", recs, err)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	path := writeQueryTable(t)
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	for name, q := range map[string]*Query{
+		"unknown column": db.Query().Where(Col("CIDADE").Eq("X")),
+		"unknown select": db.Query().Select("CIDADE"),
+		"type mismatch":  db.Query().Where(Col("SALDO").Eq("1")),
+		"text on number": db.Query().Where(Col("SALDO").HasPrefix("1")),
+		"non logical":    db.Query().Where(Cond("NOME")),
+		"bad expression": db.Query().Where(Cond("NOME +")),
+		"date as string": db.Query().Where(Col("NASC").Eq("19900517")),
+		"bool as number": db.Query().Where(Col("ATIVO").Eq(1)),
+	} {
+		if _, err := q.Rows(); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestQueryRowsShareMemoWithNext(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", "         2"},
+	}))
+	memo := make([]byte, 3*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 3)
+	copy(memo[512:], "um\x1a\x1a")
+	copy(memo[1024:], "dois\x1a\x1a")
+	writeFixture(t, dir, "notes.dbt", memo)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query().Rows()
+	if err != nil {
+		t.Fatalf("Rows returned error: %v", err)
+	}
+	if !db.Next() || db.Record()["NOTES"] != "um" {
+		t.Fatalf("Next = %v, %v", db.Record(), db.Err())
+	}
+	if !rows.Next() || rows.Record()["NOTES"] != "um" {
+		t.Fatalf("rows.Next = %v, %v", rows.Record(), rows.Err())
+	}
+	// fechar a consulta não pode fechar o memo usado por Next
+	rows.Close()
+	if !db.Next() || db.Record()["NOTES"] != "dois" {
+		t.Fatalf("Next after rows.Close = %v, %v", db.Record(), db.Err())
+	}
+	if db.Next() || db.Err() != nil {
+		t.Fatalf("expected end of table, got %v", db.Err())
+	}
+}
@@ -0,0 +1,133 @@
+package dbfmini
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// --------------------------- Leitura em fluxo ---------------------------
+
+const scanBufferSize = 64 << 10
+
+// scanState mantém o arquivo aberto entre chamadas de Next.
+type scanState struct {
+	c       io.Closer
+	r       *bufio.Reader
+	buf     []byte
+	release func()
+}
+
+// Next avança para o próximo registro, a partir do mesmo cursor usado por
+// ReadRecords. Mantém um único handle e um buffer de registro reutilizado
+// até o fim do arquivo (ou até Close/Reset). Devolve false no fim ou em
+// caso de erro; consulte Err.
+//
+//	for db.Next() {
+//		rec := db.Record()
+//	}
+//	if err := db.Err(); err != nil { ... }
+func (d *DBF) Next() bool {
+	d.cur = nil
+	if d.err != nil {
+		return false
+	}
+	if d.scan == nil {
+		if d.recordsRead >= d.RecordCount {
+			d.recno = d.RecordCount + 1
+			return false
+		}
+		if err := d.startScan(); err != nil {
+			d.err = err
+			return false
+		}
+	}
+
+	for d.recordsRead < d.RecordCount {
+		if _, err := io.ReadFull(d.scan.r, d.scan.buf); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break // arquivo menor que o header indica
+			}
+			d.err = fmt.Errorf("lendo registro: %w", err)
+			d.stopScan()
+			return false
+		}
+		d.recordsRead++
+
+		rec, skip, err := d.parseRecord(d.scan.buf)
+		if err != nil {
+			if d.opt.ReadMode == ReadStrict {
+				d.err = err
+				d.stopScan()
+				return false
+			}
+			if skip {
+				continue
+			}
+		}
+		if rec != nil {
+			d.tagRecno(rec, d.recordsRead)
+			d.cur = rec
+			d.recno = d.recordsRead
+			return true
+		}
+	}
+	d.stopScan()
+	d.recno = d.RecordCount + 1
+	return false
+}
+
+// Record devolve o registro carregado pelo último Next.
+func (d *DBF) Record() Record { return d.cur }
+
+// Err devolve o erro que interrompeu Next, se houver.
+func (d *DBF) Err() error { return d.err }
+
+func (d *DBF) startScan() error {
+	s, err := d.openScan(d.recordsRead)
+	if err != nil {
+		return err
+	}
+	d.scan = s
+	return nil
+}
+
+// openScan abre a tabela para leitura sequencial a partir do registro de
+// índice from (base 0).
+func (d *DBF) openScan(from uint32) (*scanState, error) {
+	f, c, err := d.src()
+	if err != nil {
+		return nil, err
+	}
+	release, err := d.attachMemo()
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	start := int64(d.headerLen) + int64(from)*int64(d.recordLen)
+	size := int64(d.RecordCount-from) * int64(d.recordLen)
+	return &scanState{
+		c:       c,
+		r:       bufio.NewReaderSize(io.NewSectionReader(f, start, size), scanBufferSize),
+		buf:     make([]byte, d.recordLen),
+		release: release,
+	}, nil
+}
+
+func (d *DBF) stopScan() {
+	if d.scan == nil {
+		return
+	}
+	d.scan.close()
+	d.scan = nil
+}
+
+func (s *scanState) close() {
+	s.release()
+	if s.c != nil {
+		s.c.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package dbfmini
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNextStreamsRecords(t *testing.T) {
+	path := writeAllFieldsFixture(t)
+
+	db, err := Open(path, &OpenOptions{ReadMode: ReadLoose})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	var names []string
+	for db.Next() {
+		names = append(names, db.Record()["NAME"].(string))
+	}
+	if err := db.Err(); err != nil {
+		t.Fatalf("Err returned %v", err)
+	}
+	if len(names) != 2 || names[0] != "José" || names[1] != "" {
+		t.Fatalf("names = %q, want [José \"\"]", names)
+	}
+	if db.Next() {
+		t.Fatalf("Next after EOF should return false")
+	}
+
+	db.Reset()
+	if !db.Next() || db.Record()["NAME"] != "José" {
+		t.Fatalf("Next after Reset = %#v, want first record", db.Record())
+	}
+}
+
+func TestNextSharesCursorWithReadRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41},
+		Record{"NAME": "Carla", "AGE": 25},
+	)
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	if !db.Next() || db.Record()["NAME"] != "Ana" {
+		t.Fatalf("first Next = %#v, want Ana", db.Record())
+	}
+	records, err := db.ReadRecords(1)
+	if err != nil || len(records) != 1 || records[0]["NAME"] != "Bruno" {
+		t.Fatalf("ReadRecords(1) = %#v, %v; want Bruno", records, err)
+	}
+	if !db.Next() || db.Record()["NAME"] != "Carla" {
+		t.Fatalf("Next after ReadRecords = %#v, want Carla", db.Record())
+	}
+}
+
+func TestNextReportsStrictErrors(t *testing.T) {
+	dir := t.TempDir()
+	fields := []Field{{Name: "N", Type: 'N', Size: 3}}
+	path := writeFixture(t, dir, "bad.dbf", buildDBF(0x03, fields, [][]string{{"  1"}, {"abc"}}))
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if !db.Next() {
+		t.Fatalf("first Next returned false: %v", db.Err())
+	}
+	if db.Next() {
+		t.Fatalf("Next over invalid number should fail in strict mode")
+	}
+	if db.Err() == nil {
+		t.Fatalf("Err should report the invalid number")
+	}
+}
@@ -0,0 +1,151 @@
+package dbfmini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// --------------------------- Origens de dados ---------------------------
+
+// opener abre um arquivo para leitura aleatória. O io.Closer pode ser nil
+// quando quem chamou é dono do leitor.
+type opener func() (io.ReaderAt, io.Closer, error)
+
+// siblingFunc devolve um opener para name se o arquivo existir.
+type siblingFunc func(name string) (opener, bool)
+
+// OpenReader lê um DBF a partir de r (com size bytes), como um arquivo em
+// memória, um objeto de storage ou um upload HTTP. memo, se não nil, é o
+// conteúdo do .DBT/.FPT correspondente. Os leitores não são fechados pela
+// biblioteca.
+func OpenReader(r io.ReaderAt, size int64, memo io.ReaderAt, opts *OpenOptions) (*DBF, error) {
+	if r == nil {
+		return nil, errors.New("leitor nulo")
+	}
+	sr := io.NewSectionReader(r, 0, size)
+	var memoOpen opener
+	if memo != nil {
+		memoOpen = readerOpener(memo)
+	}
+	return openTable(readerOpener(sr), "", nil, memoOpen, opts)
+}
+
+// OpenFS lê o DBF name de fsys (zip.Reader, embed.FS, os.DirFS...). O
+// .DBT/.FPT é procurado no mesmo diretório de fsys.
+func OpenFS(fsys fs.FS, name string, opts *OpenOptions) (*DBF, error) {
+	sibling := func(n string) (opener, bool) {
+		if _, err := fs.Stat(fsys, n); err != nil {
+			return nil, false
+		}
+		return fsOpener(fsys, n), true
+	}
+	return openTable(fsOpener(fsys, name), name, sibling, nil, opts)
+}
+
+func fileOpener(path string) opener {
+	return func() (io.ReaderAt, io.Closer, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	}
+}
+
+func fileSibling(name string) (opener, bool) {
+	if _, err := os.Stat(name); err != nil {
+		return nil, false
+	}
+	return fileOpener(name), true
+}
+
+func readerOpener(r io.ReaderAt) opener {
+	return func() (io.ReaderAt, io.Closer, error) { return r, nil, nil }
+}
+
+// fsOpener usa io.ReaderAt quando o arquivo oferece; senão recorre a Seek
+// ou, em último caso, carrega o arquivo em memória (ex.: entradas
+// comprimidas de um zip). O conteúdo carregado fica guardado no opener, e
+// portanto no DBF, para que GoTo, ReadAt e memos não releiam (nem
+// descomprimam) o arquivo inteiro a cada abertura.
+func fsOpener(fsys fs.FS, name string) opener {
+	var (
+		mu   sync.Mutex
+		data []byte
+	)
+	return func() (io.ReaderAt, io.Closer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if data != nil {
+			return bytes.NewReader(data), nil, nil
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ra, ok := f.(io.ReaderAt); ok {
+			return ra, f, nil
+		}
+		if rs, ok := f.(io.ReadSeeker); ok {
+			return &seekReaderAt{rs: rs}, f, nil
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("lendo %s: %w", name, err)
+		}
+		data = b
+		return bytes.NewReader(data), nil, nil
+	}
+}
+
+// seekReaderAt adapta um io.ReadSeeker para io.ReaderAt.
+type seekReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// readerSize devolve o tamanho dos dados de r, se o leitor o informar
+// (bytes.Reader, io.SectionReader, *os.File, fs.File), ou -1.
+func readerSize(r io.ReaderAt) int64 {
+	switch x := r.(type) {
+	case interface{ Size() int64 }:
+		return x.Size()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if st, err := x.Stat(); err == nil && st.Mode().IsRegular() {
+			return st.Size()
+		}
+	}
+	return -1
+}
+
+// findSibling procura path com cada extensão alternativa.
+func findSibling(path string, sibling siblingFunc, exts ...string) (opener, string) {
+	base := path[:len(path)-len(filepath.Ext(path))]
+	for _, e := range exts {
+		p := base + e
+		if op, ok := sibling(p); ok {
+			return op, p
+		}
+	}
+	return nil, ""
+}
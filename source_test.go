@@ -0,0 +1,145 @@
+package dbfmini
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func dbaseIIIMemoFixture() (table, memo []byte) {
+	table = buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+		{"  2", ""},
+	})
+	memo = make([]byte, 2*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 2)
+	copy(memo[512:], "Cliente pontual\x1a\x1a")
+	return table, memo
+}
+
+func TestOpenReader(t *testing.T) {
+	table, memo := dbaseIIIMemoFixture()
+
+	db, err := OpenReader(bytes.NewReader(table), int64(len(table)), bytes.NewReader(memo), nil)
+	if err != nil {
+		t.Fatalf("OpenReader returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 2 || records[0]["NOTES"] != "Cliente pontual" {
+		t.Fatalf("records = %#v", records)
+	}
+
+	if _, err := OpenReader(bytes.NewReader(table), int64(len(table)), nil, nil); err == nil {
+		t.Fatalf("OpenReader without memo in strict mode should fail")
+	}
+}
+
+func TestOpenReaderHonorsSize(t *testing.T) {
+	db, err := OpenReader(bytes.NewReader(allFieldsDBF), int64(len(allFieldsDBF))-int64(len(allFieldsDBF)/4), nil, &OpenOptions{ReadMode: ReadLoose, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("OpenReader returned error: %v", err)
+	}
+	var n int
+	for db.Next() {
+		n++
+	}
+	if n >= 3 {
+		t.Fatalf("read %d records past the given size", n)
+	}
+}
+
+func TestOpenFSFindsMemoSibling(t *testing.T) {
+	table, memo := dbaseIIIMemoFixture()
+	fsys := fstest.MapFS{
+		"dados/notes.dbf": {Data: table},
+		"dados/notes.DBT": {Data: memo},
+	}
+
+	db, err := OpenFS(fsys, "dados/notes.dbf", nil)
+	if err != nil {
+		t.Fatalf("OpenFS returned error: %v", err)
+	}
+	defer db.Close()
+	if !db.Next() || db.Record()["NOTES"] != "Cliente pontual" {
+		t.Fatalf("first record = %#v, err = %v", db.Record(), db.Err())
+	}
+}
+
+func TestOpenFSFromZip(t *testing.T) {
+	table, memo := dbaseIIIMemoFixture()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{"notes.dbf": table, "notes.dbt": memo} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip reader: %v", err)
+	}
+	db, err := OpenFS(zr, "notes.dbf", nil)
+	if err != nil {
+		t.Fatalf("OpenFS returned error: %v", err)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 2 || records[0]["NOTES"] != "Cliente pontual" {
+		t.Fatalf("records = %#v", records)
+	}
+}
+
+// streamFS serve arquivos só com Read, como entradas comprimidas, e conta
+// quantas vezes cada um é aberto.
+type streamFS struct {
+	files fstest.MapFS
+	opens map[string]int
+}
+
+func (s *streamFS) Open(name string) (fs.File, error) {
+	f, err := s.files.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	s.opens[name]++
+	return struct{ fs.File }{f}, nil
+}
+
+func (s *streamFS) Stat(name string) (fs.FileInfo, error) { return fs.Stat(s.files, name) }
+
+func TestOpenFSBuffersStreamOnlyFiles(t *testing.T) {
+	table, memo := dbaseIIIMemoFixture()
+	fsys := &streamFS{
+		files: fstest.MapFS{"notes.dbf": {Data: table}, "notes.dbt": {Data: memo}},
+		opens: map[string]int{},
+	}
+	db, err := OpenFS(fsys, "notes.dbf", nil)
+	if err != nil {
+		t.Fatalf("OpenFS returned error: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 3; i++ {
+		rec, err := db.ReadAt(1)
+		if err != nil || rec["NOTES"] != "Cliente pontual" {
+			t.Fatalf("ReadAt(1) = %#v, %v", rec, err)
+		}
+	}
+	if fsys.opens["notes.dbf"] != 1 || fsys.opens["notes.dbt"] != 1 {
+		t.Fatalf("files read more than once: %v", fsys.opens)
+	}
+}
@@ -0,0 +1,359 @@
+// Package sqldriver registra o driver "dbf" de database/sql, que expõe um
+// diretório de arquivos DBF como tabelas somente leitura:
+//
+//	import _ "github.com/alberto255345/dbfmini/sqldriver"
+//
+//	db, err := sql.Open("dbf", "/data/erp?encoding=CP850")
+//	rows, err := db.Query(`SELECT NOME, SALDO FROM clientes
+//		WHERE UF = ? AND NOME LIKE 'JO%' ORDER BY SALDO DESC LIMIT 10`, "SP")
+//
+// Cada arquivo .dbf do diretório é uma tabela, com o nome do arquivo sem
+// extensão (sem diferenciar maiúsculas). O SQL aceito é um subconjunto de
+// SELECT: lista de colunas (ou *), COUNT(*), COUNT(col) e SUM(col) com AS,
+// WHERE com =, <>, !=, <, <=, >, >=, LIKE, IN, IS NULL, AND, OR, NOT e
+// parênteses, ORDER BY (por nome ou posição, ASC/DESC), LIMIT e OFFSET, e
+// parâmetros ?. Não há JOIN, GROUP BY nem escrita.
+//
+// Opções do DSN: encoding (página de códigos, como OpenOptions.Encoding),
+// mode=strict|loose (default strict), deleted=true para incluir registros
+// excluídos e numeric=decimal para ler N, F e Y como dbfmini.Decimal (que
+// chega ao Scan como texto exato; SUM também fica exato).
+//
+// Os tipos das colunas seguem o campo: ColumnTypeDatabaseTypeName devolve
+// CHARACTER, NUMERIC, FLOAT, CURRENCY, INTEGER, DOUBLE, LOGICAL, DATE,
+// DATETIME, MEMO, GENERAL, BLOB, VARCHAR ou VARBINARY, e
+// ColumnTypePrecisionScale e ColumnTypeLength vêm de Size e DecimalPlaces.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// ErrReadOnly é devolvido por Exec e Begin: o driver só lê.
+var ErrReadOnly = errors.New("driver dbf é somente leitura")
+
+func init() {
+	sql.Register("dbf", Driver{})
+}
+
+// Driver implementa driver.Driver.
+type Driver struct{}
+
+// Open abre uma conexão com o diretório indicado por dsn.
+func (Driver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(cfg.dir)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, fmt.Errorf("%s não é um diretório", cfg.dir)
+	}
+	return &conn{cfg: cfg}, nil
+}
+
+// config é o DSN interpretado.
+type config struct {
+	dir  string
+	opts dbfmini.OpenOptions
+}
+
+func parseDSN(dsn string) (config, error) {
+	cfg := config{dir: dsn}
+	if i := strings.LastIndexByte(dsn, '?'); i >= 0 {
+		cfg.dir = dsn[:i]
+		q, err := url.ParseQuery(dsn[i+1:])
+		if err != nil {
+			return cfg, fmt.Errorf("DSN inválido: %w", err)
+		}
+		for k, vs := range q {
+			v := vs[len(vs)-1]
+			switch k {
+			case "encoding":
+				cfg.opts.Encoding.Default = v
+			case "mode":
+				switch mode := dbfmini.ReadMode(strings.ToLower(v)); mode {
+				case dbfmini.ReadStrict, dbfmini.ReadLoose:
+					cfg.opts.ReadMode = mode
+				default:
+					return cfg, fmt.Errorf("DSN: mode inválido %q", v)
+				}
+			case "numeric":
+				switch mode := dbfmini.NumericMode(strings.ToLower(v)); mode {
+				case dbfmini.NumericFloat, dbfmini.NumericDecimal:
+					cfg.opts.Numeric = mode
+				default:
+					return cfg, fmt.Errorf("DSN: numeric inválido %q", v)
+				}
+			case "deleted":
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return cfg, fmt.Errorf("DSN: deleted inválido %q", v)
+				}
+				cfg.opts.IncludeDeleted = b
+			default:
+				return cfg, fmt.Errorf("DSN: opção desconhecida %q", k)
+			}
+		}
+	}
+	if cfg.dir == "" {
+		return cfg, errors.New("DSN sem diretório")
+	}
+	return cfg, nil
+}
+
+// --------------------------- Conexão ---------------------------
+
+type conn struct {
+	cfg    config
+	closed bool
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	if c.closed {
+		return nil, driver.ErrBadConn
+	}
+	st, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{c: c, st: st}, nil
+}
+
+func (c *conn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) { return nil, ErrReadOnly }
+
+// QueryContext evita o Prepare explícito em db.Query.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	s, err := c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.(*stmt).QueryContext(ctx, args)
+}
+
+// openTable abre o arquivo da tabela name.
+func (c *conn) openTable(name string) (*dbfmini.DBF, error) {
+	entries, err := os.ReadDir(c.cfg.dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		file := e.Name()
+		ext := filepath.Ext(file)
+		if e.IsDir() || !strings.EqualFold(ext, ".dbf") {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSuffix(file, ext), name) || strings.EqualFold(file, name) {
+			opts := c.cfg.opts
+			return dbfmini.Open(filepath.Join(c.cfg.dir, file), &opts)
+		}
+	}
+	return nil, fmt.Errorf("tabela não encontrada: %s", name)
+}
+
+// --------------------------- Comandos ---------------------------
+
+type stmt struct {
+	c  *conn
+	st *selectStmt
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return s.st.nargs }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) { return nil, ErrReadOnly }
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	db, err := s.c.openTable(s.st.table)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPlan(s.st, db.Fields, args)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	r, err := p.run(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	r.decimal = s.c.cfg.opts.Numeric == dbfmini.NumericDecimal
+	return r, nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("parâmetros nomeados não são suportados")
+		}
+		vals[i] = a.Value
+	}
+	return s.Query(vals)
+}
+
+// --------------------------- Resultado ---------------------------
+
+type rows struct {
+	cols []column
+	src  *dbfmini.Rows
+	db   *dbfmini.DBF
+	next func() ([]driver.Value, error)
+	// decimal indica NumericDecimal: N, F e Y chegam como Decimal.
+	decimal bool
+}
+
+func (r *rows) Columns() []string {
+	names := make([]string, len(r.cols))
+	for i, c := range r.cols {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (r *rows) Close() error {
+	r.src.Close()
+	return r.db.Close()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	v, err := r.next()
+	if err != nil {
+		return err
+	}
+	copy(dest, v)
+	return nil
+}
+
+var typeNames = map[byte]string{
+	'C': "CHARACTER",
+	'N': "NUMERIC",
+	'F': "FLOAT",
+	'Y': "CURRENCY",
+	'I': "INTEGER",
+	'B': "DOUBLE",
+	'L': "LOGICAL",
+	'D': "DATE",
+	'T': "DATETIME",
+	'M': "MEMO",
+	'G': "GENERAL",
+	'W': "BLOB",
+	'V': "VARCHAR",
+	'Q': "VARBINARY",
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(i int) string {
+	switch c := r.cols[i]; c.agg {
+	case "COUNT":
+		return "INTEGER"
+	case "SUM":
+		return "NUMERIC"
+	default:
+		return typeNames[c.field.Type]
+	}
+}
+
+func (r *rows) ColumnTypeLength(i int) (int64, bool) {
+	c := r.cols[i]
+	if c.agg != "" {
+		return 0, false
+	}
+	switch c.field.Type {
+	case 'C', 'V', 'Q':
+		return int64(c.field.Size), true
+	case 'M', 'G', 'W':
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(i int) (int64, int64, bool) {
+	c := r.cols[i]
+	if c.agg != "" {
+		return 0, 0, false
+	}
+	switch c.field.Type {
+	case 'N', 'F':
+		return int64(c.field.Size), int64(c.field.DecimalPlaces), true
+	case 'Y':
+		return 19, 4, true
+	}
+	return 0, 0, false
+}
+
+func (r *rows) ColumnTypeNullable(i int) (bool, bool) {
+	return r.cols[i].agg != "COUNT", true
+}
+
+var (
+	scanString = reflect.TypeOf("")
+	scanFloat  = reflect.TypeOf(float64(0))
+	scanInt    = reflect.TypeOf(int64(0))
+	scanBool   = reflect.TypeOf(false)
+	scanTime   = reflect.TypeOf(time.Time{})
+	scanBytes  = reflect.TypeOf([]byte(nil))
+	scanAny    = reflect.TypeOf((*any)(nil)).Elem()
+	scanDec    = reflect.TypeOf(dbfmini.Decimal{})
+)
+
+func (r *rows) ColumnTypeScanType(i int) reflect.Type {
+	c := r.cols[i]
+	switch c.agg {
+	case "COUNT":
+		return scanInt
+	case "SUM":
+		if r.decimal {
+			return scanDec
+		}
+		return scanFloat
+	}
+	switch c.field.Type {
+	case 'C', 'M', 'V':
+		return scanString
+	case 'N', 'F', 'Y':
+		switch {
+		case !r.decimal:
+			return scanFloat
+		case c.field.Type == 'N' && c.field.DecimalPlaces == 0:
+			return scanInt
+		}
+		return scanDec
+	case 'B':
+		return scanFloat
+	case 'I':
+		return scanInt
+	case 'L':
+		return scanBool
+	case 'D', 'T':
+		return scanTime
+	case 'G', 'W', 'Q':
+		return scanBytes
+	}
+	return scanAny
+}
@@ -0,0 +1,225 @@
+package sqldriver
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+	"github.com/alberto255345/dbfmini/internal/dbftest"
+)
+
+func writeTable(t *testing.T, dir string) {
+	t.Helper()
+	fields := []dbfmini.Field{
+		{Name: "NOME", Type: 'C', Size: 10},
+		{Name: "UF", Type: 'C', Size: 2},
+		{Name: "SALDO", Type: 'N', Size: 10, DecimalPlaces: 2},
+		{Name: "QTD", Type: 'I'},
+		{Name: "NASC", Type: 'D'},
+		{Name: "CURR", Type: 'Y'},
+	}
+	day := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+	dbftest.Table(t, filepath.Join(dir, "CLIENTES.DBF"), fields, nil, []dbfmini.Record{
+		{"NOME": "Ana", "UF": "SP", "SALDO": 150.5, "QTD": int32(3), "NASC": day(1990, 5, 17), "CURR": 1.5},
+		{"NOME": "Bruno", "UF": "RJ", "SALDO": -20, "QTD": int32(7), "NASC": day(1985, 1, 1)},
+		{"NOME": "Carla", "UF": "SP", "SALDO": 10, "QTD": int32(1), "_deleted": true},
+		{"NOME": "Davi", "UF": "MG", "QTD": int32(5)},
+		{"NOME": "Elisa", "UF": "SP", "SALDO": 99.99, "QTD": int32(-2), "NASC": day(1999, 9, 9)},
+	})
+}
+
+func openDB(t *testing.T, query string) *sql.DB {
+	t.Helper()
+	dir := t.TempDir()
+	writeTable(t, dir)
+	db, err := sql.Open("dbf", dir+query)
+	if err != nil {
+		t.Fatalf("sql.Open returned error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func queryStrings(t *testing.T, db *sql.DB, query string, args ...any) []string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("Query(%q) returned error: %v", query, err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err returned error: %v", err)
+	}
+	return out
+}
+
+func TestQueryWhereOrderLimit(t *testing.T) {
+	db := openDB(t, "")
+	cases := []struct {
+		query string
+		args  []any
+		want  []string
+	}{
+		{"SELECT nome FROM clientes", nil, []string{"Ana", "Bruno", "Davi", "Elisa"}},
+		{"SELECT nome FROM clientes WHERE uf = ? AND saldo > 100", []any{"SP"}, []string{"Ana"}},
+		{"SELECT nome FROM clientes WHERE uf = 'RJ' OR nome LIKE '_av%'", nil, []string{"Bruno", "Davi"}},
+		{"SELECT nome FROM clientes WHERE saldo IS NULL", nil, []string{"Davi"}},
+		{"SELECT nome FROM clientes WHERE qtd IN (3, 5) ORDER BY nome DESC", nil, []string{"Davi", "Ana"}},
+		{"SELECT nome FROM clientes WHERE nasc < '1995-01-01'", nil, []string{"Ana", "Bruno"}},
+		{"SELECT nome FROM clientes ORDER BY saldo DESC LIMIT 2 OFFSET 1", nil, []string{"Elisa", "Bruno"}},
+		{"SELECT nome FROM clientes LIMIT 2 OFFSET 1", nil, []string{"Bruno", "Davi"}},
+		{"SELECT nome AS n FROM clientes ORDER BY uf, n DESC", nil, []string{"Davi", "Bruno", "Elisa", "Ana"}},
+	}
+	for _, c := range cases {
+		if got := queryStrings(t, db, c.query, c.args...); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%s = %v, want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestQueryAggregates(t *testing.T) {
+	db := openDB(t, "")
+	var n, nSaldo int64
+	var total float64
+	err := db.QueryRow("SELECT COUNT(*), COUNT(saldo), SUM(saldo) AS total FROM clientes WHERE uf <> 'MG'").
+		Scan(&n, &nSaldo, &total)
+	if err != nil {
+		t.Fatalf("QueryRow returned error: %v", err)
+	}
+	if n != 3 || nSaldo != 3 || total < 230.48 || total > 230.50 {
+		t.Fatalf("aggregates = %d, %d, %v", n, nSaldo, total)
+	}
+	var sum sql.NullFloat64
+	if err := db.QueryRow("SELECT SUM(saldo) FROM clientes WHERE uf = 'MG'").Scan(&sum); err != nil {
+		t.Fatalf("QueryRow returned error: %v", err)
+	}
+	if sum.Valid {
+		t.Fatalf("SUM over NULLs = %v, want NULL", sum.Float64)
+	}
+	if _, err := db.Query("SELECT nome, COUNT(*) FROM clientes"); err == nil {
+		t.Fatalf("mixing columns and aggregates returned nil error")
+	}
+}
+
+func TestQueryDeletedOption(t *testing.T) {
+	db := openDB(t, "?deleted=true&mode=loose")
+	var n int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM clientes").Scan(&n); err != nil {
+		t.Fatalf("QueryRow returned error: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("COUNT(*) = %d, want 5", n)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	db := openDB(t, "")
+	rows, err := db.Query("SELECT * FROM clientes LIMIT 1")
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes returned error: %v", err)
+	}
+	want := []string{"CHARACTER", "CHARACTER", "NUMERIC", "INTEGER", "DATE", "CURRENCY"}
+	for i, ct := range types {
+		if ct.DatabaseTypeName() != want[i] {
+			t.Fatalf("%s type = %s, want %s", ct.Name(), ct.DatabaseTypeName(), want[i])
+		}
+	}
+	if p, s, ok := types[2].DecimalSize(); !ok || p != 10 || s != 2 {
+		t.Fatalf("SALDO DecimalSize = %d, %d, %v", p, s, ok)
+	}
+	if p, s, ok := types[5].DecimalSize(); !ok || p != 19 || s != 4 {
+		t.Fatalf("CURR DecimalSize = %d, %d, %v", p, s, ok)
+	}
+	if n, ok := types[0].Length(); !ok || n != 10 {
+		t.Fatalf("NOME Length = %d, %v", n, ok)
+	}
+	if st := types[3].ScanType(); st != reflect.TypeOf(int64(0)) {
+		t.Fatalf("QTD ScanType = %v", st)
+	}
+	if !rows.Next() {
+		t.Fatalf("no rows: %v", rows.Err())
+	}
+	var (
+		nome, uf string
+		saldo    float64
+		qtd      int64
+		nasc     time.Time
+		curr     float64
+	)
+	if err := rows.Scan(&nome, &uf, &saldo, &qtd, &nasc, &curr); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if nome != "Ana" || saldo != 150.5 || qtd != 3 || nasc.Year() != 1990 || curr != 1.5 {
+		t.Fatalf("row = %v %v %v %v %v", nome, saldo, qtd, nasc, curr)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	db := openDB(t, "")
+	for _, q := range []string{
+		"SELECT nome FROM fornecedores",
+		"SELECT cidade FROM clientes",
+		"SELECT nome FROM clientes ORDER BY 9",
+		"SELECT SUM(nome) FROM clientes",
+	} {
+		if _, err := db.Query(q); err == nil {
+			t.Fatalf("Query(%q) returned nil error", q)
+		}
+	}
+	if _, err := db.Exec("SELECT nome FROM clientes"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Exec error = %v, want ErrReadOnly", err)
+	}
+	bad, err := sql.Open("dbf", t.TempDir()+"?modo=x")
+	if err != nil {
+		t.Fatalf("sql.Open returned error: %v", err)
+	}
+	defer bad.Close()
+	if err := bad.Ping(); err == nil {
+		t.Fatalf("Ping with unknown DSN option returned nil error")
+	}
+}
+
+func TestQueryNumericDecimal(t *testing.T) {
+	db := openDB(t, "?numeric=decimal")
+	var total, curr dbfmini.Decimal
+	if err := db.QueryRow("SELECT SUM(saldo), SUM(curr) FROM clientes").Scan(&total, &curr); err != nil {
+		t.Fatalf("QueryRow returned error: %v", err)
+	}
+	if total != (dbfmini.Decimal{Unscaled: 23049, Scale: 2}) || curr != (dbfmini.Decimal{Unscaled: 15000, Scale: 4}) {
+		t.Fatalf("SUM = %v, %v", total, curr)
+	}
+	var saldo dbfmini.Decimal
+	var raw any
+	if err := db.QueryRow("SELECT saldo, saldo FROM clientes WHERE saldo = 99.99").Scan(&saldo, &raw); err != nil {
+		t.Fatalf("QueryRow returned error: %v", err)
+	}
+	if saldo != (dbfmini.Decimal{Unscaled: 9999, Scale: 2}) || raw != "99.99" {
+		t.Fatalf("saldo = %v, %#v", saldo, raw)
+	}
+	rows, err := db.Query("SELECT saldo FROM clientes")
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	defer rows.Close()
+	types, _ := rows.ColumnTypes()
+	if st := types[0].ScanType(); st != reflect.TypeOf(dbfmini.Decimal{}) {
+		t.Fatalf("ScanType = %v", st)
+	}
+}
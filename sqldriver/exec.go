@@ -0,0 +1,612 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// --------------------------- Execução ---------------------------
+
+// column é uma coluna do resultado: um campo da tabela ou um agregado.
+type column struct {
+	name  string
+	field dbfmini.Field // campo lido (em SUM, o campo somado)
+	agg   string
+}
+
+// plan é um SELECT associado ao esquema da tabela.
+type plan struct {
+	st      *selectStmt
+	fields  []dbfmini.Field
+	cols    []column
+	args    []any
+	needed  []string // campos decodificados
+	orderBy []sortKey
+	agg     bool
+}
+
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// newPlan resolve os nomes de colunas de st contra fields.
+func newPlan(st *selectStmt, fields []dbfmini.Field, args []driver.Value) (*plan, error) {
+	p := &plan{st: st, fields: fields}
+	for _, a := range args {
+		p.args = append(p.args, normValue(a))
+	}
+	seen := map[string]bool{}
+	need := func(name string) (dbfmini.Field, error) {
+		for _, f := range fields {
+			if strings.EqualFold(f.Name, name) {
+				if !seen[f.Name] {
+					seen[f.Name] = true
+					p.needed = append(p.needed, f.Name)
+				}
+				return f, nil
+			}
+		}
+		return dbfmini.Field{}, fmt.Errorf("coluna desconhecida: %s", name)
+	}
+
+	if st.star {
+		for _, f := range fields {
+			need(f.Name)
+			p.cols = append(p.cols, column{name: f.Name, field: f})
+		}
+	}
+	plain := false
+	for _, it := range st.items {
+		c := column{name: it.name(), agg: it.agg}
+		if it.col != "" {
+			f, err := need(it.col)
+			if err != nil {
+				return nil, err
+			}
+			c.field = f
+			if it.agg == "" && it.alias == "" {
+				c.name = f.Name
+			}
+			if it.agg == "SUM" && !isNumeric(f.Type) {
+				return nil, fmt.Errorf("SUM(%s): campo não numérico", f.Name)
+			}
+		}
+		if it.agg != "" {
+			p.agg = true
+		} else {
+			plain = true
+		}
+		p.cols = append(p.cols, c)
+	}
+	if p.agg && plain {
+		return nil, errors.New("colunas e agregados misturados exigem GROUP BY, que não é suportado")
+	}
+
+	var err error
+	if st.where != nil {
+		err = st.where.walk(func(n *node) error {
+			if n.kind == nodeCol {
+				f, err := need(n.col)
+				n.col = f.Name
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range st.orderBy {
+		if p.agg {
+			break // uma única linha
+		}
+		name := o.col
+		if o.pos > 0 {
+			if o.pos > len(p.cols) {
+				return nil, fmt.Errorf("ORDER BY %d: o SELECT tem %d colunas", o.pos, len(p.cols))
+			}
+			name = p.cols[o.pos-1].field.Name
+		} else {
+			for _, c := range p.cols {
+				if strings.EqualFold(c.name, o.col) {
+					name = c.field.Name
+				}
+			}
+		}
+		f, err := need(name)
+		if err != nil {
+			return nil, err
+		}
+		p.orderBy = append(p.orderBy, sortKey{field: f.Name, desc: o.desc})
+	}
+	return p, nil
+}
+
+func (n *node) walk(fn func(*node) error) error {
+	if n == nil {
+		return nil
+	}
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, c := range append([]*node{n.left, n.right}, n.list...) {
+		if err := c.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run executa o plano sobre db. O resultado sem ORDER BY nem agregados é
+// lido sob demanda; os demais são calculados antes da primeira linha. Em
+// caso de erro, fechar db cabe ao chamador.
+func (p *plan) run(db *dbfmini.DBF) (*rows, error) {
+	q := db.Query()
+	if len(p.needed) > 0 {
+		q = q.Select(p.needed...)
+	} else {
+		q = q.Select(p.fields[0].Name) // COUNT(*): basta um campo
+	}
+	src, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	r := &rows{cols: p.cols, src: src, db: db}
+	match := func() (dbfmini.Record, error) {
+		for src.Next() {
+			rec := src.Record()
+			if p.st.where == nil {
+				return rec, nil
+			}
+			v, err := p.eval(p.st.where, rec)
+			if err != nil {
+				return nil, err
+			}
+			if v == true {
+				return rec, nil
+			}
+		}
+		if err := src.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	switch {
+	case p.agg:
+		out, err := p.aggregate(match)
+		if err != nil {
+			src.Close()
+			return nil, err
+		}
+		var recs [][]driver.Value
+		if p.st.offset == 0 && p.st.limit != 0 {
+			recs = append(recs, out)
+		}
+		r.next = fromSlice(recs)
+	case len(p.orderBy) > 0:
+		var recs []dbfmini.Record
+		for {
+			rec, err := match()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				src.Close()
+				return nil, err
+			}
+			recs = append(recs, rec)
+		}
+		if err := p.sort(recs); err != nil {
+			src.Close()
+			return nil, err
+		}
+		recs = window(recs, p.st.offset, p.st.limit)
+		out := make([][]driver.Value, len(recs))
+		for i, rec := range recs {
+			out[i] = p.project(rec)
+		}
+		r.next = fromSlice(out)
+	default:
+		skip, left := p.st.offset, p.st.limit
+		r.next = func() ([]driver.Value, error) {
+			if left == 0 {
+				return nil, io.EOF
+			}
+			for {
+				rec, err := match()
+				if err != nil {
+					return nil, err
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				if left > 0 {
+					left--
+				}
+				return p.project(rec), nil
+			}
+		}
+	}
+	return r, nil
+}
+
+func fromSlice(recs [][]driver.Value) func() ([]driver.Value, error) {
+	return func() ([]driver.Value, error) {
+		if len(recs) == 0 {
+			return nil, io.EOF
+		}
+		v := recs[0]
+		recs = recs[1:]
+		return v, nil
+	}
+}
+
+func window[T any](s []T, offset, limit int) []T {
+	if offset >= len(s) {
+		return nil
+	}
+	s = s[offset:]
+	if limit >= 0 && limit < len(s) {
+		s = s[:limit]
+	}
+	return s
+}
+
+func (p *plan) project(rec dbfmini.Record) []driver.Value {
+	out := make([]driver.Value, len(p.cols))
+	for i, c := range p.cols {
+		out[i] = driverValue(rec[c.field.Name])
+	}
+	return out
+}
+
+func (p *plan) aggregate(match func() (dbfmini.Record, error)) ([]driver.Value, error) {
+	counts := make([]int64, len(p.cols))
+	sums := make([]float64, len(p.cols))
+	// Com NumericDecimal (Decimal e int64) a soma é exata; basta um float64
+	// na coluna para voltar a float64.
+	exact := make([]*big.Rat, len(p.cols))
+	scales := make([]int, len(p.cols))
+	inexact := make([]bool, len(p.cols))
+	for {
+		rec, err := match()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range p.cols {
+			if c.field.Name == "" { // COUNT(*)
+				counts[i]++
+				continue
+			}
+			raw := rec[c.field.Name]
+			v := normValue(raw)
+			if v == nil {
+				continue
+			}
+			counts[i]++
+			if c.agg != "SUM" {
+				continue
+			}
+			if x, ok := v.(float64); ok {
+				sums[i] += x
+			}
+			var r *big.Rat
+			switch x := raw.(type) {
+			case dbfmini.Decimal:
+				r = x.Rat()
+				scales[i] = max(scales[i], int(x.Scale))
+			case int64:
+				r = new(big.Rat).SetInt64(x)
+			default:
+				inexact[i] = true
+				continue
+			}
+			if exact[i] == nil {
+				exact[i] = new(big.Rat)
+			}
+			exact[i].Add(exact[i], r)
+		}
+	}
+	out := make([]driver.Value, len(p.cols))
+	for i, c := range p.cols {
+		switch {
+		case c.agg == "COUNT":
+			out[i] = counts[i]
+		case counts[i] == 0:
+		case inexact[i]:
+			out[i] = sums[i]
+		case scales[i] == 0 && exact[i].Num().IsInt64():
+			out[i] = exact[i].Num().Int64()
+		default:
+			out[i] = exact[i].FloatString(scales[i])
+		}
+	}
+	return out, nil
+}
+
+// sort ordena recs pelas chaves do ORDER BY; NULL vem antes dos demais
+// valores (depois, em DESC).
+func (p *plan) sort(recs []dbfmini.Record) error {
+	var err error
+	sort.SliceStable(recs, func(i, j int) bool {
+		for _, k := range p.orderBy {
+			a, b := normValue(recs[i][k.field]), normValue(recs[j][k.field])
+			var n int
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				n = -1
+			case b == nil:
+				n = 1
+			default:
+				var cerr error
+				if n, cerr = compare(a, b); cerr != nil && err == nil {
+					err = cerr
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			if k.desc {
+				return n > 0
+			}
+			return n < 0
+		}
+		return false
+	})
+	return err
+}
+
+// --------------------------- Avaliação do WHERE ---------------------------
+
+// eval avalia n sobre rec. Condições devolvem true, false ou nil
+// (desconhecido, como em comparações com NULL).
+func (p *plan) eval(n *node, rec dbfmini.Record) (any, error) {
+	switch n.kind {
+	case nodeLit:
+		return n.value, nil
+	case nodeCol:
+		return normValue(rec[n.col]), nil
+	case nodeArg:
+		if n.arg >= len(p.args) {
+			return nil, fmt.Errorf("parâmetro %d não informado", n.arg+1)
+		}
+		return p.args[n.arg], nil
+	case nodeNot:
+		v, err := p.eval(n.left, rec)
+		if b, ok := v.(bool); ok {
+			return !b, err
+		}
+		return nil, err
+	case nodeAnd, nodeOr:
+		l, err := p.eval(n.left, rec)
+		if err != nil {
+			return nil, err
+		}
+		stop := n.kind == nodeOr // OR para no primeiro true; AND, no primeiro false
+		if l == stop {
+			return stop, nil
+		}
+		r, err := p.eval(n.right, rec)
+		if err != nil {
+			return nil, err
+		}
+		if r == stop {
+			return stop, nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return !stop, nil
+	case nodeIsNull:
+		v, err := p.eval(n.left, rec)
+		return (v == nil) != n.neg, err
+	}
+
+	l, err := p.eval(n.left, rec)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	switch n.kind {
+	case nodeIn:
+		var unknown bool
+		for _, item := range n.list {
+			v, err := p.eval(item, rec)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				unknown = true
+				continue
+			}
+			c, err := compare(l, v)
+			if err != nil {
+				return nil, err
+			}
+			if c == 0 {
+				return !n.neg, nil
+			}
+		}
+		if unknown {
+			return nil, nil
+		}
+		return n.neg, nil
+	}
+	r, err := p.eval(n.right, rec)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	if n.kind == nodeLike {
+		s, ok1 := l.(string)
+		pat, ok2 := r.(string)
+		if !ok1 || !ok2 {
+			return nil, errors.New("LIKE exige textos")
+		}
+		return like(s, pat) != n.neg, nil
+	}
+	c, err := compare(l, r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// normValue converte valores de registros e parâmetros para a forma usada
+// nas comparações: números em float64, bytes em string.
+func normValue(v any) any {
+	switch v := v.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case dbfmini.Decimal:
+		return v.Float64()
+	case []byte:
+		return string(v)
+	}
+	return v
+}
+
+var timeLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339Nano, "20060102"}
+
+// compare compara dois valores não nulos. Textos são convertidos para
+// número ou data quando comparados com eles.
+func compare(a, b any) (int, error) {
+	switch x := a.(type) {
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return cmpOrdered(x, y), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(y), 64); err == nil {
+				return cmpOrdered(x, f), nil
+			}
+		case bool:
+			return compare(x, boolNumber(y))
+		}
+	case string:
+		switch y := b.(type) {
+		case string:
+			return strings.Compare(x, y), nil
+		case float64, time.Time:
+			n, err := compare(b, a)
+			return -n, err
+		}
+	case bool:
+		switch y := b.(type) {
+		case bool:
+			return cmpOrdered(boolNumber(x), boolNumber(y)), nil
+		case float64:
+			return cmpOrdered(boolNumber(x), y), nil
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Compare(y), nil
+		case string:
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(y)); err == nil {
+					return x.Compare(t), nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("não é possível comparar %T com %T", a, b)
+}
+
+func cmpOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolNumber(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// like testa s contra o padrão SQL pat (% e _), diferenciando maiúsculas.
+func like(s, pat string) bool {
+	// backtracking no último %, como em correspondência de curingas
+	var si, pi, starP, starS = 0, 0, -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(pat) && pat[pi] == '%':
+			starP, starS = pi, si
+			pi++
+		case pi < len(pat) && pat[pi] == '_':
+			_, size := utf8.DecodeRuneInString(s[si:])
+			si += size
+			pi++
+		case pi < len(pat) && pat[pi] == s[si]:
+			si++
+			pi++
+		case starP >= 0:
+			_, size := utf8.DecodeRuneInString(s[starS:])
+			starS += size
+			si, pi = starS, starP+1
+		default:
+			return false
+		}
+	}
+	for pi < len(pat) && pat[pi] == '%' {
+		pi++
+	}
+	return pi == len(pat)
+}
+
+// driverValue converte um valor do registro em driver.Value.
+func driverValue(v any) driver.Value {
+	switch v := v.(type) {
+	case int32:
+		return int64(v)
+	case dbfmini.Decimal:
+		return v.String() // texto, para não perder precisão
+	}
+	return v
+}
+
+func isNumeric(t byte) bool {
+	switch t {
+	case 'N', 'F', 'Y', 'B', 'I':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,481 @@
+package sqldriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --------------------------- Análise do SQL ---------------------------
+
+// selectStmt é um SELECT já interpretado.
+type selectStmt struct {
+	star    bool
+	items   []selectItem
+	table   string
+	where   *node
+	orderBy []orderItem
+	limit   int // -1: sem LIMIT
+	offset  int
+	nargs   int // número de marcadores ?
+}
+
+// selectItem é uma coluna do SELECT: um campo ou um agregado.
+type selectItem struct {
+	agg   string // "", "COUNT" ou "SUM"
+	col   string // vazio em COUNT(*)
+	alias string
+}
+
+func (it selectItem) name() string {
+	switch {
+	case it.alias != "":
+		return it.alias
+	case it.agg == "":
+		return it.col
+	case it.col == "":
+		return it.agg + "(*)"
+	}
+	return it.agg + "(" + it.col + ")"
+}
+
+type orderItem struct {
+	col  string
+	pos  int // ORDER BY 2: posição (base 1) na lista do SELECT
+	desc bool
+}
+
+type nodeKind int
+
+const (
+	nodeLit nodeKind = iota
+	nodeCol
+	nodeArg
+	nodeCompare // =, <>, <, <=, >, >=
+	nodeLike
+	nodeIn
+	nodeIsNull
+	nodeAnd
+	nodeOr
+	nodeNot
+)
+
+// node é um nó da expressão do WHERE.
+type node struct {
+	kind  nodeKind
+	op    string
+	value any    // nodeLit
+	col   string // nodeCol
+	arg   int    // nodeArg (base 0)
+	neg   bool   // NOT LIKE, NOT IN, IS NOT NULL
+	left  *node
+	right *node
+	list  []*node // nodeIn
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokQuoted // identificador entre aspas
+	tokNumber
+	tokString
+	tokSymbol
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src   string
+	toks  []token
+	i     int
+	nargs int
+}
+
+// parse interpreta um SELECT do subconjunto suportado.
+func parse(src string) (*selectStmt, error) {
+	p := &parser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	st, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	st.nargs = p.nargs
+	return st, nil
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case isLetter(c):
+			j := i
+			for j < len(s) && (isLetter(s[j]) || isDigit(s[j])) {
+				j++
+			}
+			p.toks = append(p.toks, token{tokIdent, s[i:j], i})
+			i = j
+		case isDigit(c) || (c == '.' || c == '-') && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '.'):
+			j := i + 1
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			p.toks = append(p.toks, token{tokNumber, s[i:j], i})
+			i = j
+		case c == '\'' || c == '"' || c == '`':
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(s) {
+					return fmt.Errorf("posição %d: texto sem fechamento", i+1)
+				}
+				if s[j] == c {
+					if j+1 < len(s) && s[j+1] == c { // aspas dobradas
+						b.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			kind := tokString
+			if c != '\'' {
+				kind = tokQuoted
+			}
+			p.toks = append(p.toks, token{kind, b.String(), i})
+			i = j + 1
+		default:
+			op := string(c)
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "<>", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if len(op) == 1 && !strings.Contains("(),*=<>?;", op) {
+				return fmt.Errorf("posição %d: caractere inesperado %q", i+1, op)
+			}
+			p.toks = append(p.toks, token{tokSymbol, op, i})
+			i += len(op)
+		}
+	}
+	p.toks = append(p.toks, token{tokEOF, "", len(s)})
+	return nil
+}
+
+func isLetter(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword consome a palavra-chave kw, se for o próximo token.
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// symbol consome o símbolo sym, se for o próximo token.
+func (p *parser) symbol(sym string) bool {
+	if t := p.peek(); t.kind == tokSymbol && t.text == sym {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	if t.kind == tokEOF {
+		return fmt.Errorf("fim inesperado: "+format, args...)
+	}
+	return fmt.Errorf("posição %d: "+format, append([]any{t.pos + 1}, args...)...)
+}
+
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "ORDER": true, "BY": true, "LIMIT": true,
+	"OFFSET": true, "AND": true, "OR": true, "NOT": true, "LIKE": true, "IN": true, "IS": true,
+	"NULL": true, "AS": true, "ASC": true, "DESC": true, "TRUE": true, "FALSE": true,
+}
+
+// ident consome um nome (palavra não reservada ou entre aspas).
+func (p *parser) ident(what string) (string, error) {
+	t := p.next()
+	if t.kind == tokQuoted || t.kind == tokIdent && !reserved[strings.ToUpper(t.text)] {
+		return t.text, nil
+	}
+	return "", p.errorf(t, "esperado %s, encontrado %q", what, t.text)
+}
+
+func (p *parser) parseSelect() (*selectStmt, error) {
+	st := &selectStmt{limit: -1}
+	if !p.keyword("SELECT") {
+		return nil, p.errorf(p.peek(), "somente SELECT é suportado")
+	}
+	if p.symbol("*") {
+		st.star = true
+	} else {
+		for {
+			it, err := p.parseItem()
+			if err != nil {
+				return nil, err
+			}
+			st.items = append(st.items, it)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if !p.keyword("FROM") {
+		return nil, p.errorf(p.peek(), "esperado FROM")
+	}
+	var err error
+	if st.table, err = p.ident("nome de tabela"); err != nil {
+		return nil, err
+	}
+	if p.keyword("WHERE") {
+		if st.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, p.errorf(p.peek(), "esperado BY")
+		}
+		for {
+			var o orderItem
+			if t := p.peek(); t.kind == tokNumber {
+				p.next()
+				if o.pos, err = strconv.Atoi(t.text); err != nil || o.pos < 1 {
+					return nil, p.errorf(t, "posição inválida em ORDER BY: %s", t.text)
+				}
+			} else if o.col, err = p.ident("coluna"); err != nil {
+				return nil, err
+			}
+			if p.keyword("DESC") {
+				o.desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			st.orderBy = append(st.orderBy, o)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("LIMIT") {
+		if st.limit, err = p.count("LIMIT"); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("OFFSET") {
+		if st.offset, err = p.count("OFFSET"); err != nil {
+			return nil, err
+		}
+	}
+	p.symbol(";")
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "símbolo inesperado %q", t.text)
+	}
+	return st, nil
+}
+
+func (p *parser) count(what string) (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokNumber || err != nil || n < 0 {
+		return 0, p.errorf(t, "%s espera um inteiro não negativo", what)
+	}
+	return n, nil
+}
+
+func (p *parser) parseItem() (selectItem, error) {
+	var it selectItem
+	t := p.peek()
+	if up := strings.ToUpper(t.text); t.kind == tokIdent && (up == "COUNT" || up == "SUM") &&
+		p.toks[p.i+1].kind == tokSymbol && p.toks[p.i+1].text == "(" {
+		p.i += 2
+		it.agg = up
+		if !(up == "COUNT" && p.symbol("*")) {
+			var err error
+			if it.col, err = p.ident("coluna"); err != nil {
+				return it, err
+			}
+		}
+		if !p.symbol(")") {
+			return it, p.errorf(p.peek(), "esperado )")
+		}
+	} else {
+		var err error
+		if it.col, err = p.ident("coluna"); err != nil {
+			return it, err
+		}
+	}
+	if p.keyword("AS") {
+		var err error
+		if it.alias, err = p.ident("apelido"); err != nil {
+			return it, err
+		}
+	}
+	return it, nil
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword("OR") {
+		var right *node
+		if right, err = p.parseAnd(); err == nil {
+			left = &node{kind: nodeOr, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.keyword("AND") {
+		var right *node
+		if right, err = p.parseNot(); err == nil {
+			left = &node{kind: nodeAnd, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseNot() (*node, error) {
+	if p.keyword("NOT") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeNot, left: n}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (*node, error) {
+	if p.symbol("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, p.errorf(p.peek(), "esperado )")
+		}
+		return n, nil
+	}
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		neg := p.keyword("NOT")
+		if !p.keyword("NULL") {
+			return nil, p.errorf(p.peek(), "esperado NULL")
+		}
+		return &node{kind: nodeIsNull, left: left, neg: neg}, nil
+	}
+	neg := p.keyword("NOT")
+	switch {
+	case p.keyword("LIKE"):
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeLike, left: left, right: right, neg: neg}, nil
+	case p.keyword("IN"):
+		if !p.symbol("(") {
+			return nil, p.errorf(p.peek(), "esperado (")
+		}
+		n := &node{kind: nodeIn, left: left, neg: neg}
+		for {
+			v, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			n.list = append(n.list, v)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		if !p.symbol(")") {
+			return nil, p.errorf(p.peek(), "esperado )")
+		}
+		return n, nil
+	case neg:
+		return nil, p.errorf(p.peek(), "esperado LIKE ou IN após NOT")
+	}
+	t := p.next()
+	switch op := t.text; {
+	case t.kind == tokSymbol && (op == "=" || op == "<>" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">="):
+		if op == "!=" {
+			op = "<>"
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeCompare, op: op, left: left, right: right}, nil
+	}
+	return nil, p.errorf(t, "esperado operador de comparação, encontrado %q", t.text)
+}
+
+// parseOperand lê uma coluna, um literal ou um marcador ?.
+func (p *parser) parseOperand() (*node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokString:
+		p.next()
+		return &node{kind: nodeLit, value: t.text}, nil
+	case t.kind == tokNumber:
+		p.next()
+		x, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "número inválido %q", t.text)
+		}
+		return &node{kind: nodeLit, value: x}, nil
+	case t.kind == tokSymbol && t.text == "?":
+		p.next()
+		p.nargs++
+		return &node{kind: nodeArg, arg: p.nargs - 1}, nil
+	case t.kind == tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			p.next()
+			return &node{kind: nodeLit}, nil
+		case "TRUE", "FALSE":
+			p.next()
+			return &node{kind: nodeLit, value: strings.EqualFold(t.text, "TRUE")}, nil
+		}
+	}
+	col, err := p.ident("coluna ou valor")
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeCol, col: col}, nil
+}
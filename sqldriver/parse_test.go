@@ -0,0 +1,69 @@
+package sqldriver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSelect(t *testing.T) {
+	st, err := parse(`select nome, SUM(saldo) AS total, count(*) FROM "Clientes"
+		WHERE uf = 'SP' AND (saldo >= -1.5 OR nome LIKE 'A%') AND qtd NOT IN (1, ?)
+		ORDER BY 2 DESC, nome LIMIT 10 OFFSET 5; -- fim`)
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if st.star || st.table != "Clientes" || st.limit != 10 || st.offset != 5 || st.nargs != 1 {
+		t.Fatalf("unexpected statement: %+v", st)
+	}
+	var names []string
+	for _, it := range st.items {
+		names = append(names, it.name())
+	}
+	if got := strings.Join(names, ","); got != "nome,total,COUNT(*)" {
+		t.Fatalf("items = %s", got)
+	}
+	if len(st.orderBy) != 2 || st.orderBy[0].pos != 2 || !st.orderBy[0].desc || st.orderBy[1].col != "nome" {
+		t.Fatalf("orderBy = %+v", st.orderBy)
+	}
+	w := st.where
+	if w.kind != nodeAnd || w.right.kind != nodeIn || !w.right.neg || len(w.right.list) != 2 {
+		t.Fatalf("unexpected where: %+v", w)
+	}
+	if or := w.left.right; or.kind != nodeOr || or.left.right.value != -1.5 {
+		t.Fatalf("unexpected OR node: %+v", or)
+	}
+}
+
+func TestParseStarAndNull(t *testing.T) {
+	st, err := parse("SELECT * FROM t WHERE NOT x IS NOT NULL AND y <> 'it''s'")
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if !st.star || st.limit != -1 {
+		t.Fatalf("unexpected statement: %+v", st)
+	}
+	if n := st.where.left; n.kind != nodeNot || n.left.kind != nodeIsNull || !n.left.neg {
+		t.Fatalf("unexpected NOT node: %+v", n)
+	}
+	if n := st.where.right; n.op != "<>" || n.right.value != "it's" {
+		t.Fatalf("unexpected compare node: %+v", n.right)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{
+		"",
+		"UPDATE t SET a = 1",
+		"SELECT FROM t",
+		"SELECT a FROM",
+		"SELECT a FROM t WHERE",
+		"SELECT a FROM t WHERE a = 'aberto",
+		"SELECT a FROM t LIMIT x",
+		"SELECT a FROM t JOIN u",
+		"SELECT AVG(a) FROM t",
+	} {
+		if _, err := parse(q); err == nil {
+			t.Fatalf("parse(%q) returned nil error", q)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package sqldump gera scripts SQL a partir de tabelas DBF: o CREATE TABLE
+// correspondente aos campos e os dados em INSERTs agrupados ou, no
+// PostgreSQL, no formato texto do COPY.
+//
+//	db, _ := dbfmini.Open("clientes.dbf", nil)
+//	n, err := sqldump.Export(os.Stdout, db, &sqldump.Options{Dialect: sqldump.MySQL})
+//
+// Os tipos seguem o campo: C e V→VARCHAR(n), N e F→NUMERIC(p,s),
+// Y→NUMERIC(19,4), I→INTEGER, B→DOUBLE, L→BOOLEAN, D→DATE, T→TIMESTAMP,
+// M→TEXT e G/W/Q→BYTEA ou BLOB, com os nomes e o escape de cada dialeto.
+package sqldump
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// Dialect é o banco de destino.
+type Dialect int
+
+const (
+	PostgreSQL Dialect = iota
+	MySQL
+	SQLite
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case PostgreSQL:
+		return "PostgreSQL"
+	case MySQL:
+		return "MySQL"
+	case SQLite:
+		return "SQLite"
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+// Options controla o script gerado.
+type Options struct {
+	Dialect Dialect
+	// Table é o nome da tabela. Default: nome do arquivo, sem extensão.
+	Table string
+	// KeepCase mantém os nomes como no DBF. Por padrão tabela e colunas são
+	// gravadas em minúsculas, para que possam ser usadas sem aspas.
+	KeepCase bool
+	// NoCreate omite o CREATE TABLE.
+	NoCreate bool
+	// BatchSize é o número de registros por INSERT. Default: 100.
+	BatchSize int
+	// Copy grava os dados com COPY ... FROM stdin (só PostgreSQL).
+	Copy bool
+}
+
+func (o *Options) norm() (Options, error) {
+	var n Options
+	if o != nil {
+		n = *o
+	}
+	if n.BatchSize <= 0 {
+		n.BatchSize = 100
+	}
+	switch n.Dialect {
+	case PostgreSQL, MySQL, SQLite:
+	default:
+		return n, fmt.Errorf("dialeto desconhecido: %v", n.Dialect)
+	}
+	if n.Copy && n.Dialect != PostgreSQL {
+		return n, fmt.Errorf("COPY não é suportado em %v", n.Dialect)
+	}
+	return n, nil
+}
+
+// tableName devolve o nome da tabela para o arquivo path.
+func (o Options) tableName(path string) string {
+	name := o.Table
+	if name == "" {
+		base := filepath.Base(path)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return o.ident(name)
+}
+
+// ident aplica KeepCase e as aspas do dialeto a um nome.
+func (o Options) ident(name string) string {
+	if !o.KeepCase {
+		name = strings.ToLower(name)
+	}
+	if o.Dialect == MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// --------------------------- DDL ---------------------------
+
+// CreateTable devolve o CREATE TABLE para os campos fields, com o nome de
+// tabela table (aplicados KeepCase e as aspas do dialeto).
+func CreateTable(table string, fields []dbfmini.Field, opts *Options) (string, error) {
+	o, err := opts.norm()
+	if err != nil {
+		return "", err
+	}
+	return o.createTable(o.ident(table), fields)
+}
+
+func (o Options) createTable(table string, fields []dbfmini.Field) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", table)
+	for i, f := range fields {
+		typ, err := o.columnType(f)
+		if err != nil {
+			return "", err
+		}
+		sep := ","
+		if i == len(fields)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "  %s %s%s\n", o.ident(f.Name), typ, sep)
+	}
+	b.WriteString(");\n")
+	return b.String(), nil
+}
+
+// columnType devolve o tipo SQL do campo f.
+func (o Options) columnType(f dbfmini.Field) (string, error) {
+	numeric := "NUMERIC"
+	if o.Dialect == MySQL {
+		numeric = "DECIMAL"
+	}
+	switch f.Type {
+	case 'C', 'V':
+		return fmt.Sprintf("VARCHAR(%d)", f.Size), nil
+	case 'Q':
+		switch o.Dialect {
+		case PostgreSQL:
+			return "BYTEA", nil
+		case MySQL:
+			return fmt.Sprintf("VARBINARY(%d)", f.Size), nil
+		}
+		return "BLOB", nil
+	case 'N', 'F':
+		p, s := int(f.Size), int(f.DecimalPlaces)
+		if s > 0 {
+			p-- // ponto decimal
+		}
+		return fmt.Sprintf("%s(%d,%d)", numeric, max(p, s, 1), s), nil
+	case 'Y':
+		return numeric + "(19,4)", nil
+	case 'I':
+		return "INTEGER", nil
+	case 'B':
+		switch o.Dialect {
+		case PostgreSQL:
+			return "DOUBLE PRECISION", nil
+		case MySQL:
+			return "DOUBLE", nil
+		}
+		return "REAL", nil
+	case 'L':
+		return "BOOLEAN", nil
+	case 'D':
+		return "DATE", nil
+	case 'T':
+		if o.Dialect == MySQL {
+			return "DATETIME(3)", nil
+		}
+		return "TIMESTAMP", nil
+	case 'M':
+		if o.Dialect == MySQL {
+			return "LONGTEXT", nil
+		}
+		return "TEXT", nil
+	case 'G', 'W':
+		switch o.Dialect {
+		case PostgreSQL:
+			return "BYTEA", nil
+		case MySQL:
+			return "LONGBLOB", nil
+		}
+		return "BLOB", nil
+	}
+	return "", fmt.Errorf("%s: tipo %q sem correspondente SQL", f.Name, string(f.Type))
+}
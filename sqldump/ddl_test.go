@@ -0,0 +1,87 @@
+package sqldump
+
+import (
+	"testing"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+var testFields = []dbfmini.Field{
+	{Name: "NOME", Type: 'C', Size: 20},
+	{Name: "SALDO", Type: 'N', Size: 12, DecimalPlaces: 2},
+	{Name: "QTD", Type: 'N', Size: 5},
+	{Name: "CURR", Type: 'Y', Size: 8, DecimalPlaces: 4},
+	{Name: "COUNT", Type: 'I', Size: 4},
+	{Name: "RATIO", Type: 'B', Size: 8},
+	{Name: "ATIVO", Type: 'L', Size: 1},
+	{Name: "NASC", Type: 'D', Size: 8},
+	{Name: "STAMP", Type: 'T', Size: 8},
+	{Name: "OBS", Type: 'M', Size: 4},
+	{Name: "FOTO", Type: 'W', Size: 4},
+}
+
+func TestCreateTable(t *testing.T) {
+	cases := map[Dialect]string{
+		PostgreSQL: `CREATE TABLE "clientes" (
+  "nome" VARCHAR(20),
+  "saldo" NUMERIC(11,2),
+  "qtd" NUMERIC(5,0),
+  "curr" NUMERIC(19,4),
+  "count" INTEGER,
+  "ratio" DOUBLE PRECISION,
+  "ativo" BOOLEAN,
+  "nasc" DATE,
+  "stamp" TIMESTAMP,
+  "obs" TEXT,
+  "foto" BYTEA
+);
+`,
+		MySQL: "CREATE TABLE `clientes` (\n" +
+			"  `nome` VARCHAR(20),\n" +
+			"  `saldo` DECIMAL(11,2),\n" +
+			"  `qtd` DECIMAL(5,0),\n" +
+			"  `curr` DECIMAL(19,4),\n" +
+			"  `count` INTEGER,\n" +
+			"  `ratio` DOUBLE,\n" +
+			"  `ativo` BOOLEAN,\n" +
+			"  `nasc` DATE,\n" +
+			"  `stamp` DATETIME(3),\n" +
+			"  `obs` LONGTEXT,\n" +
+			"  `foto` LONGBLOB\n" +
+			");\n",
+		SQLite: `CREATE TABLE "clientes" (
+  "nome" VARCHAR(20),
+  "saldo" NUMERIC(11,2),
+  "qtd" NUMERIC(5,0),
+  "curr" NUMERIC(19,4),
+  "count" INTEGER,
+  "ratio" REAL,
+  "ativo" BOOLEAN,
+  "nasc" DATE,
+  "stamp" TIMESTAMP,
+  "obs" TEXT,
+  "foto" BLOB
+);
+`,
+	}
+	for d, want := range cases {
+		got, err := CreateTable("CLIENTES", testFields, &Options{Dialect: d})
+		if err != nil {
+			t.Fatalf("%v: CreateTable returned error: %v", d, err)
+		}
+		if got != want {
+			t.Fatalf("%v: unexpected DDL:\n%s\nwant:\n%s", d, got, want)
+		}
+	}
+
+	got, err := CreateTable(`Cli"entes`, testFields[:1], &Options{KeepCase: true})
+	if err != nil || got != "CREATE TABLE \"Cli\"\"entes\" (\n  \"NOME\" VARCHAR(20)\n);\n" {
+		t.Fatalf("unexpected DDL %q/%v", got, err)
+	}
+	if _, err := CreateTable("t", []dbfmini.Field{{Name: "X", Type: '0', Size: 1}}, nil); err == nil {
+		t.Fatalf("expected error for unsupported type")
+	}
+	if _, err := CreateTable("t", testFields, &Options{Dialect: MySQL, Copy: true}); err == nil {
+		t.Fatalf("expected error for COPY outside PostgreSQL")
+	}
+}
@@ -0,0 +1,297 @@
+package sqldump
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+)
+
+// --------------------------- Dados ---------------------------
+
+// Writer grava registros como INSERTs agrupados ou linhas de COPY.
+// Registros excluídos são omitidos.
+type Writer struct {
+	w       io.Writer
+	o       Options
+	table   string
+	fields  []dbfmini.Field
+	cols    string
+	pending int // registros no INSERT (ou COPY) em aberto
+	count   int
+	buf     []byte
+	closed  bool
+}
+
+// NewWriter grava o CREATE TABLE (a menos que NoCreate) e prepara a
+// gravação dos registros de fields na tabela table.
+func NewWriter(w io.Writer, table string, fields []dbfmini.Field, opts *Options) (*Writer, error) {
+	o, err := opts.norm()
+	if err != nil {
+		return nil, err
+	}
+	return o.newWriter(w, o.ident(table), fields)
+}
+
+func (o Options) newWriter(w io.Writer, table string, fields []dbfmini.Field) (*Writer, error) {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = o.ident(f.Name)
+	}
+	sw := &Writer{w: w, o: o, table: table, fields: fields, cols: strings.Join(cols, ", ")}
+	if !o.NoCreate {
+		ddl, err := o.createTable(table, fields)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, ddl+"\n"); err != nil {
+			return nil, err
+		}
+	}
+	return sw, nil
+}
+
+// Write grava rec.
+func (w *Writer) Write(rec dbfmini.Record) error {
+	if w.closed {
+		return errors.New("writer fechado")
+	}
+	if rec["_deleted"] == true {
+		return nil
+	}
+	b := w.buf[:0]
+	var err error
+	if w.o.Copy {
+		if w.pending == 0 {
+			b = fmt.Appendf(b, "COPY %s (%s) FROM stdin;\n", w.table, w.cols)
+		}
+		for i, f := range w.fields {
+			if i > 0 {
+				b = append(b, '\t')
+			}
+			if b, err = copyValue(b, f, rec[f.Name]); err != nil {
+				return err
+			}
+		}
+		b = append(b, '\n')
+	} else {
+		if w.pending == 0 {
+			b = fmt.Appendf(b, "INSERT INTO %s (%s) VALUES\n(", w.table, w.cols)
+		} else {
+			b = append(b, ",\n("...)
+		}
+		for i, f := range w.fields {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			if b, err = w.o.literal(b, f, rec[f.Name]); err != nil {
+				return err
+			}
+		}
+		b = append(b, ')')
+	}
+	w.pending++
+	if !w.o.Copy && w.pending == w.o.BatchSize {
+		b = append(b, ";\n"...)
+		w.pending = 0
+	}
+	w.buf = b
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Close termina o INSERT ou o COPY em aberto; sem lote pendente, não
+// grava nada.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.pending == 0 {
+		return nil
+	}
+	end := ";\n"
+	if w.o.Copy {
+		end = "\\.\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
+
+// Export grava o script de db em w (o CREATE TABLE, salvo com NoCreate, e
+// os dados; a tabela é Options.Table ou o nome do arquivo) e devolve o
+// número de registros.
+func Export(w io.Writer, db *dbfmini.DBF, opts *Options) (int, error) {
+	o, err := opts.norm()
+	if err != nil {
+		return 0, err
+	}
+	sw, err := o.newWriter(w, o.tableName(db.Path), db.Fields)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.Query().Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := sw.Write(rows.Record()); err != nil {
+			return sw.count, fmt.Errorf("registro %d: %w", rows.Recno(), err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return sw.count, err
+	}
+	return sw.count, sw.Close()
+}
+
+// --------------------------- Valores ---------------------------
+
+// literal grava v como literal SQL do dialeto.
+func (o Options) literal(b []byte, f dbfmini.Field, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "NULL"...), nil
+	case string:
+		return o.quote(b, v), nil
+	case []byte:
+		if f.Type == 'M' || f.Type == 'C' {
+			return o.quote(b, string(v)), nil
+		}
+		if o.Dialect == PostgreSQL {
+			b = append(b, `'\x`...)
+			b = hex.AppendEncode(b, v)
+			return append(b, '\''), nil
+		}
+		b = append(b, "X'"...)
+		b = hex.AppendEncode(b, v)
+		return append(b, '\''), nil
+	case bool:
+		if o.Dialect == SQLite {
+			if v {
+				return append(b, '1'), nil
+			}
+			return append(b, '0'), nil
+		}
+		if v {
+			return append(b, "TRUE"...), nil
+		}
+		return append(b, "FALSE"...), nil
+	case time.Time:
+		b = append(b, '\'')
+		b = appendTime(b, f, v)
+		return append(b, '\''), nil
+	}
+	return appendNumber(b, f, v)
+}
+
+// quote grava s entre apóstrofos. No MySQL a barra invertida também é
+// escapada, como exige o modo padrão (sem NO_BACKSLASH_ESCAPES).
+func (o Options) quote(b []byte, s string) []byte {
+	b = append(b, '\'')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			b = append(b, "''"...)
+		case o.Dialect != MySQL:
+			b = append(b, c)
+		case c == '\\':
+			b = append(b, `\\`...)
+		case c == 0:
+			b = append(b, `\0`...)
+		case c == '\n':
+			b = append(b, `\n`...)
+		case c == '\r':
+			b = append(b, `\r`...)
+		case c == 0x1a:
+			b = append(b, `\Z`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '\'')
+}
+
+// copyValue grava v no formato texto do COPY do PostgreSQL.
+func copyValue(b []byte, f dbfmini.Field, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, `\N`...), nil
+	case string:
+		return copyEscape(b, v), nil
+	case []byte:
+		if f.Type == 'M' || f.Type == 'C' {
+			return copyEscape(b, string(v)), nil
+		}
+		b = append(b, `\\x`...)
+		return hex.AppendEncode(b, v), nil
+	case bool:
+		if v {
+			return append(b, 't'), nil
+		}
+		return append(b, 'f'), nil
+	case time.Time:
+		return appendTime(b, f, v), nil
+	}
+	return appendNumber(b, f, v)
+}
+
+func copyEscape(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b = append(b, `\\`...)
+		case '\t':
+			b = append(b, `\t`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+func appendTime(b []byte, f dbfmini.Field, t time.Time) []byte {
+	if f.Type == 'D' {
+		return t.AppendFormat(b, "2006-01-02")
+	}
+	return t.UTC().AppendFormat(b, "2006-01-02 15:04:05.999")
+}
+
+// appendNumber grava números com as casas decimais do campo.
+func appendNumber(b []byte, f dbfmini.Field, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(b, v, 10), nil
+	case dbfmini.Decimal:
+		return append(b, v.String()...), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return b, fmt.Errorf("%s: valor %v não representável em SQL", f.Name, v)
+		}
+		switch f.Type {
+		case 'N', 'F':
+			return strconv.AppendFloat(b, v, 'f', int(f.DecimalPlaces), 64), nil
+		case 'Y':
+			return strconv.AppendFloat(b, v, 'f', 4, 64), nil
+		}
+		return strconv.AppendFloat(b, v, 'g', -1, 64), nil
+	}
+	return b, fmt.Errorf("%s: tipo %T não suportado", f.Name, v)
+}
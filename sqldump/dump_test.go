@@ -0,0 +1,135 @@
+package sqldump
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alberto255345/dbfmini"
+	"github.com/alberto255345/dbfmini/internal/dbftest"
+)
+
+var dumpFields = []dbfmini.Field{
+	{Name: "NOME", Type: 'C', Size: 20},
+	{Name: "SALDO", Type: 'N', Size: 12, DecimalPlaces: 2},
+	{Name: "CURR", Type: 'Y'},
+	{Name: "ATIVO", Type: 'L'},
+	{Name: "NASC", Type: 'D'},
+	{Name: "STAMP", Type: 'T'},
+}
+
+func writeTable(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "Clientes.dbf")
+	dbftest.Table(t, path, dumpFields, nil, []dbfmini.Record{
+		{"NOME": `O'Brien \ "x"`, "SALDO": 1234.5, "CURR": 0.1, "ATIVO": true,
+			"NASC":  time.Date(1990, time.May, 17, 0, 0, 0, 0, time.UTC),
+			"STAMP": time.Date(2024, time.January, 2, 10, 30, 15, 0, time.UTC)},
+		{"NOME": "Vazio", "ATIVO": false},
+		{"NOME": "Excluído", "_deleted": true},
+		{"NOME": "Tab\tfim"},
+	})
+	return path
+}
+
+func dump(t *testing.T, opts *Options) string {
+	t.Helper()
+	db, err := dbfmini.Open(writeTable(t), &dbfmini.OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	n, err := Export(&buf, db, opts)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 rows, got %d/%v", n, err)
+	}
+	return buf.String()
+}
+
+func TestExportInserts(t *testing.T) {
+	out := dump(t, &Options{NoCreate: true, BatchSize: 2})
+	want := `INSERT INTO "clientes" ("nome", "saldo", "curr", "ativo", "nasc", "stamp") VALUES
+('O''Brien \ "x"', 1234.50, 0.1000, TRUE, '1990-05-17', '2024-01-02 10:30:15'),
+('Vazio', NULL, 0.0000, FALSE, NULL, NULL);
+INSERT INTO "clientes" ("nome", "saldo", "curr", "ativo", "nasc", "stamp") VALUES
+('Tab	fim', NULL, 0.0000, NULL, NULL, NULL);
+`
+	if out != want {
+		t.Fatalf("unexpected PostgreSQL dump:\n%s\nwant:\n%s", out, want)
+	}
+
+	out = dump(t, &Options{Dialect: MySQL, Table: "cli"})
+	if !strings.HasPrefix(out, "CREATE TABLE `cli` (\n") ||
+		!strings.Contains(out, "INSERT INTO `cli` (`nome`,") ||
+		!strings.Contains(out, `('O''Brien \\ "x"', 1234.50, 0.1000, TRUE,`) {
+		t.Fatalf("unexpected MySQL dump:\n%s", out)
+	}
+
+	out = dump(t, &Options{Dialect: SQLite, KeepCase: true, NoCreate: true})
+	if !strings.Contains(out, `INSERT INTO "Clientes" ("NOME",`) || !strings.Contains(out, "('Vazio', NULL, 0.0000, 0, NULL, NULL)") {
+		t.Fatalf("unexpected SQLite dump:\n%s", out)
+	}
+}
+
+func TestExportCopy(t *testing.T) {
+	out := dump(t, &Options{Copy: true})
+	want := "COPY \"clientes\" (\"nome\", \"saldo\", \"curr\", \"ativo\", \"nasc\", \"stamp\") FROM stdin;\n" +
+		"O'Brien \\\\ \"x\"\t1234.50\t0.1000\tt\t1990-05-17\t2024-01-02 10:30:15\n" +
+		"Vazio\t\\N\t0.0000\tf\t\\N\t\\N\n" +
+		"Tab\\tfim\t\\N\t0.0000\t\\N\t\\N\t\\N\n" +
+		"\\.\n"
+	if !strings.HasPrefix(out, "CREATE TABLE \"clientes\" (\n") || !strings.HasSuffix(out, ");\n\n"+want) {
+		t.Fatalf("unexpected COPY dump:\n%s\nwant suffix:\n%s", out, want)
+	}
+}
+
+func TestWriterBlobs(t *testing.T) {
+	fields := []dbfmini.Field{{Name: "OBS", Type: 'M', Size: 4}, {Name: "FOTO", Type: 'G', Size: 4}}
+	for d, want := range map[Dialect]string{
+		PostgreSQL: `('linha1` + "\n" + `dois', '\x00ff')`,
+		MySQL:      `('linha1\ndois', X'00ff')`,
+		SQLite:     `('linha1` + "\n" + `dois', X'00ff')`,
+	} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, "t", fields, &Options{Dialect: d, NoCreate: true})
+		if err != nil {
+			t.Fatalf("NewWriter returned error: %v", err)
+		}
+		if err := w.Write(dbfmini.Record{"OBS": "linha1\ndois", "FOTO": []byte{0, 0xff}}); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		w.Close()
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("%v: expected %s in:\n%s", d, want, buf.String())
+		}
+	}
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, "t", fields, &Options{Copy: true, NoCreate: true})
+	w.Write(dbfmini.Record{"FOTO": []byte{0xab}})
+	w.Close()
+	if !strings.Contains(buf.String(), "\\N\t\\\\xab\n\\.\n") {
+		t.Fatalf("unexpected COPY output:\n%s", buf.String())
+	}
+}
+
+func TestWriterDecimal(t *testing.T) {
+	fields := []dbfmini.Field{
+		{Name: "VALOR", Type: 'N', Size: 20, DecimalPlaces: 2},
+		{Name: "QTD", Type: 'N', Size: 19},
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "t", fields, &Options{NoCreate: true})
+	if err != nil {
+		t.Fatalf("NewWriter returned error: %v", err)
+	}
+	rec := dbfmini.Record{"VALOR": dbfmini.Decimal{Unscaled: 1234567890123456789, Scale: 2}, "QTD": int64(9007199254740993)}
+	if err := w.Write(rec); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	w.Close()
+	if want := "(12345678901234567.89, 9007199254740993);"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected %s in:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,201 @@
+package dbfmini
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// --------------------------- Atualização ---------------------------
+
+// ErrReadOnly indica operação de escrita em DBF aberto só para leitura.
+var ErrReadOnly = errors.New("DBF aberto somente para leitura (use OpenForUpdate)")
+
+// OpenForUpdate abre o DBF como Open e mantém um handle de escrita para
+// Append, UpdateRecord, Delete e Recall. Chame Close ao terminar. Se o
+// header indicar um índice estrutural .cdx, suas tags são mantidas: cada
+// alteração regrava o índice antes de retornar (veja CreateIndex).
+func OpenForUpdate(path string, opts *OpenOptions) (*DBF, error) {
+	db, err := Open(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.loadStructuralIndex(); err != nil {
+		return nil, fmt.Errorf("índice estrutural: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	db.rw = f
+	return db, nil
+}
+
+// Close libera o handle de leitura mantido por Next e o handle de escrita
+// aberto por OpenForUpdate.
+func (d *DBF) Close() error {
+	d.stopScan()
+	if d.rw == nil {
+		return nil
+	}
+	err := d.rw.Close()
+	d.rw, d.tags = nil, nil
+	return err
+}
+
+// UpdateRecord regrava os campos presentes em rec no registro de índice
+// index (base 0). Campos ausentes mantêm o conteúdo atual; "_deleted"
+// (bool), se presente, altera a marca de exclusão. Em campos anuláveis do
+// VFP, nil grava .NULL..
+func (d *DBF) UpdateRecord(index int, rec Record) error {
+	buf, err := d.readRaw(index)
+	if err != nil {
+		return err
+	}
+	for k, v := range rec {
+		if k == "_deleted" {
+			del, ok := v.(bool)
+			if !ok {
+				return fmt.Errorf("_deleted: esperado bool, recebido %T", v)
+			}
+			buf[0] = ' '
+			if del {
+				buf[0] = 0x2A
+			}
+			continue
+		}
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		i := fieldIndex(d.Fields, k)
+		if i < 0 {
+			return fmt.Errorf("campo desconhecido: %s", k)
+		}
+		f := d.Fields[i]
+		off := d.fieldOffset(i)
+		if err := encodeField(buf[off:off+int(f.Size)], f, v, d.fieldCodec(i)); err != nil {
+			return err
+		}
+		d.setNull(buf, i, v == nil)
+		if isVarType(f.Type) {
+			d.setVarLen(buf, i)
+		}
+	}
+	return d.writeRaw(index, buf)
+}
+
+// Delete marca o registro index (base 0) como excluído (0x2A).
+func (d *DBF) Delete(index int) error { return d.setDeleted(index, true) }
+
+// Recall remove a marca de exclusão do registro index (base 0).
+func (d *DBF) Recall(index int) error { return d.setDeleted(index, false) }
+
+func (d *DBF) setDeleted(index int, deleted bool) error {
+	buf, err := d.readRaw(index)
+	if err != nil {
+		return err
+	}
+	buf[0] = ' '
+	if deleted {
+		buf[0] = 0x2A
+	}
+	if _, err := d.rw.WriteAt(buf[:1], d.recordOffset(index)); err != nil {
+		return fmt.Errorf("gravando registro %d: %w", index, err)
+	}
+	if err := d.touch(); err != nil {
+		return err
+	}
+	return d.updateIndex(uint32(index)+1, buf)
+}
+
+func (d *DBF) readRaw(index int) ([]byte, error) {
+	if d.rw == nil {
+		return nil, ErrReadOnly
+	}
+	if err := d.checkIndex(index); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, d.recordLen)
+	if _, err := d.rw.ReadAt(buf, d.recordOffset(index)); err != nil {
+		return nil, fmt.Errorf("lendo registro %d: %w", index, err)
+	}
+	return buf, nil
+}
+
+func (d *DBF) writeRaw(index int, buf []byte) error {
+	if _, err := d.rw.WriteAt(buf, d.recordOffset(index)); err != nil {
+		return fmt.Errorf("gravando registro %d: %w", index, err)
+	}
+	if err := d.touch(); err != nil {
+		return err
+	}
+	return d.updateIndex(uint32(index)+1, buf)
+}
+
+// touch grava a data de hoje como última atualização no header.
+func (d *DBF) touch() error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if d.DateOfLastUpd.Equal(today) {
+		return nil
+	}
+	hdr := make([]byte, 4)
+	putHeaderDate(hdr, today)
+	if _, err := d.rw.WriteAt(hdr[1:4], 1); err != nil {
+		return fmt.Errorf("atualizando header: %w", err)
+	}
+	d.DateOfLastUpd = today
+	return nil
+}
+
+func (d *DBF) checkIndex(index int) error {
+	if index < 0 || index >= int(d.RecordCount) {
+		return fmt.Errorf("registro %d fora da faixa (0..%d)", index, int(d.RecordCount)-1)
+	}
+	return nil
+}
+
+func (d *DBF) recordOffset(index int) int64 {
+	return int64(d.headerLen) + int64(index)*int64(d.recordLen)
+}
+
+// setNull liga ou desliga o bit de .NULL. do campo i em _NullFlags (se o
+// campo for anulável).
+func (d *DBF) setNull(b []byte, i int, null bool) {
+	if d.nullBits != nil {
+		d.setFlag(b, d.nullBits[i].null, null)
+	}
+}
+
+// setVarLen liga o bit de tamanho variável do campo V/Q i, que grava
+// vazio: tamanho 0 no último byte.
+func (d *DBF) setVarLen(b []byte, i int) {
+	if d.nullBits != nil {
+		d.setFlag(b, d.nullBits[i].varLen, true)
+	}
+}
+
+func (d *DBF) setFlag(b []byte, bit int, on bool) {
+	if bit < 0 {
+		return
+	}
+	if on {
+		b[d.nullOff+bit/8] |= 1 << (bit % 8)
+	} else {
+		b[d.nullOff+bit/8] &^= 1 << (bit % 8)
+	}
+}
+
+// fieldOffset devolve a posição do campo i dentro do registro.
+func (d *DBF) fieldOffset(i int) int {
+	if d.offsets != nil {
+		return d.offsets[i]
+	}
+	off := 1
+	for _, f := range d.Fields[:i] {
+		off += int(f.Size)
+	}
+	return off
+}
@@ -0,0 +1,136 @@
+package dbfmini
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var peopleFields = []Field{
+	{Name: "NAME", Type: 'C', Size: 10},
+	{Name: "AGE", Type: 'N', Size: 3},
+}
+
+func writePeople(t testing.TB, path string, rows ...Record) {
+	t.Helper()
+	w, err := Create(path, peopleFields, &CreateOptions{Date: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	for _, r := range rows {
+		if err := w.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestUpdateRecordDeleteAndRecall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path,
+		Record{"NAME": "Ana", "AGE": 30},
+		Record{"NAME": "Bruno", "AGE": 41},
+		Record{"NAME": "Carla", "AGE": 25},
+	)
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.UpdateRecord(1, Record{"AGE": 42}); err != nil {
+		t.Fatalf("UpdateRecord returned error: %v", err)
+	}
+	if err := db.Delete(0); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := db.Delete(2); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := db.Recall(2); err != nil {
+		t.Fatalf("Recall returned error: %v", err)
+	}
+	if err := db.UpdateRecord(3, Record{"AGE": 1}); err == nil {
+		t.Fatalf("UpdateRecord out of range should fail")
+	}
+	if err := db.UpdateRecord(0, Record{"AGE": 1000}); err == nil {
+		t.Fatalf("UpdateRecord with oversized value should fail")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.DateOfLastUpd.Year() == 2000 {
+		t.Fatalf("DateOfLastUpd was not refreshed: %v", db.DateOfLastUpd)
+	}
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("len(records) = %d, want 2", len(records))
+	}
+	if records[0]["NAME"] != "Bruno" || records[0]["AGE"] != 42.0 {
+		t.Fatalf("record 0 = %#v, want Bruno/42", records[0])
+	}
+	if records[1]["NAME"] != "Carla" || records[1]["AGE"] != 25.0 {
+		t.Fatalf("record 1 = %#v, want Carla/25", records[1])
+	}
+}
+
+func TestUpdateRequiresOpenForUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.dbf")
+	writePeople(t, path, Record{"NAME": "Ana", "AGE": 30})
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if err := db.Delete(0); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Delete error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestAppendMemoTable(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFixture(t, dir, "notes.dbf", buildDBF(0x83, memoFields, [][]string{
+		{"  1", "         1"},
+	}))
+	memo := make([]byte, 2*512)
+	binary.LittleEndian.PutUint32(memo[0:4], 2)
+	copy(memo[512:], "Cliente pontual\x1a\x1a")
+	writeFixture(t, dir, "notes.dbt", memo)
+
+	db, err := OpenForUpdate(path, nil)
+	if err != nil {
+		t.Fatalf("OpenForUpdate returned error: %v", err)
+	}
+	if err := db.Append(Record{"ID": 2}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	if err := db.Append(Record{"ID": 3, "NOTES": "novo"}); err == nil {
+		t.Fatalf("Append with memo content should fail")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, err = Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	recs, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(recs) != 2 || recs[0]["NOTES"] != "Cliente pontual" || recs[1]["ID"] != 2.0 || recs[1]["NOTES"] != nil {
+		t.Fatalf("records = %v", recs)
+	}
+}
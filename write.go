@@ -0,0 +1,636 @@
+package dbfmini
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// --------------------------- Escrita ---------------------------
+
+// CreateOptions controla a criação de um novo arquivo DBF.
+type CreateOptions struct {
+	// Version é o byte de versão gravado no header. Default: 0x03 (dBase III),
+	// ou 0x30 (Visual FoxPro) quando há campos I, B, T ou Y.
+	Version byte
+	// Encoding é a página de códigos dos campos texto, também registrada no
+	// language driver ID do header quando há um LDID para ela.
+	// Default: ISO-8859-1.
+	Encoding string
+	// Codec, se definido, substitui Encoding na conversão do texto (o LDID
+	// continua vindo de Encoding).
+	Codec encoding.Encoding
+	// Date é a data de última atualização gravada no header. Default: hoje.
+	Date time.Time
+}
+
+// Writer grava registros sequencialmente em um DBF criado por Create.
+// O header só fica consistente após Close.
+type Writer struct {
+	f         *os.File
+	path      string
+	w         *bufio.Writer
+	fields    []Field
+	version   byte
+	recordLen uint16
+	count     uint32
+	enc       encoding.Encoding
+	ldid      byte
+	date      time.Time
+	buf       []byte
+	closed    bool
+	indexes   []IndexDef // tags do .cdx estrutural (AddIndex)
+}
+
+const vfpBacklinkSize = 263
+
+// Create cria (ou trunca) path e grava o header com os campos informados.
+func Create(path string, fields []Field, opts *CreateOptions) (*Writer, error) {
+	if opts == nil {
+		opts = &CreateOptions{}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("nenhum campo informado")
+	}
+
+	fields = append([]Field(nil), fields...)
+	version := opts.Version
+	if version == 0 {
+		version = 0x03
+		for _, f := range fields {
+			switch f.Type {
+			case 'I', 'B', 'T', 'Y':
+				version = 0x30
+			}
+		}
+	}
+	for i := range fields {
+		normFieldSize(&fields[i])
+		if err := validateWriteField(fields[i], version); err != nil {
+			return nil, err
+		}
+		for _, ex := range fields[:i] {
+			if strings.EqualFold(ex.Name, fields[i].Name) {
+				return nil, fmt.Errorf("nome de campo duplicado: %s", fields[i].Name)
+			}
+		}
+	}
+
+	encName := opts.Encoding
+	if encName == "" {
+		encName = defaultCodePage
+	}
+	enc := opts.Codec
+	if enc == nil {
+		var err error
+		if enc, err = lookupCodePage(encName); err != nil {
+			return nil, err
+		}
+	}
+	date := opts.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	// os dois tamanhos vão em campos de 16 bits do header
+	recordLen := calcRecordLen(fields)
+	if recordLen > math.MaxUint16 {
+		return nil, fmt.Errorf("registro com %d bytes excede 65535", recordLen)
+	}
+	if n := headerSize(version, len(fields)); n > math.MaxUint16 {
+		return nil, fmt.Errorf("header com %d bytes excede 65535 (campos demais)", n)
+	}
+
+	w := &Writer{
+		fields:    fields,
+		version:   version,
+		recordLen: uint16(recordLen),
+		enc:       enc,
+		ldid:      codePageLDIDs[canonicalCodePage(encName)],
+		date:      date,
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w.f = f
+	w.path = path
+	w.w = bufio.NewWriter(f)
+	if _, err := w.w.Write(w.header()); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("gravando header: %w", err)
+	}
+	w.buf = make([]byte, w.recordLen)
+	return w, nil
+}
+
+// headerSize devolve o tamanho do header: 32 bytes, um descritor de 32
+// bytes por campo, o terminador e, no VFP, o backlink do .DBC.
+func headerSize(version byte, fields int) int {
+	n := 32 + 32*fields + 1
+	if isVFP(version) {
+		n += vfpBacklinkSize
+	}
+	return n
+}
+
+// header monta o header completo, incluindo os descritores de campo.
+func (w *Writer) header() []byte {
+	headerLen := headerSize(w.version, len(w.fields))
+	out := make([]byte, headerLen)
+	out[0] = w.version
+	putHeaderDate(out, w.date)
+	out[29] = w.ldid
+	binary.LittleEndian.PutUint32(out[4:8], w.count)
+	binary.LittleEndian.PutUint16(out[8:10], uint16(headerLen))
+	binary.LittleEndian.PutUint16(out[10:12], w.recordLen)
+
+	pos, displacement := 32, 1
+	for _, f := range w.fields {
+		des := out[pos : pos+32]
+		copy(des[0:11], f.Name)
+		des[11] = f.Type
+		if isVFP(w.version) {
+			binary.LittleEndian.PutUint32(des[12:16], uint32(displacement))
+		}
+		des[16] = f.Size
+		des[17] = f.DecimalPlaces
+		pos += 32
+		displacement += int(f.Size)
+	}
+	out[pos] = 0x0D
+	return out
+}
+
+// Append grava um registro. Campos ausentes ficam vazios; "_deleted": true
+// grava o registro já marcado como excluído.
+func (w *Writer) Append(rec Record) error {
+	if w.closed {
+		return errors.New("writer fechado")
+	}
+	if err := encodeRecord(w.buf, w.fields, nil, rec, w.fieldCodec); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return fmt.Errorf("gravando registro: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Close grava o marcador de fim de arquivo, atualiza a contagem de
+// registros no header e fecha o arquivo. Com tags de AddIndex, marca o
+// índice de produção no header e grava o .cdx de mesmo nome.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.w.WriteByte(0x1A); err != nil {
+		w.f.Close()
+		return err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	cnt := make([]byte, 4)
+	binary.LittleEndian.PutUint32(cnt, w.count)
+	if _, err := w.f.WriteAt(cnt, 4); err != nil {
+		w.f.Close()
+		return fmt.Errorf("atualizando header: %w", err)
+	}
+	if len(w.indexes) > 0 {
+		if _, err := w.f.WriteAt([]byte{0x01}, 28); err != nil {
+			w.f.Close()
+			return fmt.Errorf("atualizando header: %w", err)
+		}
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if len(w.indexes) > 0 {
+		return w.writeIndex()
+	}
+	return nil
+}
+
+// --------------------------- Codificação de registro ---------------------------
+
+// encodeRecord preenche buf (recordLen bytes) a partir do registro; codec
+// devolve a página de códigos do campo i e offs, se não for nil, a posição
+// de cada campo (tabelas com colunas ocultas, como _NullFlags).
+func encodeRecord(buf []byte, fields []Field, offs []int, rec Record, codec func(int) encoding.Encoding) error {
+	for k := range rec {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		if fieldIndex(fields, k) < 0 {
+			return fmt.Errorf("campo desconhecido: %s", k)
+		}
+	}
+
+	buf[0] = ' '
+	if del, _ := rec["_deleted"].(bool); del {
+		buf[0] = 0x2A
+	}
+	offset := 1
+	for i, f := range fields {
+		if offs != nil {
+			offset = offs[i]
+		}
+		if err := encodeField(buf[offset:offset+int(f.Size)], f, rec[f.Name], codec(i)); err != nil {
+			return err
+		}
+		offset += int(f.Size)
+	}
+	return nil
+}
+
+// encodeField grava v em dst (exatamente f.Size bytes) no formato do tipo.
+func encodeField(dst []byte, f Field, v any, enc encoding.Encoding) error {
+	switch f.Type {
+	case 'C':
+		fillSpaces(dst)
+		if v == nil {
+			return nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: esperado string, recebido %T", f.Name, v)
+		}
+		b, err := encodeBytes(s, enc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if len(b) > len(dst) {
+			return fmt.Errorf("%s: texto com %d bytes excede o tamanho %d", f.Name, len(b), f.Size)
+		}
+		copy(dst, b)
+
+	case 'N', 'F':
+		fillSpaces(dst)
+		if v == nil {
+			return nil
+		}
+		s, err := formatNumber(v, int(f.DecimalPlaces))
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if len(s) > len(dst) {
+			return fmt.Errorf("%s: valor %s não cabe em N(%d,%d)", f.Name, s, f.Size, f.DecimalPlaces)
+		}
+		copy(dst[len(dst)-len(s):], s)
+
+	case 'Y':
+		clear(dst)
+		if v == nil {
+			return nil
+		}
+		if x, ok := v.(Decimal); ok {
+			r, err := exactRescale(x, 4)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			binary.LittleEndian.PutUint64(dst, uint64(r.Unscaled))
+			return nil
+		}
+		x, err := toFloat(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		switch v.(type) {
+		case float64, float32:
+			s, frac, err := shortFloat(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			if frac > 4 {
+				return fmt.Errorf("%s: valor %s tem mais de 4 casas decimais", f.Name, s)
+			}
+		}
+		// com no máximo 4 casas, Round só corrige o erro binário de x*10000
+		scaled := math.Round(x * 10000)
+		if scaled > math.MaxInt64 || scaled < math.MinInt64 {
+			return fmt.Errorf("%s: valor %v fora da faixa de currency", f.Name, v)
+		}
+		binary.LittleEndian.PutUint64(dst, uint64(int64(scaled)))
+
+	case 'L':
+		switch b := v.(type) {
+		case nil:
+			dst[0] = '?'
+		case bool:
+			dst[0] = 'F'
+			if b {
+				dst[0] = 'T'
+			}
+		default:
+			return fmt.Errorf("%s: esperado bool, recebido %T", f.Name, v)
+		}
+
+	case 'D':
+		fillSpaces(dst)
+		if v == nil {
+			return nil
+		}
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("%s: esperado time.Time, recebido %T", f.Name, v)
+		}
+		if t.IsZero() {
+			return nil
+		}
+		if t.Year() < 0 || t.Year() > 9999 {
+			return fmt.Errorf("%s: ano fora da faixa: %d", f.Name, t.Year())
+		}
+		copy(dst, t.Format("20060102"))
+
+	case 'I':
+		clear(dst)
+		if v == nil {
+			return nil
+		}
+		n, err := toInt(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if n > math.MaxInt32 || n < math.MinInt32 {
+			return fmt.Errorf("%s: valor %d fora da faixa de int32", f.Name, n)
+		}
+		binary.LittleEndian.PutUint32(dst, uint32(int32(n)))
+
+	case 'B':
+		clear(dst)
+		if v == nil {
+			return nil
+		}
+		x, err := toFloat(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		binary.LittleEndian.PutUint64(dst, math.Float64bits(x))
+
+	case 'T':
+		clear(dst)
+		if v == nil {
+			return nil
+		}
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("%s: esperado time.Time, recebido %T", f.Name, v)
+		}
+		if t.IsZero() {
+			return nil
+		}
+		jd, ms := utcToVFPDateTime(t)
+		binary.LittleEndian.PutUint32(dst[0:4], uint32(int32(jd)))
+		binary.LittleEndian.PutUint32(dst[4:8], uint32(int32(ms)))
+
+	case 'M', 'G', 'W':
+		// só o ponteiro vazio: gravar o conteúdo exigiria alocar blocos no
+		// .DBT/.FPT
+		if v != nil {
+			return fmt.Errorf("%s: gravação de memo não suportada", f.Name)
+		}
+		if len(dst) == 4 { // ponteiro binário do VFP
+			clear(dst)
+		} else {
+			fillSpaces(dst)
+		}
+
+	case 'V', 'Q':
+		// vazio; o chamador liga o bit de tamanho em _NullFlags
+		if v != nil {
+			return fmt.Errorf("%s: gravação de tipo %q não suportada", f.Name, string(f.Type))
+		}
+		clear(dst)
+
+	default:
+		return fmt.Errorf("%s: tipo %q não suportado na escrita", f.Name, string(f.Type))
+	}
+	return nil
+}
+
+// normFieldSize completa o tamanho dos tipos de largura fixa.
+func normFieldSize(f *Field) {
+	switch f.Type {
+	case 'L':
+		if f.Size == 0 {
+			f.Size = 1
+		}
+	case 'I':
+		if f.Size == 0 {
+			f.Size = 4
+		}
+	case 'D', 'B', 'T':
+		if f.Size == 0 {
+			f.Size = 8
+		}
+	case 'Y':
+		if f.Size == 0 {
+			f.Size = 8
+		}
+		if f.DecimalPlaces == 0 {
+			f.DecimalPlaces = 4
+		}
+	}
+}
+
+func validateWriteField(f Field, version byte) error {
+	if err := validateField(f, version); err != nil {
+		return err
+	}
+	switch f.Type {
+	case 'C', 'N', 'F':
+		if f.Size == 0 {
+			return fmt.Errorf("%s: tamanho obrigatório", f.Name)
+		}
+	case 'I':
+		if f.Size != 4 {
+			return fmt.Errorf("%s: inteiro deve ter 4 bytes", f.Name)
+		}
+	case 'Y', 'L', 'D', 'B', 'T':
+	default:
+		return fmt.Errorf("%s: tipo %q não suportado na escrita", f.Name, string(f.Type))
+	}
+	if (f.Type == 'N' || f.Type == 'F') && f.DecimalPlaces > 0 && int(f.DecimalPlaces) > int(f.Size)-2 {
+		return fmt.Errorf("%s: casas decimais (%d) incompatíveis com o tamanho %d", f.Name, f.DecimalPlaces, f.Size)
+	}
+	return nil
+}
+
+func fieldIndex(fields []Field, name string) int {
+	for i, f := range fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func fillSpaces(b []byte) {
+	for i := range b {
+		b[i] = ' '
+	}
+}
+
+func putHeaderDate(hdr []byte, t time.Time) {
+	hdr[1] = byte(t.Year() - 1900)
+	hdr[2] = byte(t.Month())
+	hdr[3] = byte(t.Day())
+}
+
+// formatNumber formata v com dec casas decimais, sem notação científica.
+// Valores com mais casas que dec são recusados em vez de arredondados.
+func formatNumber(v any, dec int) (string, error) {
+	switch x := v.(type) {
+	case Decimal:
+		r, err := exactRescale(x, uint8(dec))
+		if err != nil {
+			return "", err
+		}
+		return r.String(), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		n, err := toInt(v)
+		if err != nil {
+			return "", err
+		}
+		s := strconv.FormatInt(n, 10)
+		if dec > 0 {
+			s += "." + strings.Repeat("0", dec)
+		}
+		return s, nil
+	}
+	s, frac, err := shortFloat(v)
+	if err != nil {
+		return "", err
+	}
+	if frac > dec {
+		return "", fmt.Errorf("valor %s tem mais de %d casas decimais", s, dec)
+	}
+	if dec > frac {
+		if frac == 0 {
+			s += "."
+		}
+		s += strings.Repeat("0", dec-frac)
+	}
+	return s, nil
+}
+
+// exactRescale muda a escala de x para scale; falha se isso descartaria
+// dígitos diferentes de zero.
+func exactRescale(x Decimal, scale uint8) (Decimal, error) {
+	r, ok := x.rescale(scale)
+	if !ok {
+		return x, fmt.Errorf("valor %s fora da faixa", x)
+	}
+	if r.Rat().Cmp(x.Rat()) != 0 {
+		return x, fmt.Errorf("valor %s tem mais de %d casas decimais", x, scale)
+	}
+	return r, nil
+}
+
+// shortFloat devolve o menor texto decimal que identifica o float v (com a
+// precisão do próprio tipo, para float32) e quantas casas decimais ele tem.
+func shortFloat(v any) (string, int, error) {
+	bits := 64
+	if _, ok := v.(float32); ok {
+		bits = 32
+	}
+	x, err := toFloat(v)
+	if err != nil {
+		return "", 0, err
+	}
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return "", 0, fmt.Errorf("valor numérico inválido: %v", x)
+	}
+	s := strconv.FormatFloat(x, 'f', -1, bits)
+	frac := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		frac = len(s) - i - 1
+	}
+	return s, frac, nil
+}
+
+func toFloat(v any) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case Decimal:
+		return n.Float64(), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		i, err := toInt(v)
+		return float64(i), err
+	default:
+		return 0, fmt.Errorf("esperado número, recebido %T", v)
+	}
+}
+
+func toInt(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return toInt(uint64(n))
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("valor %d fora da faixa", n)
+		}
+		return int64(n), nil
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) || n != math.Trunc(n) {
+			return 0, fmt.Errorf("valor %v não é inteiro", n)
+		}
+		if n < math.MinInt64 || n >= math.MaxInt64 { // float64(MaxInt64) é 2^63
+			return 0, fmt.Errorf("valor %v fora da faixa", n)
+		}
+		return int64(n), nil
+	case float32:
+		return toInt(float64(n))
+	case Decimal:
+		i, exact := n.Int64()
+		if !exact {
+			return 0, fmt.Errorf("valor %s não é inteiro", n)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("esperado inteiro, recebido %T", v)
+	}
+}
+
+// utcToVFPDateTime é o inverso de vfpDateTimeToUTC.
+func utcToVFPDateTime(t time.Time) (julianDay int, msSinceMidnight int) {
+	y, m, d := t.Date()
+	a := (14 - int(m)) / 12
+	yy := y + 4800 - a
+	mm := int(m) + 12*a - 3
+	julianDay = d + (153*mm+2)/5 + 365*yy + yy/4 - yy/100 + yy/400 - 32045
+	msSinceMidnight = ((t.Hour()*60+t.Minute())*60+t.Second())*1000 + t.Nanosecond()/1e6
+	return julianDay, msSinceMidnight
+}
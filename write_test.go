@@ -0,0 +1,232 @@
+package dbfmini
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateRoundTripAllFieldTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.dbf")
+	fields := []Field{
+		{Name: "NAME", Type: 'C', Size: 10},
+		{Name: "AGE", Type: 'N', Size: 5},
+		{Name: "BALANCE", Type: 'F', Size: 10, DecimalPlaces: 2},
+		{Name: "CURR", Type: 'Y'},
+		{Name: "ACTIVE", Type: 'L'},
+		{Name: "BIRTH", Type: 'D'},
+		{Name: "COUNT", Type: 'I'},
+		{Name: "RATIO", Type: 'B'},
+		{Name: "STAMP", Type: 'T'},
+	}
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	w, err := Create(path, fields, &CreateOptions{Date: date})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	stamp := time.Date(2023, time.August, 15, 23, 59, 59, 0, time.UTC)
+	birth := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows := []Record{
+		{"NAME": "José", "AGE": 42, "BALANCE": 123.45, "CURR": 12.34, "ACTIVE": true,
+			"BIRTH": birth, "COUNT": int32(123456), "RATIO": 3.14159, "STAMP": stamp},
+		{"NAME": "Vazio"},
+		{"NAME": "Excluído", "_deleted": true},
+	}
+	for _, r := range rows {
+		if err := w.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, err := Open(path, &OpenOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.Version() != 0x30 {
+		t.Fatalf("Version = 0x%02x, want 0x30", db.Version())
+	}
+	if db.RecordCount != 3 {
+		t.Fatalf("RecordCount = %d, want 3", db.RecordCount)
+	}
+	if !db.DateOfLastUpd.Equal(date) {
+		t.Fatalf("DateOfLastUpd = %v, want %v", db.DateOfLastUpd, date)
+	}
+	if db.Fields[3].Size != 8 || db.Fields[3].DecimalPlaces != 4 {
+		t.Fatalf("CURR field = %+v, want size 8 and 4 decimals", db.Fields[3])
+	}
+
+	records, err := db.ReadRecords(0)
+	if err != nil {
+		t.Fatalf("ReadRecords returned error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("len(records) = %d, want 3", len(records))
+	}
+
+	rec := records[0]
+	if rec["NAME"] != "José" || rec["AGE"] != 42.0 || rec["ACTIVE"] != true || rec["COUNT"] != int32(123456) {
+		t.Fatalf("record 0 = %#v", rec)
+	}
+	if bal := rec["BALANCE"].(float64); math.Abs(bal-123.45) > 1e-9 {
+		t.Fatalf("BALANCE = %v, want 123.45", bal)
+	}
+	if curr := rec["CURR"].(float64); math.Abs(curr-12.34) > 1e-9 {
+		t.Fatalf("CURR = %v, want 12.34", curr)
+	}
+	if ratio := rec["RATIO"].(float64); ratio != 3.14159 {
+		t.Fatalf("RATIO = %v, want 3.14159", ratio)
+	}
+	if got := rec["BIRTH"].(time.Time); !got.Equal(birth) {
+		t.Fatalf("BIRTH = %v, want %v", got, birth)
+	}
+	if got := rec["STAMP"].(time.Time); !got.Equal(stamp) {
+		t.Fatalf("STAMP = %v, want %v", got, stamp)
+	}
+
+	empty := records[1]
+	for _, name := range []string{"AGE", "BALANCE", "ACTIVE", "BIRTH", "STAMP"} {
+		if empty[name] != nil {
+			t.Fatalf("empty %s = %#v, want nil", name, empty[name])
+		}
+	}
+	if del, _ := records[2]["_deleted"].(bool); !del {
+		t.Fatalf("record 2 should be flagged deleted")
+	}
+}
+
+func TestCreateDBaseIIIHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.dbf")
+	w, err := Create(path, []Field{{Name: "UF", Type: 'C', Size: 2}}, &CreateOptions{Encoding: "CP850"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := w.Append(Record{"UF": "SP"}); err != nil {
+		t.Fatalf("Append returned error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if db.Version() != 0x03 || db.HeaderLength() != 32+32+1 || db.RecordLength() != 3 {
+		t.Fatalf("header version=0x%02x headerLen=%d recordLen=%d", db.Version(), db.HeaderLength(), db.RecordLength())
+	}
+}
+
+func TestCreateVFPVariantsHaveBacklink(t *testing.T) {
+	for _, version := range []byte{0x30, 0x31, 0x32} {
+		path := filepath.Join(t.TempDir(), "out.dbf")
+		fields := []Field{{Name: "ID", Type: 'I'}, {Name: "UF", Type: 'C', Size: 2}}
+		w, err := Create(path, fields, &CreateOptions{Version: version})
+		if err != nil {
+			t.Fatalf("0x%02x: Create returned error: %v", version, err)
+		}
+		if err := w.Append(Record{"ID": 7, "UF": "SP"}); err != nil {
+			t.Fatalf("0x%02x: Append returned error: %v", version, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("0x%02x: Close returned error: %v", version, err)
+		}
+
+		db, err := Open(path, nil)
+		if err != nil {
+			t.Fatalf("0x%02x: Open returned error: %v", version, err)
+		}
+		if db.HeaderLength() != 32+2*32+1+vfpBacklinkSize {
+			t.Fatalf("0x%02x: headerLen=%d, want backlink included", version, db.HeaderLength())
+		}
+		rec, err := db.ReadAt(1)
+		if err != nil || rec["UF"] != "SP" {
+			t.Fatalf("0x%02x: ReadAt(1) = %v, %v", version, rec, err)
+		}
+	}
+}
+
+func TestWriterAppendValidatesValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.dbf")
+	fields := []Field{
+		{Name: "NAME", Type: 'C', Size: 4},
+		{Name: "PRICE", Type: 'N', Size: 6, DecimalPlaces: 2},
+		{Name: "QTY", Type: 'I'},
+		{Name: "CURR", Type: 'Y'},
+	}
+	w, err := Create(path, fields, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	defer w.Close()
+
+	cases := []struct {
+		rec  Record
+		want string
+	}{
+		{Record{"NAME": "Longo demais"}, "excede"},
+		{Record{"PRICE": 12345.6}, "não cabe"},
+		{Record{"PRICE": 1.005}, "mais de 2 casas"},
+		{Record{"PRICE": Decimal{Unscaled: 1234, Scale: 3}}, "mais de 2 casas"},
+		{Record{"CURR": 0.00001}, "mais de 4 casas"},
+		{Record{"CURR": Decimal{Unscaled: 15, Scale: 5}}, "mais de 4 casas"},
+		{Record{"QTY": int64(math.MaxInt32) + 1}, "fora da faixa"},
+		{Record{"QTY": 2.5}, "não é inteiro"},
+		{Record{"QTY": math.NaN()}, "não é inteiro"},
+		{Record{"QTY": math.Inf(-1)}, "não é inteiro"},
+		{Record{"QTY": 1e19}, "fora da faixa"},
+		{Record{"NAME": 10}, "esperado string"},
+		{Record{"OTHER": "x"}, "campo desconhecido"},
+	}
+	for _, c := range cases {
+		err := w.Append(c.rec)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("Append(%v) error = %v, want containing %q", c.rec, err, c.want)
+		}
+	}
+	// casas a menos, zeros à direita e float32 cabem sem arredondar
+	for _, v := range []any{12.5, float32(0.1), Decimal{Unscaled: 12500, Scale: 4}, 7} {
+		if err := w.Append(Record{"PRICE": v, "CURR": v}); err != nil {
+			t.Fatalf("Append(%v) returned error: %v", v, err)
+		}
+	}
+}
+
+func TestCreateRejectsInvalidSchema(t *testing.T) {
+	dir := t.TempDir()
+	cases := [][]Field{
+		{{Name: "NAME", Type: 'C'}},
+		{{Name: "A", Type: 'C', Size: 1}, {Name: "A", Type: 'C', Size: 1}},
+		{{Name: "NOTES", Type: 'M', Size: 10}},
+		{{Name: "N", Type: 'N', Size: 3, DecimalPlaces: 2}},
+	}
+	for i, fields := range cases {
+		if _, err := Create(filepath.Join(dir, "bad.dbf"), fields, nil); err == nil {
+			t.Fatalf("case %d: Create(%+v) should fail", i, fields)
+		}
+	}
+}
+
+func TestCreateRejectsOversizedRecordAndHeader(t *testing.T) {
+	dir := t.TempDir()
+	wide := make([]Field, 258) // 258*255+1 bytes por registro
+	for i := range wide {
+		wide[i] = Field{Name: fmt.Sprintf("F%d", i), Type: 'C', Size: 255}
+	}
+	if _, err := Create(filepath.Join(dir, "wide.dbf"), wide, nil); err == nil {
+		t.Fatalf("Create with a %d-byte record should fail", calcRecordLen(wide))
+	}
+	many := make([]Field, 2048) // header de 32+32*2048+1 bytes
+	for i := range many {
+		many[i] = Field{Name: fmt.Sprintf("F%d", i), Type: 'C', Size: 1}
+	}
+	if _, err := Create(filepath.Join(dir, "many.dbf"), many, nil); err == nil {
+		t.Fatalf("Create with %d fields should fail", len(many))
+	}
+}